@@ -0,0 +1,647 @@
+// Code generated by import; DO NOT EDIT.
+
+package mediawiki
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Enumerate all images sequentially.
+// https://www.mediawiki.org/wiki/Special:MyLanguage/API:Allimages
+//
+// Flags:
+// * This module requires read rights.
+// * This module can be used as a generator.
+
+// Allimages
+
+type AllimagesResponse struct {
+	QueryResponse
+	Continue map[string]string `json:"continue,omitempty"`
+	Query    *AllimagesQuery   `json:"query,omitempty"`
+}
+
+type AllimagesQuery struct {
+	Allimages []AllimagesResult `json:"allimages,omitempty"`
+}
+
+type AllimagesResult struct {
+	Name                string     `json:"name"`
+	Timestamp           *time.Time `json:"timestamp,omitempty"`
+	Url                 string     `json:"url,omitempty"`
+	Descriptionurl      string     `json:"descriptionurl,omitempty"`
+	Descriptionshorturl string     `json:"descriptionshorturl,omitempty"`
+	Ns                  Namespace  `json:"ns"`
+	Title               string     `json:"title"`
+	User                string     `json:"user,omitempty"`
+	Size                int        `json:"size,omitempty"`
+	Width               int        `json:"width,omitempty"`
+	Height              int        `json:"height,omitempty"`
+	Sha1                string     `json:"sha1,omitempty"`
+	Mime                string     `json:"mime,omitempty"`
+}
+
+// AllimagesSort is the sort parameter of Allimages.
+type AllimagesSort string
+
+const (
+	AllimagesSortName      AllimagesSort = "name"
+	AllimagesSortTimestamp AllimagesSort = "timestamp"
+)
+
+// AllimagesFilterbots is the filterbots parameter of Allimages.
+type AllimagesFilterbots string
+
+const (
+	AllimagesFilterbotsAll    AllimagesFilterbots = "all"
+	AllimagesFilterbotsBots   AllimagesFilterbots = "bots"
+	AllimagesFilterbotsNobots AllimagesFilterbots = "nobots"
+)
+
+type AllimagesOption func(map[string]string)
+
+type AllimagesClient struct {
+	o    []AllimagesOption
+	c    *Client
+	cont map[string]string
+}
+
+func (c *Client) Allimages() *AllimagesClient {
+	return &AllimagesClient{c: c}
+}
+
+// Sort
+// Property to sort by.
+// One of the following values: name, timestamp
+// If Sort isn't called, the wiki uses AllimagesSortName.
+func (w *AllimagesClient) Sort(s AllimagesSort) *AllimagesClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["aisort"] = string(s)
+	})
+	return w
+}
+
+// Dir
+// The direction in which to list.
+// One of the following values: ascending, descending, newer, older
+// If Dir isn't called, the wiki uses DirAscending.
+func (w *AllimagesClient) Dir(s Direction) *AllimagesClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["aidir"] = string(s)
+	})
+	return w
+}
+
+// From
+// The image title to start enumerating from. Can only be used with aisort=name.
+func (w *AllimagesClient) From(s string) *AllimagesClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["aifrom"] = s
+	})
+	return w
+}
+
+// To
+// The image title to stop enumerating at. Can only be used with aisort=name.
+func (w *AllimagesClient) To(s string) *AllimagesClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["aito"] = s
+	})
+	return w
+}
+
+// Continue
+// When more results are available, use this to continue.
+func (w *AllimagesClient) Continue(s string) *AllimagesClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["aicontinue"] = s
+	})
+	return w
+}
+
+// Start
+// The timestamp to start enumerating from. Can only be used with aisort=timestamp.
+// Type: timestamp
+func (w *AllimagesClient) Start(t time.Time) *AllimagesClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["aistart"] = t.Format("2006-01-02T15:04:05Z")
+	})
+	return w
+}
+
+// End
+// The timestamp to end enumerating. Can only be used with aisort=timestamp.
+// Type: timestamp
+func (w *AllimagesClient) End(t time.Time) *AllimagesClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["aiend"] = t.Format("2006-01-02T15:04:05Z")
+	})
+	return w
+}
+
+// Prop
+// Which file information to get:
+// ;timestamp:Adds timestamp for the uploaded version.
+// ;user:Adds the user who uploaded each file version.
+// ;userid:Add the ID of the user that uploaded each file version.
+// ;comment:Comment on the version.
+// ;parsedcomment:Parse the comment on the version.
+// ;canonicaltitle:Adds the canonical title of the file.
+// ;url:Gives URL to the file and the description page.
+// ;size:Adds the size of the file in bytes and the height, width and page count (if applicable).
+// ;dimensions:Alias for size.
+// ;sha1:Adds SHA-1 hash for the file.
+// ;mime:Adds MIME type of the file.
+// ;mediatype:Adds the media type of the file.
+// ;metadata:Lists Exif metadata for the version of the file.
+// ;commonmetadata:Lists file format generic metadata for the version of the file.
+// ;extmetadata:Lists formatted metadata combined from multiple sources. Results are HTML formatted.
+// ;bitdepth:Adds the bit depth of the version.
+// ;badfile:Adds whether the file is on the MediaWiki:Bad image list
+// Values (separate with | or alternative): badfile, bitdepth, canonicaltitle, comment, commonmetadata, dimensions, extmetadata, mediatype, metadata, mime, parsedcomment, sha1, size, timestamp, url, user, userid
+// Maximum number of values is 50 (500 for clients allowed higher limits).
+// If Prop isn't called, the wiki uses timestamp|url.
+func (w *AllimagesClient) Prop(s ...string) *AllimagesClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["aiprop"] = strings.Join(s, "|")
+	})
+	return w
+}
+
+// Prefix
+// Search for all image titles that begin with this value. Can only be used with aisort=name.
+func (w *AllimagesClient) Prefix(s string) *AllimagesClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["aiprefix"] = s
+	})
+	return w
+}
+
+// Minsize
+// Limit to images with at least this many bytes.
+// Type: integer
+func (w *AllimagesClient) Minsize(i int) *AllimagesClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["aiminsize"] = strconv.FormatInt(int64(i), 10)
+	})
+	return w
+}
+
+// Maxsize
+// Limit to images with at most this many bytes.
+// Type: integer
+func (w *AllimagesClient) Maxsize(i int) *AllimagesClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["aimaxsize"] = strconv.FormatInt(int64(i), 10)
+	})
+	return w
+}
+
+// Sha1
+// SHA1 hash of image. Overrides aisha1base36.
+func (w *AllimagesClient) Sha1(s string) *AllimagesClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["aisha1"] = s
+	})
+	return w
+}
+
+// Sha1base36
+// SHA1 hash of image in base 36 (used in MediaWiki).
+func (w *AllimagesClient) Sha1base36(s string) *AllimagesClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["aisha1base36"] = s
+	})
+	return w
+}
+
+// User
+// Only return files where the last version was uploaded by this user. Can only be used with aisort=timestamp. Cannot be used together with aifilterbots.
+// Type: user
+func (w *AllimagesClient) User(s string) *AllimagesClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["aiuser"] = s
+	})
+	return w
+}
+
+// Filterbots
+// How to filter files uploaded by bots. Can only be used with aisort=timestamp. Cannot be used together with aiuser.
+// One of the following values: all, bots, nobots
+// If Filterbots isn't called, the wiki uses AllimagesFilterbotsAll.
+func (w *AllimagesClient) Filterbots(s AllimagesFilterbots) *AllimagesClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["aifilterbots"] = string(s)
+	})
+	return w
+}
+
+// Mime
+// What MIME types to search for, e.g. image/jpeg.
+// If set, implies aisort=name.
+// Separate values with | or alternative.
+// Maximum number of values is 50 (500 for clients allowed higher limits).
+func (w *AllimagesClient) Mime(s ...string) *AllimagesClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["aimime"] = strings.Join(s, "|")
+	})
+	return w
+}
+
+// Limit
+// How many images in total to return.
+// Type: limit
+// The value must be between 1 and 500.
+// If Limit isn't called, the wiki uses 10.
+func (w *AllimagesClient) Limit(i int) *AllimagesClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["ailimit"] = strconv.FormatInt(int64(i), 10)
+	})
+	return w
+}
+
+// Validate checks the parameters of the request without sending it,
+// and returns all problems found. Do calls it before sending the request.
+func (w *AllimagesClient) Validate() error {
+	parameters := Values{}
+	for _, o := range w.o {
+		o(parameters)
+	}
+
+	return checkParams(parameters,
+		oneOf("aisort", "name", "timestamp"),
+		oneOf("aidir", "ascending", "descending", "newer", "older"),
+		oneOf("aifilterbots", "all", "bots", "nobots"))
+}
+
+// ContinueFrom
+// Continues the query where the response with the given continue values
+// left off. The values replace those of earlier calls.
+func (w *AllimagesClient) ContinueFrom(c map[string]string) *AllimagesClient {
+	w.cont = c
+	return w
+}
+
+// DoAll sends the request, continuing it until all results are fetched,
+// and calls f with every response. It stops at the first error, from the
+// request or from f.
+func (w *AllimagesClient) DoAll(ctx context.Context, f func(AllimagesResponse) error) error {
+	for {
+		r, err := w.Do(ctx)
+		if err != nil {
+			return err
+		}
+
+		if err := f(r); err != nil {
+			return err
+		}
+
+		if r.Continue == nil {
+			return nil
+		}
+		w.ContinueFrom(r.Continue)
+	}
+}
+
+func (w *AllimagesClient) Do(ctx context.Context) (AllimagesResponse, error) {
+	if err := w.Validate(); err != nil {
+		return AllimagesResponse{}, err
+	}
+
+	if err := w.c.checkKeepAlive(ctx); err != nil {
+		return AllimagesResponse{}, err
+	}
+
+	// Specify parameters to send.
+	parameters := Values{
+		"action": "query",
+		"list":   "allimages",
+	}
+
+	for _, o := range w.o {
+		o(parameters)
+	}
+	for k, v := range w.cont {
+		parameters[k] = v
+	}
+
+	// Make the request.
+	r := AllimagesResponse{}
+	j, err := w.c.GetInto(ctx, parameters, &r)
+	r.RawJSON = j
+	if err != nil {
+		return r, fmt.Errorf("failed to get: %w", err)
+	}
+
+	if e := r.Error; e != nil {
+		return r, fmt.Errorf("%s: %s", e.Code, e.Info)
+	} else if r.Query == nil {
+		return r, fmt.Errorf("unexpected error in query")
+	}
+
+	return r, nil
+}
+
+// AllimagesGenerator uses allimages as a generator.
+
+type AllimagesGeneratorResponse struct {
+	QueryResponse
+	Continue map[string]string   `json:"continue,omitempty"`
+	Query    *QueryResponseQuery `json:"query,omitempty"`
+}
+
+type AllimagesGeneratorOption func(map[string]string)
+
+type AllimagesGeneratorClient struct {
+	o    []AllimagesGeneratorOption
+	c    *Client
+	cont map[string]string
+}
+
+func (c *Client) AllimagesGenerator() *AllimagesGeneratorClient {
+	return &AllimagesGeneratorClient{c: c}
+}
+
+// Sort
+// Property to sort by.
+// One of the following values: name, timestamp
+// If Sort isn't called, the wiki uses AllimagesSortName.
+func (w *AllimagesGeneratorClient) Sort(s AllimagesSort) *AllimagesGeneratorClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["gaisort"] = string(s)
+	})
+	return w
+}
+
+// Dir
+// The direction in which to list.
+// One of the following values: ascending, descending, newer, older
+// If Dir isn't called, the wiki uses DirAscending.
+func (w *AllimagesGeneratorClient) Dir(s Direction) *AllimagesGeneratorClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["gaidir"] = string(s)
+	})
+	return w
+}
+
+// From
+// The image title to start enumerating from. Can only be used with aisort=name.
+func (w *AllimagesGeneratorClient) From(s string) *AllimagesGeneratorClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["gaifrom"] = s
+	})
+	return w
+}
+
+// To
+// The image title to stop enumerating at. Can only be used with aisort=name.
+func (w *AllimagesGeneratorClient) To(s string) *AllimagesGeneratorClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["gaito"] = s
+	})
+	return w
+}
+
+// Continue
+// When more results are available, use this to continue.
+func (w *AllimagesGeneratorClient) Continue(s string) *AllimagesGeneratorClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["gaicontinue"] = s
+	})
+	return w
+}
+
+// Start
+// The timestamp to start enumerating from. Can only be used with aisort=timestamp.
+// Type: timestamp
+func (w *AllimagesGeneratorClient) Start(t time.Time) *AllimagesGeneratorClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["gaistart"] = t.Format("2006-01-02T15:04:05Z")
+	})
+	return w
+}
+
+// End
+// The timestamp to end enumerating. Can only be used with aisort=timestamp.
+// Type: timestamp
+func (w *AllimagesGeneratorClient) End(t time.Time) *AllimagesGeneratorClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["gaiend"] = t.Format("2006-01-02T15:04:05Z")
+	})
+	return w
+}
+
+// Prop
+// Which file information to get:
+// ;timestamp:Adds timestamp for the uploaded version.
+// ;user:Adds the user who uploaded each file version.
+// ;userid:Add the ID of the user that uploaded each file version.
+// ;comment:Comment on the version.
+// ;parsedcomment:Parse the comment on the version.
+// ;canonicaltitle:Adds the canonical title of the file.
+// ;url:Gives URL to the file and the description page.
+// ;size:Adds the size of the file in bytes and the height, width and page count (if applicable).
+// ;dimensions:Alias for size.
+// ;sha1:Adds SHA-1 hash for the file.
+// ;mime:Adds MIME type of the file.
+// ;mediatype:Adds the media type of the file.
+// ;metadata:Lists Exif metadata for the version of the file.
+// ;commonmetadata:Lists file format generic metadata for the version of the file.
+// ;extmetadata:Lists formatted metadata combined from multiple sources. Results are HTML formatted.
+// ;bitdepth:Adds the bit depth of the version.
+// ;badfile:Adds whether the file is on the MediaWiki:Bad image list
+// Values (separate with | or alternative): badfile, bitdepth, canonicaltitle, comment, commonmetadata, dimensions, extmetadata, mediatype, metadata, mime, parsedcomment, sha1, size, timestamp, url, user, userid
+// Maximum number of values is 50 (500 for clients allowed higher limits).
+// If Prop isn't called, the wiki uses timestamp|url.
+func (w *AllimagesGeneratorClient) Prop(s ...string) *AllimagesGeneratorClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["gaiprop"] = strings.Join(s, "|")
+	})
+	return w
+}
+
+// Prefix
+// Search for all image titles that begin with this value. Can only be used with aisort=name.
+func (w *AllimagesGeneratorClient) Prefix(s string) *AllimagesGeneratorClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["gaiprefix"] = s
+	})
+	return w
+}
+
+// Minsize
+// Limit to images with at least this many bytes.
+// Type: integer
+func (w *AllimagesGeneratorClient) Minsize(i int) *AllimagesGeneratorClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["gaiminsize"] = strconv.FormatInt(int64(i), 10)
+	})
+	return w
+}
+
+// Maxsize
+// Limit to images with at most this many bytes.
+// Type: integer
+func (w *AllimagesGeneratorClient) Maxsize(i int) *AllimagesGeneratorClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["gaimaxsize"] = strconv.FormatInt(int64(i), 10)
+	})
+	return w
+}
+
+// Sha1
+// SHA1 hash of image. Overrides aisha1base36.
+func (w *AllimagesGeneratorClient) Sha1(s string) *AllimagesGeneratorClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["gaisha1"] = s
+	})
+	return w
+}
+
+// Sha1base36
+// SHA1 hash of image in base 36 (used in MediaWiki).
+func (w *AllimagesGeneratorClient) Sha1base36(s string) *AllimagesGeneratorClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["gaisha1base36"] = s
+	})
+	return w
+}
+
+// User
+// Only return files where the last version was uploaded by this user. Can only be used with aisort=timestamp. Cannot be used together with aifilterbots.
+// Type: user
+func (w *AllimagesGeneratorClient) User(s string) *AllimagesGeneratorClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["gaiuser"] = s
+	})
+	return w
+}
+
+// Filterbots
+// How to filter files uploaded by bots. Can only be used with aisort=timestamp. Cannot be used together with aiuser.
+// One of the following values: all, bots, nobots
+// If Filterbots isn't called, the wiki uses AllimagesFilterbotsAll.
+func (w *AllimagesGeneratorClient) Filterbots(s AllimagesFilterbots) *AllimagesGeneratorClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["gaifilterbots"] = string(s)
+	})
+	return w
+}
+
+// Mime
+// What MIME types to search for, e.g. image/jpeg.
+// If set, implies aisort=name.
+// Separate values with | or alternative.
+// Maximum number of values is 50 (500 for clients allowed higher limits).
+func (w *AllimagesGeneratorClient) Mime(s ...string) *AllimagesGeneratorClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["gaimime"] = strings.Join(s, "|")
+	})
+	return w
+}
+
+// Limit
+// How many images in total to return.
+// Type: limit
+// The value must be between 1 and 500.
+// If Limit isn't called, the wiki uses 10.
+func (w *AllimagesGeneratorClient) Limit(i int) *AllimagesGeneratorClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["gailimit"] = strconv.FormatInt(int64(i), 10)
+	})
+	return w
+}
+
+// Pageprop
+// Which properties to get for the generated pages.
+// Separate values with | or alternative.
+func (w *AllimagesGeneratorClient) Pageprop(s ...string) *AllimagesGeneratorClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["prop"] = strings.Join(s, "|")
+	})
+	return w
+}
+
+// Validate checks the parameters of the request without sending it,
+// and returns all problems found. Do calls it before sending the request.
+func (w *AllimagesGeneratorClient) Validate() error {
+	parameters := Values{}
+	for _, o := range w.o {
+		o(parameters)
+	}
+
+	return checkParams(parameters,
+		oneOf("gaisort", "name", "timestamp"),
+		oneOf("gaidir", "ascending", "descending", "newer", "older"),
+		oneOf("gaifilterbots", "all", "bots", "nobots"))
+}
+
+// ContinueFrom
+// Continues the query where the response with the given continue values
+// left off. The values replace those of earlier calls.
+func (w *AllimagesGeneratorClient) ContinueFrom(c map[string]string) *AllimagesGeneratorClient {
+	w.cont = c
+	return w
+}
+
+// DoAll sends the request, continuing it until all results are fetched,
+// and calls f with every response. It stops at the first error, from the
+// request or from f.
+func (w *AllimagesGeneratorClient) DoAll(ctx context.Context, f func(AllimagesGeneratorResponse) error) error {
+	for {
+		r, err := w.Do(ctx)
+		if err != nil {
+			return err
+		}
+
+		if err := f(r); err != nil {
+			return err
+		}
+
+		if r.Continue == nil {
+			return nil
+		}
+		w.ContinueFrom(r.Continue)
+	}
+}
+
+func (w *AllimagesGeneratorClient) Do(ctx context.Context) (AllimagesGeneratorResponse, error) {
+	if err := w.Validate(); err != nil {
+		return AllimagesGeneratorResponse{}, err
+	}
+
+	if err := w.c.checkKeepAlive(ctx); err != nil {
+		return AllimagesGeneratorResponse{}, err
+	}
+
+	// Specify parameters to send.
+	parameters := Values{
+		"action":    "query",
+		"generator": "allimages",
+	}
+
+	for _, o := range w.o {
+		o(parameters)
+	}
+	for k, v := range w.cont {
+		parameters[k] = v
+	}
+
+	// Make the request.
+	r := AllimagesGeneratorResponse{}
+	j, err := w.c.GetInto(ctx, parameters, &r)
+	r.RawJSON = j
+	if err != nil {
+		return r, fmt.Errorf("failed to get: %w", err)
+	}
+
+	if e := r.Error; e != nil {
+		return r, fmt.Errorf("%s: %s", e.Code, e.Info)
+	}
+
+	return r, nil
+}
@@ -15,9 +15,8 @@ import (
 
 type AllpagesResponse struct {
 	QueryResponse
-	BatchComplete string                    `json:"batchcomplete"`
-	Continue      *AllpagesResponseContinue `json:"continue,omitempty"`
-	Query         *AllpagesResponseQuery    `json:"query,omitempty"`
+	Continue *AllpagesResponseContinue `json:"continue,omitempty"`
+	Query    *AllpagesResponseQuery    `json:"query,omitempty"`
 }
 
 type AllpagesResponseContinue struct {
@@ -26,7 +25,7 @@ type AllpagesResponseContinue struct {
 }
 
 type AllpagesResponseQuery struct {
-	Pages map[string]QueryResponseQueryPage `json:"pages"`
+	Pages []QueryResponseQueryPage `json:"pages"`
 }
 
 // Allpages
@@ -93,9 +92,9 @@ func (w *AllpagesClient) Namespace(i Namespace) *AllpagesClient {
 // Note: Due to miser mode, using this may result in fewer than aplimit results returned before continuing; in extreme cases, zero results may be returned.
 // One of the following values: all, nonredirects, redirects
 // Default: all
-func (w *AllpagesClient) Filterredir(s string) *AllpagesClient {
+func (w *AllpagesClient) Filterredir(s RedirectFilter) *AllpagesClient {
 	w.o = append(w.o, func(m map[string]string) {
-		m["gapfilterredir"] = s
+		m["gapfilterredir"] = string(s)
 	})
 	return w
 }
@@ -168,9 +167,9 @@ func (w *AllpagesClient) Limit(i int) *AllpagesClient {
 // The direction in which to list.
 // One of the following values: ascending, descending
 // Default: ascending
-func (w *AllpagesClient) Dir(s string) *AllpagesClient {
+func (w *AllpagesClient) Dir(s Direction) *AllpagesClient {
 	w.o = append(w.o, func(m map[string]string) {
-		m["gapdir"] = s
+		m["gapdir"] = string(s)
 	})
 	return w
 }
@@ -200,7 +199,24 @@ func (w *AllpagesClient) Prexpiry(s string) *AllpagesClient {
 	return w
 }
 
+// Validate checks the parameters of the request without sending it,
+// and returns all problems found. Do calls it before sending the request.
+func (w *AllpagesClient) Validate() error {
+	parameters := Values{}
+	for _, o := range w.o {
+		o(parameters)
+	}
+
+	return checkParams(parameters,
+		oneOf("gapfilterredir", string(RedirectFilterAll), string(RedirectFilterNonredirects), string(RedirectFilterRedirects)),
+		oneOf("gapdir", string(DirAscending), string(DirDescending)))
+}
+
 func (w *AllpagesClient) Do(ctx context.Context) (AllpagesResponse, error) {
+	if err := w.Validate(); err != nil {
+		return AllpagesResponse{}, err
+	}
+
 	if err := w.c.checkKeepAlive(ctx); err != nil {
 		return AllpagesResponse{}, err
 	}
@@ -18,7 +18,7 @@ import (
 
 type AllrevisionsResponse struct {
 	CoreResponse
-	Batchcomplete any                           `json:"batchcomplete,omitempty"`
+	Batchcomplete bool                          `json:"batchcomplete,omitempty"`
 	Continue      *AllrevisionsResponseContinue `json:"continue,omitempty"`
 	Query         *AllrevisionsResponseQuery    `json:"query,omitempty"`
 }
@@ -214,9 +214,9 @@ func (w *AllrevisionsClient) End(t time.Time) *AllrevisionsClient {
 }
 
 // dir
-func (w *AllrevisionsClient) Dir(s string) *AllrevisionsClient {
+func (w *AllrevisionsClient) Dir(s Direction) *AllrevisionsClient {
 	w.o = append(w.o, func(m map[string]string) {
-		m["arvdir"] = s
+		m["arvdir"] = string(s)
 	})
 	return w
 }
@@ -248,7 +248,23 @@ func (w *AllrevisionsClient) Generatetitles(b bool) *AllrevisionsClient {
 	return w
 }
 
+// Validate checks the parameters of the request without sending it,
+// and returns all problems found. Do calls it before sending the request.
+func (w *AllrevisionsClient) Validate() error {
+	parameters := Values{}
+	for _, o := range w.o {
+		o(parameters)
+	}
+
+	return checkParams(parameters,
+		oneOf("arvdir", string(DirNewer), string(DirOlder)))
+}
+
 func (w *AllrevisionsClient) Do(ctx context.Context) (AllrevisionsResponse, error) {
+	if err := w.Validate(); err != nil {
+		return AllrevisionsResponse{}, err
+	}
+
 	if err := w.c.checkKeepAlive(ctx); err != nil {
 		return AllrevisionsResponse{}, err
 	}
@@ -13,11 +13,11 @@ import (
 // Flags:
 // * This module requires read rights.
 
-type AllUsersDir string
+type AllUsersDir = Direction
 
 const (
-	AllusersAscending  AllUsersDir = "ascending"
-	AllusersDescending AllUsersDir = "descending"
+	AllusersAscending  = DirAscending
+	AllusersDescending = DirDescending
 )
 
 // Allusers
@@ -175,7 +175,23 @@ func (w *AllusersClient) Attachedwiki(s string) *AllusersClient {
 	return w
 }
 
+// Validate checks the parameters of the request without sending it,
+// and returns all problems found. Do calls it before sending the request.
+func (w *AllusersClient) Validate() error {
+	parameters := Values{}
+	for _, o := range w.o {
+		o(parameters)
+	}
+
+	return checkParams(parameters,
+		oneOf("audir", string(DirAscending), string(DirDescending)))
+}
+
 func (w *AllusersClient) Do(ctx context.Context) (AllusersResponse, error) {
+	if err := w.Validate(); err != nil {
+		return AllusersResponse{}, err
+	}
+
 	if err := w.c.checkKeepAlive(ctx); err != nil {
 		return AllusersResponse{}, err
 	}
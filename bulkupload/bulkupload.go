@@ -0,0 +1,448 @@
+// Package bulkupload uploads the files of a directory to a wiki, with
+// the description pages rendered from per-file metadata.
+//
+//	u := &bulkupload.Uploader{
+//		Client:      client,
+//		Comment:     "Import of the 2024 photo library",
+//		Concurrency: 4,
+//		Interval:    time.Second,
+//		Log:         "upload.log",
+//	}
+//	summary, err := u.Run(ctx, "photos")
+//
+// The metadata of each file is read from its sidecar, a YAML or JSON
+// file named after it, as in photo.jpg.yaml, or from a CSV manifest. The
+// results log records the outcome of every file, so that running the
+// upload again skips the files already done.
+package bulkupload
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/clockworksoul/mediawiki"
+)
+
+// Template delimiters. Wikitext uses {{ and }} for its own templates, so
+// the page templates use {% and %} instead.
+const (
+	LeftDelim  = "{%"
+	RightDelim = "%}"
+)
+
+// DefaultTemplate is the page text of files if Uploader.Template is nil.
+const DefaultTemplate = `== {{int:filedesc}} ==
+{{Information
+|description={% param .Description %}
+|date={% param .Date %}
+|source={% param .Source %}
+|author={% param .Author %}
+}}
+{% if .License %}
+== {{int:license-header}} ==
+{% .License %}
+{% end %}
+{% range .Categories %}[[Category:{% . %}]]
+{% end %}`
+
+// ParseTemplate parses the text of a page template, which is executed
+// with the Metadata of each file, using the delimiters {% and %}.
+//
+// Values are inserted as wikitext, so that they may hold links or
+// formatting. Two functions escape them:
+//
+//   - param escapes a value for a parameter of a wikitext template: pipes
+//     become {{!}}, and braces are written as HTML entities, so that
+//     the value can't end the template or add parameters to it.
+//   - nowiki wraps a value in <nowiki> tags, so that it is shown as it
+//     is.
+func ParseTemplate(text string) (*template.Template, error) {
+	return template.New("page").Delims(LeftDelim, RightDelim).Funcs(funcs).Parse(text)
+}
+
+// funcs are the functions of page templates.
+var funcs = template.FuncMap{
+	"param":  param,
+	"nowiki": nowiki,
+}
+
+// paramEscaper escapes the text of a template parameter.
+var paramEscaper = strings.NewReplacer("|", "{{!}}", "{", "&#123;", "}", "&#125;")
+
+func param(s string) string {
+	return paramEscaper.Replace(s)
+}
+
+// nowikiClose matches the closing tags that would end a nowiki early.
+var nowikiClose = regexp.MustCompile(`(?i)</(nowiki)`)
+
+func nowiki(s string) string {
+	return "<nowiki>" + nowikiClose.ReplaceAllString(s, "&lt;/$1") + "</nowiki>"
+}
+
+// DefaultConcurrency is the number of files uploaded at once if
+// Uploader.Concurrency is zero.
+const DefaultConcurrency = 4
+
+// Uploader uploads the files of a directory.
+type Uploader struct {
+	Client *mediawiki.Client
+
+	// Comment is the upload comment of every file.
+	Comment string
+
+	// Template renders the text of the description page of each file
+	// from its Metadata. If it is nil, DefaultTemplate is used.
+	Template *template.Template
+
+	// Policy says what to do about the warnings of each upload.
+	Policy mediawiki.UploadPolicy
+
+	// Manifest is the path of a CSV manifest. If it is set, only the
+	// files it lists are uploaded, with its metadata, and sidecars are
+	// ignored.
+	Manifest string
+
+	// Concurrency is the number of files uploaded at once, or
+	// DefaultConcurrency if it is zero.
+	Concurrency int
+
+	// Interval is the minimum time between the start of two uploads.
+	Interval time.Duration
+
+	// Log is the path of the results log, to which the result of every
+	// file is appended as a line of JSON. Files the log records as done
+	// are skipped, unless their size changed.
+	Log string
+
+	// OnResult is called with the result of every file, from the
+	// goroutine that uploaded it.
+	OnResult func(Result)
+}
+
+// Statuses of results. The others are those of mediawiki.UploadStatus.
+const (
+	// StatusDone is the status of files skipped because the log records
+	// them as done.
+	StatusDone mediawiki.UploadStatus = "done"
+
+	// StatusError is the status of files that couldn't be uploaded.
+	StatusError mediawiki.UploadStatus = "error"
+)
+
+// Result is the outcome of a file, as recorded in the log.
+type Result struct {
+	// File is the path of the file, relative to the directory.
+	File string `json:"file"`
+	Size int64  `json:"size"`
+
+	// Filename is the name of the file on the wiki.
+	Filename string                 `json:"filename"`
+	Status   mediawiki.UploadStatus `json:"status"`
+	Sha1     string                 `json:"sha1,omitempty"`
+	Warnings []string               `json:"warnings,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+	Time     time.Time              `json:"time"`
+}
+
+// done reports whether the file of the result doesn't need to be
+// uploaded again.
+func (r Result) done() bool {
+	switch r.Status {
+	case mediawiki.UploadStatusUploaded, mediawiki.UploadStatusRenamed, mediawiki.UploadStatusSkipped:
+		return true
+	}
+
+	return false
+}
+
+// Summary counts the results of a run by status.
+type Summary map[mediawiki.UploadStatus]int
+
+// job is a file to upload.
+type job struct {
+	file string
+	meta Metadata
+}
+
+// Run uploads the files of dir, and returns the number of files by
+// status. Files that fail are recorded in the log, and don't stop the
+// run; Run only fails if the manifest or the log can't be read, or if
+// ctx is cancelled.
+func (u *Uploader) Run(ctx context.Context, dir string) (Summary, error) {
+	tmpl := u.Template
+	if tmpl == nil {
+		tmpl = template.Must(ParseTemplate(DefaultTemplate))
+	}
+
+	done, err := readLog(u.Log)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs, err := u.jobs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var log *os.File
+	if u.Log != "" {
+		log, err = os.OpenFile(u.Log, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		defer log.Close()
+	}
+
+	summary := Summary{}
+	var mu sync.Mutex
+	var logErr error
+
+	record := func(r Result) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		summary[r.Status]++
+		if r.Status != StatusDone {
+			r.Time = time.Now().UTC()
+		}
+		if log != nil && r.Status != StatusDone && logErr == nil {
+			b, _ := json.Marshal(r)
+			_, logErr = log.Write(append(b, '\n'))
+		}
+		if u.OnResult != nil {
+			u.OnResult(r)
+		}
+	}
+
+	// Starts are spaced by Interval.
+	var tick <-chan time.Time
+	if u.Interval > 0 {
+		t := time.NewTicker(u.Interval)
+		defer t.Stop()
+		tick = t.C
+	}
+	first := make(chan struct{}, 1)
+	first <- struct{}{}
+
+	ch := make(chan job)
+	var wg sync.WaitGroup
+
+	n := u.Concurrency
+	if n == 0 {
+		n = DefaultConcurrency
+	}
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range ch {
+				if tick != nil {
+					select {
+					case <-first:
+					case <-tick:
+					case <-ctx.Done():
+						continue
+					}
+				}
+				if ctx.Err() != nil {
+					continue
+				}
+				record(u.upload(ctx, dir, j, tmpl))
+			}
+		}()
+	}
+
+feed:
+	for _, j := range jobs {
+		if st, err := os.Stat(filepath.Join(dir, j.file)); err == nil {
+			if r, ok := done[j.file]; ok && r.Size == st.Size() {
+				r.Status = StatusDone
+				record(r)
+				continue
+			}
+		}
+
+		select {
+		case ch <- j:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(ch)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return summary, err
+	}
+
+	return summary, logErr
+}
+
+// jobs returns the files of dir to upload, with their metadata.
+func (u *Uploader) jobs(dir string) ([]job, error) {
+	var jobs []job
+
+	if u.Manifest != "" {
+		f, err := os.Open(u.Manifest)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		meta, order, err := readManifest(f)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range order {
+			jobs = append(jobs, job{file: file, meta: meta[file]})
+		}
+
+		return jobs, nil
+	}
+
+	// The log may be kept in the directory.
+	var log string
+	if u.Log != "" {
+		log, _ = filepath.Abs(u.Log)
+	}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if strings.HasPrefix(d.Name(), ".") && path != dir {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || isSidecar(path) {
+			return nil
+		}
+		if abs, err := filepath.Abs(path); err == nil && abs == log {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		jobs = append(jobs, job{file: rel})
+
+		return nil
+	})
+
+	return jobs, err
+}
+
+// upload uploads a file, and returns its result.
+func (u *Uploader) upload(ctx context.Context, dir string, j job, tmpl *template.Template) Result {
+	r := Result{File: j.file, Filename: j.meta.Filename, Status: StatusError}
+
+	path := filepath.Join(dir, j.file)
+
+	meta := j.meta
+	if u.Manifest == "" {
+		var err error
+		if meta, err = readSidecar(path); err != nil {
+			r.Error = err.Error()
+			return r
+		}
+	}
+	if meta.Filename == "" {
+		meta.Filename = filepath.Base(j.file)
+	}
+	r.Filename = meta.Filename
+
+	text := &strings.Builder{}
+	if err := tmpl.Execute(text, meta); err != nil {
+		r.Error = fmt.Sprintf("error rendering page: %s", err)
+		return r
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	defer f.Close()
+
+	st, err := f.Stat()
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	r.Size = st.Size()
+
+	c := u.Client.Upload().Text(text.String())
+	if u.Comment != "" {
+		c.Comment(u.Comment)
+	}
+
+	out, err := c.UploadWithPolicy(ctx, meta.Filename, f, st.Size(), u.Policy)
+	r.Filename, r.Sha1, r.Warnings = out.Filename, out.Sha1, out.Warnings
+	if out.Status != "" {
+		r.Status = out.Status
+	}
+	if err != nil {
+		r.Error = err.Error()
+		if out.Status == "" {
+			r.Status = StatusError
+		}
+	}
+
+	return r
+}
+
+// readLog returns the last result of every file recorded as done in the
+// log, by path. The log may not exist.
+func readLog(path string) (map[string]Result, error) {
+	done := map[string]Result{}
+	if path == "" {
+		return done, nil
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return done, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		if len(strings.TrimSpace(s.Text())) == 0 {
+			continue
+		}
+
+		var r Result
+		if err := json.Unmarshal(s.Bytes(), &r); err != nil {
+			// The last line is cut short if a run was killed while
+			// writing it.
+			continue
+		}
+
+		if r.done() {
+			done[r.File] = r
+		} else {
+			delete(done, r.File)
+		}
+	}
+
+	return done, s.Err()
+}
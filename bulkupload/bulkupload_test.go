@@ -0,0 +1,285 @@
+package bulkupload
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/clockworksoul/mediawiki"
+	"github.com/clockworksoul/mediawiki/internal/wikitest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// wiki is a wiki that accepts uploads in one chunk.
+type wiki struct {
+	mu    sync.Mutex
+	stash map[string][]byte
+	files map[string][]byte
+	texts map[string]string
+
+	// fail makes the uploads of the file name fail.
+	fail string
+
+	// active and maxActive count the chunks being received.
+	active, maxActive int
+	starts            []time.Time
+}
+
+func newWiki(t *testing.T) (*wiki, *mediawiki.Client) {
+	t.Helper()
+
+	w := &wiki{stash: map[string][]byte{}, files: map[string][]byte{}, texts: map[string]string{}}
+
+	s := wikitest.New(t, map[string]http.HandlerFunc{
+		"query+allimages": func(rw http.ResponseWriter, r *http.Request) {
+			wikitest.Reply(rw, map[string]any{"batchcomplete": true, "query": map[string]any{"allimages": []any{}}})
+		},
+		"upload": w.upload,
+	})
+
+	c, err := mediawiki.New(s.URL, "test-bot")
+	require.NoError(t, err)
+
+	return w, c
+}
+
+func (w *wiki) upload(rw http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.MultipartForm != nil && r.MultipartForm.File["chunk"] != nil:
+		w.mu.Lock()
+		w.active++
+		w.maxActive = max(w.maxActive, w.active)
+		w.starts = append(w.starts, time.Now())
+		key := r.Form.Get("filename")
+		w.mu.Unlock()
+
+		// Uploads overlap if they run concurrently.
+		time.Sleep(10 * time.Millisecond)
+
+		f, _ := r.MultipartForm.File["chunk"][0].Open()
+		b, _ := io.ReadAll(f)
+
+		w.mu.Lock()
+		w.active--
+		w.stash[key] = b
+		w.mu.Unlock()
+
+		wikitest.Reply(rw, map[string]any{"upload": map[string]any{"result": "Success", "filekey": key}})
+
+	default:
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		name := r.Form.Get("filename")
+		if name == w.fail {
+			rw.Write([]byte(`{"errors":[{"code":"verification-error","text":"File extension does not match MIME type."}]}`))
+			return
+		}
+
+		w.files[name] = w.stash[r.Form.Get("filekey")]
+		w.texts[name] = r.Form.Get("text")
+		wikitest.Reply(rw, map[string]any{"upload": map[string]any{"result": "Success", "filename": name}})
+	}
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+	}
+}
+
+func TestRunSidecars(t *testing.T) {
+	w, c := newWiki(t)
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.jpg": "aaa",
+		"a.jpg.yaml": `description: A cat
+author: Alice
+license: "{{self|cc-by-sa-4.0}}"
+categories: [Cats, Photos]
+`,
+		"b.png":      "bbb",
+		"b.png.json": `{"filename": "Dog.png", "description": "A dog"}`,
+		"sub/c.gif":  "ccc",
+		".hidden":    "hidden",
+		".git/d.jpg": "ddd",
+	})
+	log := filepath.Join(dir, "upload.log")
+
+	var results []Result
+	u := &Uploader{Client: c, Comment: "import", Log: log, OnResult: func(r Result) { results = append(results, r) }}
+
+	s, err := u.Run(context.Background(), dir)
+	require.NoError(t, err)
+	assert.Equal(t, Summary{mediawiki.UploadStatusUploaded: 3}, s)
+
+	assert.Equal(t, map[string][]byte{"a.jpg": []byte("aaa"), "Dog.png": []byte("bbb"), "c.gif": []byte("ccc")}, w.files)
+	assert.Equal(t, `== {{int:filedesc}} ==
+{{Information
+|description=A cat
+|date=
+|source=
+|author=Alice
+}}
+
+== {{int:license-header}} ==
+{{self|cc-by-sa-4.0}}
+
+[[Category:Cats]]
+[[Category:Photos]]
+`, w.texts["a.jpg"])
+	assert.NotContains(t, w.texts["Dog.png"], "license-header")
+
+	require.Len(t, results, 3)
+	assert.NotEmpty(t, results[0].Sha1)
+
+	// The files are done.
+	w.files = map[string][]byte{}
+	results = nil
+
+	s, err = u.Run(context.Background(), dir)
+	require.NoError(t, err)
+	assert.Equal(t, Summary{StatusDone: 3}, s)
+	assert.Empty(t, w.files)
+
+	// Changed files are uploaded again.
+	writeFiles(t, dir, map[string]string{"b.png": "bbbb"})
+
+	s, err = u.Run(context.Background(), dir)
+	require.NoError(t, err)
+	assert.Equal(t, Summary{StatusDone: 2, mediawiki.UploadStatusUploaded: 1}, s)
+	assert.Equal(t, map[string][]byte{"Dog.png": []byte("bbbb")}, w.files)
+}
+
+func TestTemplateEscaping(t *testing.T) {
+	w, c := newWiki(t)
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.jpg":      "aaa",
+		"a.jpg.yaml": `description: "Left | right, see [[Foo|bar]] }} {{x}}"`,
+	})
+
+	_, err := (&Uploader{Client: c}).Run(context.Background(), dir)
+	require.NoError(t, err)
+	assert.Contains(t, w.texts["a.jpg"], "|description=Left {{!}} right, see [[Foo{{!}}bar]] &#125;&#125; &#123;&#123;x&#125;&#125;\n|date=")
+
+	tmpl, err := ParseTemplate(`{% nowiki .Description %}`)
+	require.NoError(t, err)
+	b := &strings.Builder{}
+	require.NoError(t, tmpl.Execute(b, Metadata{Description: "''a'' </NoWiki> b"}))
+	assert.Equal(t, "<nowiki>''a'' &lt;/NoWiki> b</nowiki>", b.String())
+}
+
+func TestRunManifest(t *testing.T) {
+	w, c := newWiki(t)
+	w.fail = "Bad.jpg"
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"x/1.jpg":     "one",
+		"x/2.jpg":     "two",
+		"x/3.jpg":     "three",
+		"x/1.jpg.yml": "description: ignored",
+	})
+	manifest := filepath.Join(t.TempDir(), "manifest.csv")
+	require.NoError(t, os.WriteFile(manifest, []byte(`file,filename,description,categories,photographer
+x/1.jpg,One.jpg,The first,A; B,Bob
+x/2.jpg,Bad.jpg,The second,,
+`), 0o644))
+	log := filepath.Join(t.TempDir(), "upload.log")
+
+	tmpl, err := ParseTemplate(`{% .Description %} by {% index .Fields "photographer" %}{% range .Categories %} [[Category:{% . %}]]{% end %}`)
+	require.NoError(t, err)
+
+	u := &Uploader{Client: c, Manifest: manifest, Template: tmpl, Log: log}
+
+	s, err := u.Run(context.Background(), dir)
+	require.NoError(t, err)
+	assert.Equal(t, Summary{mediawiki.UploadStatusUploaded: 1, StatusError: 1}, s)
+	assert.Equal(t, "The first by Bob [[Category:A]] [[Category:B]]", w.texts["One.jpg"])
+
+	b, err := os.ReadFile(log)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"error":"verification-error: File extension does not match MIME type."`)
+
+	// Only the failed file is uploaded again.
+	w.fail = ""
+	w.files = map[string][]byte{}
+
+	s, err = u.Run(context.Background(), dir)
+	require.NoError(t, err)
+	assert.Equal(t, Summary{StatusDone: 1, mediawiki.UploadStatusUploaded: 1}, s)
+	assert.Equal(t, map[string][]byte{"Bad.jpg": []byte("two")}, w.files)
+}
+
+func TestRunConcurrency(t *testing.T) {
+	w, c := newWiki(t)
+
+	dir := t.TempDir()
+	for _, n := range []string{"a", "b", "c", "d", "e", "f"} {
+		writeFiles(t, dir, map[string]string{n + ".jpg": n})
+	}
+
+	u := &Uploader{Client: c, Concurrency: 2}
+	s, err := u.Run(context.Background(), dir)
+	require.NoError(t, err)
+	assert.Equal(t, Summary{mediawiki.UploadStatusUploaded: 6}, s)
+	assert.Equal(t, 2, w.maxActive)
+
+	// Starts are spaced by the interval.
+	w.starts = nil
+	u = &Uploader{Client: c, Concurrency: 6, Interval: 30 * time.Millisecond}
+	_, err = u.Run(context.Background(), dir)
+	require.NoError(t, err)
+	require.Len(t, w.starts, 6)
+	assert.GreaterOrEqual(t, w.starts[5].Sub(w.starts[0]), 5*25*time.Millisecond)
+}
+
+func TestRunCancel(t *testing.T) {
+	_, c := newWiki(t)
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.jpg": "a", "b.jpg": "b"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	u := &Uploader{Client: c}
+	s, err := u.Run(ctx, dir)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, s)
+}
+
+func TestReadLog(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "upload.log")
+	require.NoError(t, os.WriteFile(log, []byte(strings.Join([]string{
+		`{"file":"a.jpg","size":1,"status":"uploaded"}`,
+		`{"file":"b.jpg","size":1,"status":"uploaded"}`,
+		`{"file":"b.jpg","size":2,"status":"error"}`,
+		`{"file":"c.jpg","size":1,"status":"skipped"}`,
+		`{"file":"d.jpg","si`,
+	}, "\n")), 0o644))
+
+	done, err := readLog(log)
+	require.NoError(t, err)
+	assert.Len(t, done, 2)
+	assert.Contains(t, done, "a.jpg")
+	assert.Contains(t, done, "c.jpg")
+
+	done, err = readLog(filepath.Join(t.TempDir(), "missing.log"))
+	require.NoError(t, err)
+	assert.Empty(t, done)
+}
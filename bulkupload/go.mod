@@ -0,0 +1,17 @@
+module github.com/clockworksoul/mediawiki/bulkupload
+
+go 1.21
+
+require (
+	github.com/clockworksoul/mediawiki v0.0.0
+	github.com/stretchr/testify v1.8.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/text v0.4.0 // indirect
+)
+
+replace github.com/clockworksoul/mediawiki => ../
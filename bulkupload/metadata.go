@@ -0,0 +1,167 @@
+package bulkupload
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Metadata describes a file to upload. It is read from the sidecar of
+// the file or from its row of the manifest.
+type Metadata struct {
+	// Filename is the name of the file on the wiki. If it is empty, the
+	// base name of the local file is used.
+	Filename string `json:"filename" yaml:"filename"`
+
+	Description string `json:"description" yaml:"description"`
+	Author      string `json:"author" yaml:"author"`
+	Source      string `json:"source" yaml:"source"`
+	Date        string `json:"date" yaml:"date"`
+
+	// License is the wikitext of the license, such as
+	// {{self|cc-by-sa-4.0}}.
+	License string `json:"license" yaml:"license"`
+
+	// Categories are the names of the categories of the file, without
+	// the namespace.
+	Categories []string `json:"categories" yaml:"categories"`
+
+	// Fields holds any other fields, for use by custom templates.
+	Fields map[string]string `json:"fields" yaml:"fields"`
+}
+
+// sidecarExtensions are the extensions of sidecar files, which are named
+// after the file they describe, as in photo.jpg.yaml.
+var sidecarExtensions = []string{".yaml", ".yml", ".json"}
+
+// isSidecar reports whether the file is a sidecar.
+func isSidecar(path string) bool {
+	for _, ext := range sidecarExtensions {
+		if strings.HasSuffix(path, ext) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readSidecar reads the metadata of the file from its sidecar. It returns
+// empty metadata if the file has no sidecar.
+func readSidecar(path string) (Metadata, error) {
+	var m Metadata
+
+	for _, ext := range sidecarExtensions {
+		b, err := os.ReadFile(path + ext)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return m, err
+		}
+
+		if ext == ".json" {
+			err = json.Unmarshal(b, &m)
+		} else {
+			err = yaml.Unmarshal(b, &m)
+		}
+		if err != nil {
+			return m, fmt.Errorf("invalid sidecar %s: %w", path+ext, err)
+		}
+
+		return m, nil
+	}
+
+	return m, nil
+}
+
+// Columns of the manifest. Other columns are added to the Fields of the
+// metadata.
+const (
+	columnFile        = "file"
+	columnFilename    = "filename"
+	columnDescription = "description"
+	columnAuthor      = "author"
+	columnSource      = "source"
+	columnDate        = "date"
+	columnLicense     = "license"
+	columnCategories  = "categories"
+)
+
+// readManifest reads a CSV manifest, whose first row names the columns.
+// The file column holds the path of each file, relative to the
+// directory, and the categories column is separated by semicolons. It
+// returns the metadata by path, and the paths in the order of the rows.
+func readManifest(r io.Reader) (map[string]Metadata, []string, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	for i, h := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(h))
+	}
+	if !slices.Contains(header, columnFile) {
+		return nil, nil, fmt.Errorf("invalid manifest: no %s column", columnFile)
+	}
+
+	files := map[string]Metadata{}
+	var order []string
+
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("invalid manifest: %w", err)
+		}
+
+		var m Metadata
+		var file string
+		for i, v := range row {
+			switch header[i] {
+			case columnFile:
+				file = filepath.Clean(filepath.FromSlash(v))
+			case columnFilename:
+				m.Filename = v
+			case columnDescription:
+				m.Description = v
+			case columnAuthor:
+				m.Author = v
+			case columnSource:
+				m.Source = v
+			case columnDate:
+				m.Date = v
+			case columnLicense:
+				m.License = v
+			case columnCategories:
+				for _, c := range strings.Split(v, ";") {
+					if c = strings.TrimSpace(c); c != "" {
+						m.Categories = append(m.Categories, c)
+					}
+				}
+			default:
+				if m.Fields == nil {
+					m.Fields = map[string]string{}
+				}
+				m.Fields[header[i]] = v
+			}
+		}
+
+		if _, ok := files[file]; ok {
+			return nil, nil, fmt.Errorf("invalid manifest: %s is listed twice", file)
+		}
+		files[file] = m
+		order = append(order, file)
+	}
+
+	return files, order, nil
+}
@@ -0,0 +1,56 @@
+package bulkupload
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadManifest(t *testing.T) {
+	meta, order, err := readManifest(strings.NewReader(`File, Description, Categories, License
+a/b.jpg, "A file, with a comma", "X;;Y ", {{PD}}
+c.jpg,,,
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.FromSlash("a/b.jpg"), "c.jpg"}, order)
+	assert.Equal(t, Metadata{
+		Description: "A file, with a comma",
+		Categories:  []string{"X", "Y"},
+		License:     "{{PD}}",
+	}, meta[filepath.FromSlash("a/b.jpg")])
+	assert.Equal(t, Metadata{}, meta["c.jpg"])
+
+	_, _, err = readManifest(strings.NewReader("name,description\na.jpg,A\n"))
+	assert.ErrorContains(t, err, "no file column")
+
+	_, _, err = readManifest(strings.NewReader("file\na.jpg\n./a.jpg\n"))
+	assert.ErrorContains(t, err, "listed twice")
+
+	_, _, err = readManifest(strings.NewReader("file,description\na.jpg\n"))
+	assert.Error(t, err)
+}
+
+func TestReadSidecar(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "a.jpg")
+
+	m, err := readSidecar(p)
+	require.NoError(t, err)
+	assert.Equal(t, Metadata{}, m)
+
+	require.NoError(t, os.WriteFile(p+".yml", []byte("description: A\nfields:\n  camera: X100\n"), 0o644))
+	m, err = readSidecar(p)
+	require.NoError(t, err)
+	assert.Equal(t, Metadata{Description: "A", Fields: map[string]string{"camera": "X100"}}, m)
+
+	require.NoError(t, os.WriteFile(p+".yaml", []byte("description: [unclosed"), 0o644))
+	_, err = readSidecar(p)
+	assert.ErrorContains(t, err, "invalid sidecar")
+
+	assert.True(t, isSidecar("a.jpg.json"))
+	assert.False(t, isSidecar("a.jpg"))
+}
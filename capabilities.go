@@ -0,0 +1,270 @@
+package mediawiki
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// UnsupportedError is returned when a request uses a module or parameter
+// the wiki doesn't have, typically because it runs an older version of
+// MediaWiki or lacks an extension. Parameter is empty if the module
+// itself is missing.
+type UnsupportedError struct {
+	Module    string
+	Parameter string
+
+	// Version is the MediaWiki version of the wiki, if known.
+	Version string
+}
+
+func (e *UnsupportedError) Error() string {
+	s := fmt.Sprintf("unsupported: module %q", e.Module)
+	if e.Parameter != "" {
+		s = fmt.Sprintf("unsupported: parameter %q of module %q", e.Parameter, e.Module)
+	}
+	if e.Version != "" {
+		s += " on MediaWiki " + e.Version
+	}
+	return s
+}
+
+// capabilities caches the paraminfo of the modules used so far. A nil
+// module is one the wiki doesn't have.
+type capabilities struct {
+	sync.Mutex
+	modules map[string]*ParaminfoModule
+}
+
+// paraminfoBatch is the number of modules fetched per paraminfo request.
+const paraminfoBatch = 50
+
+// Module returns the parameter information of the API module with the
+// given path, such as "edit" or "query+revisions". Module information
+// is fetched with action=paraminfo the first time it is needed, and
+// cached until InvalidateSite is called. If the wiki doesn't have the
+// module, the error is an *UnsupportedError.
+func (c *Client) Module(ctx context.Context, path string) (*ParaminfoModule, error) {
+	mods, err := c.modules(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if mods[path] == nil {
+		return nil, c.unsupported(path, "")
+	}
+	return mods[path], nil
+}
+
+// Supports reports whether the wiki has the API module with the given
+// path and, if param is not empty, whether the module has the parameter.
+// param is the parameter name without the module prefix, such as
+// "slots" for rvslots.
+func (c *Client) Supports(ctx context.Context, module, param string) (bool, error) {
+	err := c.Require(ctx, module, param)
+
+	var ue *UnsupportedError
+	if errors.As(err, &ue) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Require returns an *UnsupportedError if the wiki doesn't have the API
+// module with the given path or any of the parameters, given without
+// the module prefix.
+func (c *Client) Require(ctx context.Context, module string, params ...string) error {
+	m, err := c.Module(ctx, module)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range params {
+		if p == "" || m.Dynamicparameters != nil {
+			continue
+		}
+		if _, ok := m.Parameter(p); !ok {
+			return c.unsupported(module, p)
+		}
+	}
+
+	return nil
+}
+
+// modules returns the paraminfo of the modules with the given paths,
+// fetching the ones that aren't cached yet. The cache isn't locked while
+// they are fetched.
+func (c *Client) modules(ctx context.Context, paths ...string) (map[string]*ParaminfoModule, error) {
+	c.caps.Lock()
+	var missing []string
+	for _, p := range paths {
+		if _, ok := c.caps.modules[p]; !ok && !slices.Contains(missing, p) {
+			missing = append(missing, p)
+		}
+	}
+	c.caps.Unlock()
+
+	fetched := map[string]*ParaminfoModule{}
+	for len(missing) > 0 {
+		batch := missing[:min(len(missing), paraminfoBatch)]
+		missing = missing[len(batch):]
+
+		mods, err := c.fetchModules(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range batch {
+			fetched[p] = nil
+		}
+		for i := range mods {
+			fetched[mods[i].Path] = &mods[i]
+		}
+	}
+
+	c.caps.Lock()
+	defer c.caps.Unlock()
+
+	if c.caps.modules == nil {
+		c.caps.modules = map[string]*ParaminfoModule{}
+	}
+	for p, m := range fetched {
+		c.caps.modules[p] = m
+	}
+
+	out := make(map[string]*ParaminfoModule, len(paths))
+	for _, p := range paths {
+		out[p] = c.caps.modules[p]
+	}
+	return out, nil
+}
+
+// fetchModules fetches the paraminfo of the modules with the given
+// paths. Unlike ParaminfoClient.Do, it doesn't check the session: it is
+// called while gating requests, including those of the keep-alive login,
+// which would wait for themselves.
+func (c *Client) fetchModules(ctx context.Context, paths []string) ([]ParaminfoModule, error) {
+	r := ParaminfoResponse{}
+	_, err := c.GetInto(ctx, Values{
+		"action":     "paraminfo",
+		"helpformat": "none",
+		"modules":    strings.Join(paths, "|"),
+	}, &r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get: %w", err)
+	}
+
+	if e := r.Error; e != nil {
+		return nil, fmt.Errorf("%s: %s", e.Code, e.Info)
+	} else if r.Paraminfo == nil {
+		return nil, fmt.Errorf("unexpected error in paraminfo")
+	}
+
+	return r.Paraminfo.Modules, nil
+}
+
+// unsupported returns an *UnsupportedError, with the MediaWiki version
+// if the site metadata has already been loaded.
+func (c *Client) unsupported(module, param string) *UnsupportedError {
+	e := &UnsupportedError{Module: module, Parameter: param}
+
+	if c.SiteCache != nil {
+		if s, ok := c.SiteCache.Get(c.apiURL.String()); ok {
+			if v, err := s.Version(); err == nil {
+				e.Version = v.String()
+			}
+		}
+	}
+
+	return e
+}
+
+// gate checks that the wiki supports the module and all parameters of
+// a request. Query submodules are recognized by their prefix, and the
+// parameters of the output format, such as formatversion, are looked up
+// in its module.
+func (c *Client) gate(ctx context.Context, v Values) error {
+	action := v["action"]
+	if action == "" || action == "paraminfo" {
+		return nil
+	}
+
+	paths := []string{"main", action}
+	generator := ""
+	if action == "query" {
+		for _, k := range []string{"prop", "list", "meta"} {
+			for _, s := range strings.Split(v[k], "|") {
+				if s != "" {
+					paths = append(paths, "query+"+s)
+				}
+			}
+		}
+		if g := v["generator"]; g != "" {
+			generator = "query+" + g
+			paths = append(paths, generator)
+		}
+	}
+
+	format := v["format"]
+	fetch := paths
+	if format != "" {
+		fetch = append(slices.Clip(paths), format)
+	}
+
+	mods, err := c.modules(ctx, fetch...)
+	if err != nil {
+		return err
+	}
+	for _, p := range paths[1:] {
+		if mods[p] == nil {
+			return c.unsupported(p, "")
+		}
+	}
+
+	has := func(m *ParaminfoModule, name string) bool {
+		if m == nil {
+			return false
+		}
+		_, ok := m.Parameter(name)
+		return ok || m.Dynamicparameters != nil
+	}
+
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		if has(mods["main"], k) || has(mods[action], k) || has(mods[format], k) {
+			continue
+		}
+
+		module, param := action, k
+		ok := false
+		for _, p := range paths[2:] {
+			prefix := mods[p].Prefix
+			if p == generator {
+				prefix = "g" + prefix
+			}
+			if !strings.HasPrefix(k, prefix) {
+				continue
+			}
+			if has(mods[p], k[len(prefix):]) {
+				ok = true
+				break
+			}
+			if prefix != "" {
+				module, param = p, k[len(prefix):]
+			}
+		}
+
+		if !ok {
+			return c.unsupported(module, param)
+		}
+	}
+
+	return nil
+}
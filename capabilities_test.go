@@ -0,0 +1,187 @@
+package mediawiki
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clockworksoul/mediawiki/internal/wikitest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// oldWikiModules describe a wiki from before MediaWiki 1.32, without
+// rvslots and watchlistexpiry.
+var oldWikiModules = map[string]map[string]any{
+	"main":            {"prefix": "", "params": []string{"action", "format", "maxlag", "curtimestamp", "errorformat"}},
+	"json":            {"prefix": "", "params": []string{"callback", "utf8", "ascii", "formatversion"}},
+	"query":           {"prefix": "", "params": []string{"prop", "list", "meta", "titles", "pageids", "revids", "generator", "continue"}},
+	"query+revisions": {"prefix": "rv", "params": []string{"prop", "limit", "section", "continue"}},
+	"query+tokens":    {"prefix": "", "params": []string{"type"}},
+	"query+allpages":  {"prefix": "ap", "params": []string{"from", "limit"}},
+	"query+info":      {"prefix": "in", "params": []string{"prop"}},
+	"move":            {"prefix": "", "params": []string{"from", "to", "reason", "token", "watchlist"}},
+	"edit": {
+		"prefix": "", "params": []string{"title", "text", "token", "summary"},
+		"templated": []any{map[string]any{"name": "{slot}-content", "templatevars": map[string]string{"slot": "slots"}}},
+	},
+}
+
+// newOldWiki returns a wiki answering paraminfo queries from
+// oldWikiModules, and counting the other requests by action.
+func newOldWiki(t *testing.T) (*Client, *[]string, map[string]int) {
+	t.Helper()
+
+	var paraminfo []string
+	actions := map[string]int{}
+
+	s := wikitest.New(t, map[string]http.HandlerFunc{
+		"paraminfo": func(w http.ResponseWriter, r *http.Request) {
+			paraminfo = append(paraminfo, r.Form.Get("modules"))
+
+			var mods []any
+			for _, path := range strings.Split(r.Form.Get("modules"), "|") {
+				m, ok := oldWikiModules[path]
+				if !ok {
+					continue
+				}
+				var params []any
+				for _, p := range m["params"].([]string) {
+					params = append(params, map[string]any{"name": p, "type": "string"})
+				}
+				mods = append(mods, map[string]any{
+					"path": path, "prefix": m["prefix"], "parameters": params, "templatedparameters": m["templated"],
+				})
+			}
+			json.NewEncoder(w).Encode(map[string]any{"paraminfo": map[string]any{"modules": mods}})
+		},
+
+		"query+revisions": func(w http.ResponseWriter, r *http.Request) {
+			actions["revisions"]++
+			assert.Empty(t, r.Form.Get("rvslots"))
+			w.Write([]byte(`{"query":{"pages":[{"ns":0,"title":"Foo","revisions":[{"revid":3,"contentmodel":"wikitext","content":"Hello"}]}]}}`))
+		},
+
+		"*": func(w http.ResponseWriter, r *http.Request) {
+			actions[r.Form.Get("action")]++
+			w.Write([]byte(`{}`))
+		},
+	})
+
+	c, err := New(s.URL, agent)
+	require.NoError(t, err)
+
+	return c, &paraminfo, actions
+}
+
+func TestCapabilitiesAdapt(t *testing.T) {
+	c, paraminfo, actions := newOldWiki(t)
+	c.CheckCapabilities = true
+	c.Maxlag.On = true
+	ctx := context.Background()
+
+	r, err := c.Revisions().Titles("Foo").Prop("content").Do(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, actions["revisions"])
+	assert.Equal(t, "Hello", r.Query.Pages[0].Revisions[0].Slots["main"].Content)
+	assert.Equal(t, "wikitext", r.Query.Pages[0].Revisions[0].Slots["main"].Contentmodel)
+
+	_, err = c.Revisions().Titles("Foo").Prop("content").Do(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"query+revisions", "main|query|json"}, *paraminfo, "module information is cached")
+}
+
+func TestCapabilitiesFailEarly(t *testing.T) {
+	c, _, actions := newOldWiki(t)
+	c.CheckCapabilities = true
+	ctx := context.Background()
+
+	_, err := c.Move().From("Foo").To("Bar").Watchlistexpiry("1 week").Do(ctx)
+
+	var ue *UnsupportedError
+	require.True(t, errors.As(err, &ue), "%v", err)
+	assert.Equal(t, &UnsupportedError{Module: "move", Parameter: "watchlistexpiry"}, ue)
+	assert.Zero(t, actions["move"], "the request isn't sent")
+
+	_, err = c.Allpages().Limit(10).Do(ctx)
+	require.NoError(t, err)
+
+	_, err = c.Linkshere().Titles("Foo").Do(ctx)
+	require.True(t, errors.As(err, &ue), "%v", err)
+	assert.Equal(t, "query+linkshere", ue.Module)
+	assert.Empty(t, ue.Parameter)
+
+	_, err = c.Edit().Title("Foo").Text("x").Do(ctx)
+	assert.False(t, errors.As(err, &ue), "%v", err)
+	assert.Equal(t, 1, actions["edit"])
+}
+
+func TestCapabilitiesSupports(t *testing.T) {
+	c, paraminfo, _ := newOldWiki(t)
+	ctx := context.Background()
+
+	ok, err := c.Supports(ctx, "query+revisions", "slots")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = c.Supports(ctx, "query+revisions", "section")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = c.Supports(ctx, "edit", "main-content")
+	require.NoError(t, err)
+	assert.True(t, ok, "templated parameters match")
+
+	ok, err = c.Supports(ctx, "query+linkshere", "")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	err = c.Require(ctx, "edit", "title", "watchlistexpiry")
+	assert.EqualError(t, err, `unsupported: parameter "watchlistexpiry" of module "edit"`)
+
+	m, err := c.Module(ctx, "query+revisions")
+	require.NoError(t, err)
+	assert.Equal(t, "rv", m.Prefix)
+
+	assert.Len(t, *paraminfo, 3)
+
+	c.InvalidateSite()
+	_, err = c.Module(ctx, "query+revisions")
+	require.NoError(t, err)
+	assert.Len(t, *paraminfo, 4)
+}
+
+func TestCapabilitiesKeepAlive(t *testing.T) {
+	c, paraminfo, _ := newOldWiki(t)
+	c.CheckCapabilities = true
+
+	// Without a session, the request logs in again, and the login
+	// requests are gated as well.
+	c.username, c.password, c.loginBot = "Bot", "secret", true
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Revisions().Titles("Foo").Prop("content").Do(context.Background())
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the keep-alive login waits for itself")
+	}
+	assert.NotEmpty(t, *paraminfo)
+}
+
+func TestParaminfoParameterValues(t *testing.T) {
+	var p ParaminfoParameter
+	require.NoError(t, json.Unmarshal([]byte(`{"name":"dir","type":["newer","older"]}`), &p))
+	assert.Equal(t, []string{"newer", "older"}, p.Values())
+
+	require.NoError(t, json.Unmarshal([]byte(`{"name":"limit","type":"limit"}`), &p))
+	assert.Nil(t, p.Values())
+}
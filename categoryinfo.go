@@ -14,19 +14,19 @@ type CategoryInfoResponse struct {
 
 type CategoryInfoResponseQuery struct {
 	QueryResponseQuery
-	Pages map[string]QueryResponseQueryPage `json:"pages"`
+	Pages []CategoryInfoResponsePage `json:"pages"`
 }
 
 type CategoryInfoResponsePage struct {
 	QueryResponseQueryPage
-	CategoryInfo map[string]CategoryInfoResponsePagesCategoryInfo `json:"categoryinfo,omitempty"`
 }
 
 type CategoryInfoResponsePagesCategoryInfo struct {
-	Files   int `json:"files"`
-	Pages   int `json:"pages"`
-	Size    int `json:"size"`
-	Subcats int `json:"subcats"`
+	Files   int  `json:"files"`
+	Pages   int  `json:"pages"`
+	Size    int  `json:"size"`
+	Subcats int  `json:"subcats"`
+	Hidden  bool `json:"hidden,omitempty"`
 }
 
 type CategoryinfoClient struct {
@@ -189,7 +189,23 @@ func (w *CategoryinfoClient) Converttitles(b bool) *CategoryinfoClient {
 	return w
 }
 
+// Validate returns the problems with the page selection: titles,
+// pageids and revids can't be combined.
+func (w *CategoryinfoClient) Validate() error {
+	parameters := Values{}
+	for _, o := range w.o {
+		o(parameters)
+	}
+
+	return checkParams(parameters,
+		atMostOne("titles", "pageids", "revids"))
+}
+
 func (w *CategoryinfoClient) Do(ctx context.Context) (CategoryInfoResponse, error) {
+	if err := w.Validate(); err != nil {
+		return CategoryInfoResponse{}, err
+	}
+
 	if err := w.c.checkKeepAlive(ctx); err != nil {
 		return CategoryInfoResponse{}, err
 	}
@@ -8,9 +8,8 @@ import (
 
 type CategoryMembers struct {
 	QueryResponse
-	BatchComplete string                   `json:"batchcomplete"`
-	Continue      *CategoryMembersContinue `json:"continue,omitempty"`
-	Query         *CategoryMembersQuery    `json:"query"`
+	Continue *CategoryMembersContinue `json:"continue,omitempty"`
+	Query    *CategoryMembersQuery    `json:"query"`
 }
 
 type CategoryMembersQuery struct {
@@ -59,7 +58,23 @@ func (w *CategoryMembersClient) Limit(i int) *CategoryMembersClient {
 	return w
 }
 
+// Validate checks that the category is given either by Title or by
+// PageId.
+func (w *CategoryMembersClient) Validate() error {
+	parameters := Values{}
+	for _, o := range w.o {
+		o(parameters)
+	}
+
+	return checkParams(parameters,
+		exactlyOne("cmtitle", "cmpageid"))
+}
+
 func (w *CategoryMembersClient) Do(ctx context.Context) (CategoryMembers, error) {
+	if err := w.Validate(); err != nil {
+		return CategoryMembers{}, err
+	}
+
 	if err := w.c.checkKeepAlive(ctx); err != nil {
 		return CategoryMembers{}, err
 	}
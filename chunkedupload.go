@@ -0,0 +1,430 @@
+package mediawiki
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// This uploads large files in chunks: each chunk is added to a stashed
+// file, which the server assembles once all chunks are received, and the
+// stashed file is then published under its name.
+
+const (
+	// DefaultChunkSize is the size of the chunks sent by UploadLarge if
+	// ChunkSize isn't called.
+	DefaultChunkSize = 5 << 20
+
+	// DefaultChunkRetries is how many times UploadLarge retries a failed
+	// chunk if ChunkRetries isn't called.
+	DefaultChunkRetries = 3
+
+	// DefaultPollInterval is how often UploadLarge checks the status of
+	// an asynchronous upload if PollInterval isn't called.
+	DefaultPollInterval = 2 * time.Second
+)
+
+// chunkRetryDelay is the delay before the first retry of a chunk. It is
+// doubled for every further retry.
+var chunkRetryDelay = time.Second
+
+// UploadState is the progress of a chunked upload.
+type UploadState struct {
+	Filename string `json:"filename"`
+	Filesize int64  `json:"filesize"`
+
+	// Sha1 is the SHA-1 of the contents of the file, in hex. A state is
+	// only resumed for the same contents.
+	Sha1 string `json:"sha1"`
+
+	// Filekey identifies the stashed file the chunks are added to. It is
+	// empty until the first chunk is received.
+	Filekey string `json:"filekey,omitempty"`
+
+	// Offset is the number of bytes received by the server.
+	Offset int64 `json:"offset"`
+}
+
+// UploadStateStore holds the progress of chunked uploads. Implementations
+// must be safe for concurrent use.
+type UploadStateStore interface {
+	Load(key string) (UploadState, bool, error)
+	Save(key string, s UploadState) error
+	Delete(key string) error
+}
+
+// MemoryUploadStateStore is an UploadStateStore that keeps the states in
+// memory. It is the default store of a Client.
+type MemoryUploadStateStore struct {
+	mu     sync.Mutex
+	states map[string]UploadState
+}
+
+// NewMemoryUploadStateStore returns an empty MemoryUploadStateStore.
+func NewMemoryUploadStateStore() *MemoryUploadStateStore {
+	return &MemoryUploadStateStore{states: map[string]UploadState{}}
+}
+
+func (m *MemoryUploadStateStore) Load(key string) (UploadState, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.states[key]
+	return s, ok, nil
+}
+
+func (m *MemoryUploadStateStore) Save(key string, s UploadState) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.states[key] = s
+	return nil
+}
+
+func (m *MemoryUploadStateStore) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.states, key)
+	return nil
+}
+
+// FileUploadStateStore is an UploadStateStore that keeps each state in a
+// JSON file of a directory, so that uploads can be resumed by another
+// process.
+type FileUploadStateStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileUploadStateStore returns a FileUploadStateStore keeping its
+// files in dir, which is created as needed.
+func NewFileUploadStateStore(dir string) *FileUploadStateStore {
+	return &FileUploadStateStore{dir: dir}
+}
+
+func (f *FileUploadStateStore) path(key string) string {
+	h := sha1.Sum([]byte(key))
+	return filepath.Join(f.dir, hex.EncodeToString(h[:])+".json")
+}
+
+func (f *FileUploadStateStore) Load(key string) (UploadState, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var s UploadState
+
+	b, err := os.ReadFile(f.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return s, false, nil
+	} else if err != nil {
+		return s, false, err
+	}
+
+	if err := json.Unmarshal(b, &s); err != nil {
+		return s, false, fmt.Errorf("invalid upload state %s: %w", f.path(key), err)
+	}
+
+	return s, true, nil
+}
+
+func (f *FileUploadStateStore) Save(key string, s UploadState) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(f.dir, 0o755); err != nil {
+		return err
+	}
+
+	// The state is renamed into place, so that it is never left half
+	// written.
+	tmp := f.path(key) + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, f.path(key))
+}
+
+func (f *FileUploadStateStore) Delete(key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	err := os.Remove(f.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+// ChunkSize
+// Size of the chunks sent by UploadLarge.
+// The server may require chunks of at least 1 KiB, except for the last one.
+func (w *UploadClient) ChunkSize(n int64) *UploadClient {
+	w.chunkSize = n
+	return w
+}
+
+// ChunkRetries
+// Number of times UploadLarge retries a chunk that failed because of a
+// network or server error.
+func (w *UploadClient) ChunkRetries(n int) *UploadClient {
+	w.chunkRetries = &n
+	return w
+}
+
+// PollInterval
+// How often UploadLarge checks the status of an asynchronous upload.
+func (w *UploadClient) PollInterval(d time.Duration) *UploadClient {
+	w.pollInterval = d
+	return w
+}
+
+// UploadLarge uploads the size bytes of r as the file name in chunks. See
+// UploadClient.UploadLarge.
+func (c *Client) UploadLarge(ctx context.Context, name string, r io.ReaderAt, size int64) (UploadResponse, error) {
+	return c.Upload().UploadLarge(ctx, name, r, size)
+}
+
+// UploadLarge uploads the size bytes of r as the file name in chunks, for
+// files too large to be sent in one request. The chunks are stashed on
+// the server, and failed chunks are retried. The progress is saved in
+// Client.UploadStates, so that calling UploadLarge again for the same
+// file resumes an interrupted upload. The contents are hashed to make
+// sure they haven't changed since, which reads r once more.
+//
+// Once all chunks are received, the stashed file is published with the
+// other parameters of the client, such as Comment and Text. Progress
+// reports the bytes sent out of the whole file. Assembling
+// and publishing are asynchronous if the wiki supports it, and
+// UploadLarge waits for them to finish. If the upload ends with a
+// Warning result, the stashed file is kept, and calling UploadLarge
+// again with Ignorewarnings publishes it without sending it again.
+//
+// In dry-run mode, the upload is recorded as a single request.
+func (w *UploadClient) UploadLarge(ctx context.Context, name string, r io.ReaderAt, size int64) (UploadResponse, error) {
+	if w.c.isDryRun(w.dryRun) {
+		return w.publish(name, "").File(io.NewSectionReader(r, 0, size)).Do(ctx)
+	}
+
+	h := sha1.New()
+	if _, err := io.Copy(h, io.NewSectionReader(r, 0, size)); err != nil {
+		return UploadResponse{}, fmt.Errorf("error reading %s: %w", name, err)
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+
+	key := w.c.uploadStateKey(name, size)
+	store := w.c.uploadStates()
+
+	state, ok, err := store.Load(key)
+	if err != nil {
+		return UploadResponse{}, err
+	}
+	if !ok || state.Sha1 != sum {
+		state = UploadState{Filename: name, Filesize: size, Sha1: sum}
+	}
+
+	// The saved offset lags behind the server's if the upload was
+	// interrupted before it could be saved.
+	if state.Filekey != "" {
+		res, err := w.status(ctx, name, state.Filekey)
+		if err == nil && res.Upload.Result != Continue {
+			res, err = w.wait(ctx, name, state.Filekey, res)
+		}
+
+		switch {
+		case err != nil && !stashFailed(res):
+			return res, err
+		case err != nil:
+			// The stashed file has expired, or assembling it failed, so
+			// start over.
+			state = UploadState{Filename: name, Filesize: size, Sha1: sum}
+		case res.Upload.Result == Continue:
+			state.Offset = res.Upload.Offset
+		default:
+			state.Offset = size
+		}
+	}
+
+	for state.Offset < size {
+		n := min(or(w.chunkSize, DefaultChunkSize), size-state.Offset)
+
+		res, err := w.sendChunk(ctx, state, io.NewSectionReader(r, state.Offset, n))
+		if err != nil {
+			return res, fmt.Errorf("chunk at offset %d: %w", state.Offset, err)
+		}
+
+		state.Filekey = res.Upload.FileKey
+		if res.Upload.Result == Continue {
+			state.Offset = res.Upload.Offset
+		} else {
+			state.Offset = size
+		}
+
+		if err := store.Save(key, state); err != nil {
+			return res, err
+		}
+
+		if _, err := w.wait(ctx, name, state.Filekey, res); err != nil {
+			return res, err
+		}
+	}
+
+	res, err := w.publish(name, state.Filekey).Do(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	res, err = w.wait(ctx, name, state.Filekey, res)
+	if err != nil {
+		return res, err
+	}
+
+	if res.Upload.Result == Success {
+		err = store.Delete(key)
+	} else if res.Upload.FileKey == "" {
+		res.Upload.FileKey = state.Filekey
+	}
+
+	return res, err
+}
+
+// uploadStateKey returns the key of the state of the upload of a file of
+// the size under the name. The state holds the SHA-1 of the contents,
+// which tells different files of the same size apart.
+func (w *Client) uploadStateKey(name string, size int64) string {
+	return strings.Join([]string{w.apiURL.String(), name, strconv.FormatInt(size, 10)}, "|")
+}
+
+// uploadStates returns the UploadStates of the client, or a store that
+// forgets the states if it is nil.
+func (w *Client) uploadStates() UploadStateStore {
+	if w.UploadStates == nil {
+		return NewMemoryUploadStateStore()
+	}
+
+	return w.UploadStates
+}
+
+// sub returns an upload client sharing the client and dry-run setting of
+// w, but none of its parameters.
+func (w *UploadClient) sub(name string) *UploadClient {
+	return (&UploadClient{c: w.c, dryRun: w.dryRun}).Filename(name)
+}
+
+// sendChunk sends the chunk at the offset of the state, retrying it if
+// it fails because of a network or server error.
+func (w *UploadClient) sendChunk(ctx context.Context, state UploadState, chunk *io.SectionReader) (UploadResponse, error) {
+	retries := DefaultChunkRetries
+	if w.chunkRetries != nil {
+		retries = *w.chunkRetries
+	}
+
+	for attempt := 0; ; attempt++ {
+		c := w.sub(state.Filename).Chunk(io.NewSectionReader(chunk, 0, chunk.Size())).Stash(true).Async(true)
+		if w.progress != nil {
+			c.Progress(func(sent, _ int64) {
+				w.progress(state.Offset+sent, state.Filesize)
+			})
+		}
+		c.o = append(c.o, func(m map[string]string) {
+			m["filesize"] = strconv.FormatInt(state.Filesize, 10)
+			m["offset"] = strconv.FormatInt(state.Offset, 10)
+			if state.Filekey != "" {
+				m["filekey"] = state.Filekey
+			}
+		})
+
+		res, err := c.Do(ctx)
+		if err == nil {
+			return res, nil
+		}
+
+		if attempt == retries || ctx.Err() != nil || !retryableChunkError(res) {
+			return res, err
+		}
+
+		if err := sleep(ctx, chunkRetryDelay<<attempt); err != nil {
+			return res, err
+		}
+
+		// The chunk may have been received even though its response was
+		// lost, in which case it must not be sent again.
+		if state.Filekey != "" {
+			s, err := w.status(ctx, state.Filename, state.Filekey)
+			if err == nil && (s.Upload.Result != Continue || s.Upload.Offset >= state.Offset+chunk.Size()) {
+				s.Upload.FileKey = state.Filekey
+				return s, nil
+			}
+		}
+	}
+}
+
+// retryableChunkError reports whether the failed chunk upload of res may
+// succeed if sent again: if the request failed, or the server failed to
+// store the chunk.
+func retryableChunkError(res UploadResponse) bool {
+	if res.Error == nil {
+		return true
+	}
+
+	return res.Error.Code == "stashfailed" || strings.HasPrefix(res.Error.Code, "internal_api_error")
+}
+
+// stashFailed reports whether the failed request res shows that the
+// stashed file can't be used anymore: the server doesn't know it, or
+// assembling or publishing it failed.
+func stashFailed(res UploadResponse) bool {
+	return res.Error != nil || (res.Upload != nil && res.Upload.Result == Failure)
+}
+
+// status returns the status of the stashed upload of the file key.
+func (w *UploadClient) status(ctx context.Context, name, filekey string) (UploadResponse, error) {
+	return w.sub(name).Filekey(filekey).Checkstatus(true).Do(ctx)
+}
+
+// wait polls the status of the stashed upload of the file key until res
+// is no longer an asynchronous upload in progress.
+func (w *UploadClient) wait(ctx context.Context, name, filekey string, res UploadResponse) (UploadResponse, error) {
+	for res.Upload.Result == Poll {
+		if err := sleep(ctx, or(w.pollInterval, DefaultPollInterval)); err != nil {
+			return res, err
+		}
+
+		var err error
+		res, err = w.status(ctx, name, filekey)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	return res, nil
+}
+
+// publish returns a client publishing the stashed file of the file key
+// under its name, with the parameters of w.
+func (w *UploadClient) publish(name, filekey string) *UploadClient {
+	c := &UploadClient{c: w.c, dryRun: w.dryRun, o: append([]UploadOption{}, w.o...)}
+	c.Filename(name)
+	if filekey != "" {
+		c.Filekey(filekey).Async(true)
+	}
+
+	return c
+}
@@ -0,0 +1,351 @@
+package mediawiki
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/clockworksoul/mediawiki/internal/wikitest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stashServer is a wiki that accepts chunked uploads to its stash, and
+// assembles and publishes them asynchronously.
+type stashServer struct {
+	mu        sync.Mutex
+	stash     map[string][]byte
+	polls     map[string]int
+	published map[string][]byte
+	comments  map[string]string
+
+	// offsets are the offsets of the chunks received.
+	offsets []int64
+
+	// failed are the stashed files whose assembly failed.
+	failed map[string]bool
+
+	// fail is called with the offset of every chunk, and returns the
+	// response sent instead of accepting it, if any. If keep is set, the
+	// chunk is accepted anyway.
+	fail func(offset int64) (status int, body string, keep bool)
+}
+
+func newStashServer(t *testing.T) (*stashServer, *wikitest.Wiki) {
+	t.Helper()
+
+	old := chunkRetryDelay
+	chunkRetryDelay = time.Millisecond
+	t.Cleanup(func() { chunkRetryDelay = old })
+
+	f := &stashServer{
+		stash:     map[string][]byte{},
+		polls:     map[string]int{},
+		published: map[string][]byte{},
+		comments:  map[string]string{},
+		failed:    map[string]bool{},
+	}
+
+	s := wikitest.New(t, map[string]http.HandlerFunc{
+		"upload":          f.upload,
+		"query+allimages": f.allimages,
+	})
+
+	return f, s
+}
+
+func (f *stashServer) upload(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := r.Form.Get("filekey")
+	reply := func(v map[string]any) {
+		b, _ := json.Marshal(map[string]any{"upload": v})
+		w.Write(b)
+	}
+
+	switch {
+	case r.Form.Get("checkstatus") != "":
+		data, ok := f.stash[key]
+		switch {
+		case !ok:
+			w.Write([]byte(`{"errors":[{"code":"missingresult","text":"No result in status data."}]}`))
+		case f.failed[key]:
+			reply(map[string]any{"result": Failure, "stage": "assembling"})
+		case f.polls[key] > 0:
+			f.polls[key]--
+			reply(map[string]any{"result": Poll, "stage": "assembling"})
+		case r.Form.Get("filename") != "" && f.published[r.Form.Get("filename")] != nil:
+			reply(map[string]any{"result": Success, "filename": r.Form.Get("filename")})
+		case int64(len(data)) < f.size(r):
+			reply(map[string]any{"result": Continue, "offset": len(data), "filekey": key})
+		default:
+			reply(map[string]any{"result": Success, "filekey": key})
+		}
+
+	case r.MultipartForm != nil && r.MultipartForm.File["chunk"] != nil:
+		fh := r.MultipartForm.File["chunk"][0]
+		file, _ := fh.Open()
+		chunk, _ := io.ReadAll(file)
+
+		offset, _ := strconv.ParseInt(r.Form.Get("offset"), 10, 64)
+		if key == "" {
+			key = fmt.Sprintf("key%d", len(f.stash)+1)
+		}
+		if offset != int64(len(f.stash[key])) {
+			w.Write([]byte(`{"errors":[{"code":"stashfailed","text":"Invalid chunk offset"}]}`))
+			return
+		}
+
+		if f.fail != nil {
+			if status, body, keep := f.fail(offset); status != 0 {
+				if keep {
+					f.stash[key] = append(f.stash[key], chunk...)
+				}
+				w.WriteHeader(status)
+				w.Write([]byte(body))
+				return
+			}
+		}
+
+		f.offsets = append(f.offsets, offset)
+		f.stash[key] = append(f.stash[key], chunk...)
+		if n := int64(len(f.stash[key])); n < f.size(r) {
+			reply(map[string]any{"result": Continue, "offset": n, "filekey": key})
+			return
+		}
+
+		f.polls[key] = 2
+		reply(map[string]any{"result": Poll, "stage": "queued", "filekey": key})
+
+	default:
+		data, ok := f.stash[key]
+		if !ok {
+			w.Write([]byte(`{"errors":[{"code":"stashnosuchfilekey","text":"No such filekey."}]}`))
+			return
+		}
+
+		name := r.Form.Get("filename")
+		if warnings := f.warnings(name, data); len(warnings) > 0 && r.Form.Get("ignorewarnings") == "" {
+			reply(map[string]any{"result": Warning, "warnings": warnings, "filekey": key, "filename": name})
+			return
+		}
+
+		f.published[name] = data
+		f.comments[name] = r.Form.Get("comment")
+		f.polls[key] = 1
+		reply(map[string]any{"result": Poll, "stage": "publish"})
+	}
+}
+
+// warnings returns the warnings of publishing the data under the name.
+func (f *stashServer) warnings(name string, data []byte) map[string]any {
+	warnings := map[string]any{}
+
+	if strings.Contains(name, ":") {
+		warnings["badfilename"] = strings.ReplaceAll(name, ":", "-")
+	}
+
+	if strings.HasPrefix(name, "DSC") {
+		warnings["bad-prefix"] = "DSC"
+	}
+
+	if old, ok := f.published[name]; ok {
+		warnings["exists"] = name
+		if bytes.Equal(old, data) {
+			warnings["nochange"] = map[string]any{"timestamp": "2024-01-01T00:00:00Z"}
+		}
+	}
+
+	var dups []string
+	for n, b := range f.published {
+		if n != name && bytes.Equal(b, data) {
+			dups = append(dups, strings.ReplaceAll(n, " ", "_"))
+		}
+	}
+	if dups != nil {
+		sort.Strings(dups)
+		warnings["duplicate"] = dups
+	}
+
+	return warnings
+}
+
+// allimages lists the published files with the SHA-1 of the request.
+func (f *stashServer) allimages(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	images := []map[string]any{}
+	for n, b := range f.published {
+		if fmt.Sprintf("%x", sha1.Sum(b)) == r.Form.Get("aisha1") {
+			images = append(images, map[string]any{"name": strings.ReplaceAll(n, " ", "_"), "ns": 6, "title": "File:" + n})
+		}
+	}
+	sort.Slice(images, func(i, j int) bool { return images[i]["name"].(string) < images[j]["name"].(string) })
+
+	b, _ := json.Marshal(map[string]any{"batchcomplete": true, "query": map[string]any{"allimages": images}})
+	w.Write(b)
+}
+
+func (f *stashServer) size(r *http.Request) int64 {
+	n, _ := strconv.ParseInt(r.Form.Get("filesize"), 10, 64)
+	if n == 0 {
+		// checkstatus requests don't send the size.
+		return 10000
+	}
+	return n
+}
+
+func randomFile(t *testing.T, n int) []byte {
+	t.Helper()
+
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	require.NoError(t, err)
+
+	return b
+}
+
+func TestUploadLarge(t *testing.T) {
+	f, s := newStashServer(t)
+	data := randomFile(t, 10000)
+
+	attempts := map[int64]int{}
+	f.fail = func(offset int64) (int, string, bool) {
+		attempts[offset]++
+		switch {
+		case offset == 3000 && attempts[offset] == 1:
+			return http.StatusServiceUnavailable, "", false
+		case offset == 6000 && attempts[offset] == 1:
+			// The chunk is received, but the response is lost.
+			return http.StatusBadGateway, "", true
+		}
+		return 0, "", false
+	}
+
+	c, err := New(s.URL, agent)
+	require.NoError(t, err)
+
+	r, err := c.Upload().
+		Comment("big file").
+		ChunkSize(3000).
+		PollInterval(time.Millisecond).
+		UploadLarge(context.Background(), "Big.webm", bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	require.NotNil(t, r.Upload)
+	assert.Equal(t, Success, r.Upload.Result)
+	assert.Equal(t, "Big.webm", r.Upload.Filename)
+
+	assert.Equal(t, []int64{0, 3000, 9000}, f.offsets)
+	assert.Equal(t, data, f.published["Big.webm"])
+	assert.Equal(t, "big file", f.comments["Big.webm"])
+
+	_, ok, err := c.UploadStates.Load(s.URL + "|Big.webm|10000")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestUploadLargeResume(t *testing.T) {
+	f, s := newStashServer(t)
+	data := randomFile(t, 10000)
+	store := NewFileUploadStateStore(t.TempDir())
+	key := s.URL + "|Big.webm|10000"
+
+	f.fail = func(offset int64) (int, string, bool) {
+		if offset == 6000 {
+			return http.StatusOK, `{"errors":[{"code":"permissiondenied","text":"Denied."}]}`, false
+		}
+		return 0, "", false
+	}
+
+	c, err := New(s.URL, agent)
+	require.NoError(t, err)
+	c.UploadStates = store
+
+	_, err = c.Upload().ChunkSize(2000).UploadLarge(context.Background(), "Big.webm", bytes.NewReader(data), int64(len(data)))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "permissiondenied")
+	assert.Equal(t, []int64{0, 2000, 4000}, f.offsets)
+
+	state, ok, err := store.Load(key)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, int64(6000), state.Offset)
+
+	// The saved offset lags behind the server's.
+	state.Offset = 2000
+	require.NoError(t, store.Save(key, state))
+
+	f.fail = nil
+	f.offsets = nil
+
+	c, err = New(s.URL, agent)
+	require.NoError(t, err)
+	c.UploadStates = store
+
+	r, err := c.Upload().ChunkSize(2000).PollInterval(time.Millisecond).UploadLarge(context.Background(), "Big.webm", bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	assert.Equal(t, Success, r.Upload.Result)
+	assert.Equal(t, []int64{6000, 8000}, f.offsets)
+	assert.Equal(t, data, f.published["Big.webm"])
+
+	_, ok, err = store.Load(key)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	sum := fmt.Sprintf("%x", sha1.Sum(data))
+	upload := func() {
+		t.Helper()
+		f.offsets = nil
+		_, err = c.Upload().ChunkSize(5000).PollInterval(time.Millisecond).UploadLarge(context.Background(), "Big.webm", bytes.NewReader(data), int64(len(data)))
+		require.NoError(t, err)
+	}
+
+	// A stashed file that has expired is uploaded again.
+	require.NoError(t, store.Save(key, UploadState{Filename: "Big.webm", Filesize: 10000, Sha1: sum, Filekey: "expired", Offset: 4000}))
+	upload()
+	assert.Equal(t, []int64{0, 5000}, f.offsets)
+
+	// So is one whose assembly failed.
+	f.stash["failed"] = data[:4000]
+	f.failed["failed"] = true
+	require.NoError(t, store.Save(key, UploadState{Filename: "Big.webm", Filesize: 10000, Sha1: sum, Filekey: "failed", Offset: 4000}))
+	upload()
+	assert.Equal(t, []int64{0, 5000}, f.offsets)
+
+	// The state of a file of the same size but different contents isn't
+	// resumed.
+	f.stash["other"] = data[:4000]
+	require.NoError(t, store.Save(key, UploadState{Filename: "Big.webm", Filesize: 10000, Sha1: "0123", Filekey: "other", Offset: 4000}))
+	upload()
+	assert.Equal(t, []int64{0, 5000}, f.offsets)
+}
+
+func TestUploadLargeDryRun(t *testing.T) {
+	s := wikitest.New(t, nil)
+
+	c, err := New(s.URL, agent)
+	require.NoError(t, err)
+	c.DryRun = true
+
+	data := randomFile(t, 100)
+	r, err := c.UploadLarge(context.Background(), "Big.webm", bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	assert.True(t, r.Simulated)
+
+	reqs := c.Plan.Requests()
+	require.Len(t, reqs, 1)
+	assert.Equal(t, "Big.webm", reqs[0].File)
+}
@@ -6,10 +6,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -33,8 +35,74 @@ type Client struct {
 	// Use GetToken to obtain tokens.
 	Tokens *Tokens
 
+	// Logger receives one structured record per API call. Passwords,
+	// tokens and cookies are always redacted. If Logger is nil, nothing
+	// is logged.
+	Logger *slog.Logger
+
+	// LogBodies controls whether request and response bodies are
+	// included in the log output. Bodies are logged at debug level.
+	LogBodies BodyLogLevel
+
+	// Maxlag controls the use of the maxlag parameter.
+	Maxlag Maxlag
+
+	// Metrics receives counters and timings for every API call.
+	// If Metrics is nil, nothing is recorded.
+	Metrics Metrics
+
+	// Tracer creates a span for every API call. If Tracer is nil,
+	// no spans are created.
+	Tracer Tracer
+
+	// DryRun makes the write clients (Edit, Delete, Move, Protect and
+	// Upload) validate and build their requests, but record them in Plan
+	// instead of sending them. Each write client's DryRun method
+	// overrides this setting for a single call.
+	DryRun bool
+
+	// Plan holds the requests recorded in dry-run mode. New sets it, and
+	// it is created on the first dry-run request otherwise.
+	Plan      *Plan
+	planMutex sync.Mutex
+
+	// OnWarning is called with every warning returned by the API. If
+	// OnWarning is nil, warnings are only available in the responses.
+	OnWarning func(ctx context.Context, w ResponseWarning)
+
+	// WarningsAsErrors lists the codes of the warnings that make a call
+	// fail with a *WarningError, such as "deprecation". "*" matches all
+	// warnings. The response is still decoded.
+	WarningsAsErrors []string
+
+	// Deprecated: Use Logger instead. If Debug is set and Logger is nil,
+	// a text logger writing to Debug is used, and all bodies are logged.
 	Debug io.Writer
 
+	// SiteCache holds the site metadata loaded by Site, for SiteTTL, or
+	// DefaultSiteTTL if SiteTTL is zero. New sets it to a MemorySiteCache.
+	SiteCache SiteCache
+	SiteTTL   time.Duration
+	siteMutex sync.Mutex
+
+	// UploadStates holds the progress of the chunked uploads of
+	// UploadLarge, so that an interrupted upload can be resumed. New
+	// sets it to a MemoryUploadStateStore; use a FileUploadStateStore to
+	// resume uploads after a restart.
+	UploadStates UploadStateStore
+
+	// CheckCapabilities makes every request check, before it is sent,
+	// that the wiki supports its module and parameters, and fail with an
+	// *UnsupportedError if it doesn't. Some clients also adapt their
+	// requests to older wikis. The module information is fetched with
+	// action=paraminfo as needed and cached.
+	CheckCapabilities bool
+	caps              capabilities
+
+	// namespaces is loaded by Namespaces.
+	namespaces      *Namespaces
+	namespacesMutex sync.Mutex
+
 	// Used for keep-alive
 	lastLoginTime      time.Time
 	username, password string
@@ -42,6 +110,16 @@ type Client struct {
 	keepAliveMutex     sync.Mutex
 }
 
+// Maxlag holds the settings for the maxlag parameter. When On is true,
+// every request is sent with maxlag=Timeout, and requests rejected
+// because of replication lag are retried up to Retries times, waiting
+// for as long as the server's Retry-After header asks.
+type Maxlag struct {
+	On      bool
+	Timeout string
+	Retries int
+}
+
 type Token string
 
 type Tokens struct {
@@ -89,7 +167,15 @@ func New(inURL, userAgent string) (*Client, error) {
 		ua = DefaultUserAgent
 	}
 
-	client := &Client{}
+	client := &Client{
+		Maxlag: Maxlag{
+			On:      false,
+			Timeout: "5",
+			Retries: 3,
+		},
+		Plan:         &Plan{},
+		UploadStates: NewMemoryUploadStateStore(),
+	}
 	client.init(apiurl, ua)
 
 	return client, nil
@@ -109,42 +195,30 @@ func (w *Client) GetToken(ctx context.Context, token Token) (string, error) {
 		return t, nil
 	}
 
-	url := fmt.Sprintf("%s?format=json&action=query&meta=tokens&type=%s", w.apiURL.String(), token)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return "", fmt.Errorf("error requesting token: %w", err)
+	v := Values{
+		"format": "json",
+		"action": "query",
+		"meta":   "tokens",
+		"type":   string(token),
 	}
 
-	req.Header.Set("User-Agent", w.UserAgent)
+	b, _, err := w.call(ctx, v, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", w.apiURL.String()+"?"+v.Encode(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("error requesting token: %w", err)
+		}
 
-	// if w.Debug != nil {
-	// 	reqdump, err := httputil.DumpRequestOut(req, true)
-	// 	if err != nil {
-	// 		fmt.Fprintf(w.Debug, "Err dumping request: %v\n", err)
-	// 	} else {
-	// 		w.Debug.Write(reqdump)
-	// 	}
-	// }
+		req.Header.Set("User-Agent", w.UserAgent)
 
-	resp, err := w.Client.Do(req)
+		return req, nil
+	})
 	if err != nil {
 		return "", fmt.Errorf("error receiving token: %w", err)
 	}
 
-	// if w.Debug != nil {
-	// 	respdump, err := httputil.DumpResponse(resp, true)
-	// 	if err != nil {
-	// 		fmt.Fprintf(w.Debug, "Err dumping response: %v\n", err)
-	// 	} else {
-	// 		w.Debug.Write(respdump)
-	// 	}
-	// }
-
 	r := Response{}
-	err = ParseResponseReader(resp.Body, &r)
-	if err != nil {
-		return "", fmt.Errorf("error parsing response: %w", err)
+	if _, err := w.decodeInto(ctx, b, &r); err != nil {
+		return "", err
 	}
 
 	if e := r.Error; e != nil {
@@ -154,6 +228,7 @@ func (w *Client) GetToken(ctx context.Context, token Token) (string, error) {
 	ts := r.Query.Tokens[string(token)+"token"]
 	if ts != "" {
 		w.Tokens.m[token] = ts
+		w.metrics().TokenRefresh(token)
 	}
 
 	return ts, nil
@@ -199,84 +274,227 @@ func (w *Client) BotLogin(ctx context.Context, username, password string) (Respo
 func (w *Client) GetInto(ctx context.Context, v Values, a any) (string, error) {
 	v["format"] = "json"
 
-	query := w.apiURL.String() + "?" + v.Encode()
+	b, _, err := w.call(ctx, v, func(ctx context.Context) (*http.Request, error) {
+		query := w.apiURL.String() + "?" + v.Encode()
 
-	if w.Debug != nil {
-		fmt.Fprintln(w.Debug, query)
-	}
+		req, err := http.NewRequestWithContext(ctx, "GET", query, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error constructing GET: %w", err)
+		}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", query, nil)
+		req.Header.Set("User-Agent", w.UserAgent)
+
+		return req, nil
+	})
 	if err != nil {
-		return "", fmt.Errorf("error constructing GET: %w", err)
+		return "", fmt.Errorf("error executing Get: %w", err)
 	}
 
-	req.Header.Set("User-Agent", w.UserAgent)
+	return w.decodeInto(ctx, b, a)
+}
 
-	resp, err := w.Client.Do(req)
+func (w *Client) PostInto(ctx context.Context, v Values, a any) (string, error) {
+	v["format"] = "json"
+
+	b, _, err := w.call(ctx, v, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", w.apiURL.String(), strings.NewReader(v.Encode()))
+		if err != nil {
+			return nil, fmt.Errorf("error constructing POST: %w", err)
+		}
+
+		req.Header.Set("User-Agent", w.UserAgent)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		return req, nil
+	})
 	if err != nil {
-		return "", fmt.Errorf("error executing Get: %w", err)
+		return "", fmt.Errorf("error executing POST: %w", err)
 	}
 
-	b, _ := io.ReadAll(resp.Body)
+	return w.decodeInto(ctx, b, a)
+}
+
+// decodeInto indents the raw response body b, parses it into a, and
+// returns the indented JSON. If a embeds a CoreResponse, its errors are
+// normalized and its warnings handled.
+func (w *Client) decodeInto(ctx context.Context, b []byte, a any) (string, error) {
 	buf := &bytes.Buffer{}
 	json.Indent(buf, b, "", "  ")
 	j := buf.String()
 
-	if w.Debug != nil {
-		fmt.Fprintln(w.Debug, j)
-	}
-
-	err = ParseResponseReader(buf, a)
+	err := ParseResponseReader(buf, a)
 	if err != nil {
 		return j, fmt.Errorf("error parsing response: %w", err)
 	}
 
-	if w.Debug != nil {
-		fmt.Fprintln(w.Debug, "-----")
-		b, _ = json.MarshalIndent(a, "", "  ")
-		fmt.Fprintln(w.Debug, string(b))
+	if c, ok := a.(interface{ core() *CoreResponse }); ok {
+		r := c.core()
+		r.normalize()
+		if err := w.handleWarnings(ctx, r.Warnings); err != nil {
+			return j, err
+		}
 	}
 
 	return j, nil
 }
 
-func (w *Client) PostInto(ctx context.Context, v Values, a any) (string, error) {
-	v["format"] = "json"
+// prepare adds the parameters that call sends with every request to v.
+func (w *Client) prepare(v Values) {
+	v["formatversion"] = "2"
+	v["errorformat"] = "plaintext"
 
-	req, err := http.NewRequestWithContext(ctx, "POST", w.apiURL.String(), strings.NewReader(v.Encode()))
-	if err != nil {
-		return "", fmt.Errorf("error constructing POST: %w", err)
+	if w.Maxlag.On {
+		v["maxlag"] = w.Maxlag.Timeout
 	}
+}
 
-	req.Header.Set("User-Agent", w.UserAgent)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+// call executes the request built by newReq and returns the response
+// body and HTTP status code. newReq is invoked once per attempt, so it must build a fresh
+// body each time. call asks for formatversion=2 responses, with errors
+// and warnings in plain text. If maxlag is enabled, call adds the maxlag
+// parameter to v before the first attempt and retries while the server
+// reports replication lag. Every call is logged once it completes.
+func (w *Client) call(ctx context.Context, v Values, newReq func(context.Context) (*http.Request, error)) ([]byte, int, error) {
+	w.prepare(v)
+
+	if w.CheckCapabilities {
+		if err := w.gate(ctx, v); err != nil {
+			return nil, 0, err
+		}
+	}
 
-	resp, err := w.Client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("error executing POST: %w", err)
+	ctx, span := w.tracer().Start(ctx, "mediawiki."+v["action"], map[string]string{
+		"mediawiki.action": v["action"],
+		"mediawiki.module": moduleOf(v),
+		"mediawiki.title":  titleOf(v),
+	})
+
+	start := time.Now()
+	rec := callRecord{values: v}
+
+	for {
+		req, err := newReq(ctx)
+		if err != nil {
+			rec.err = err
+			break
+		}
+
+		rec.request = req
+
+		resp, err := w.Client.Do(req)
+		if err != nil {
+			rec.err = err
+			break
+		}
+
+		rec.response = resp
+		rec.body, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			rec.err = fmt.Errorf("error reading response: %w", err)
+			break
+		}
+
+		rec.code = peekErrorCode(rec.body)
+
+		if rec.code != "maxlag" || !w.Maxlag.On || rec.retries >= w.Maxlag.Retries {
+			break
+		}
+
+		wait := retryAfter(resp, 5*time.Second)
+		w.metrics().MaxlagWait(wait)
+
+		if err := sleep(ctx, wait); err != nil {
+			rec.err = err
+			break
+		}
+
+		rec.retries++
+		w.metrics().Retry(v["action"])
 	}
 
-	b, _ := io.ReadAll(resp.Body)
-	buf := &bytes.Buffer{}
-	json.Indent(buf, b, "", "  ")
-	j := buf.String()
+	rec.duration = time.Since(start)
+	w.logCall(ctx, rec)
+	w.instrumentCall(rec, span)
 
-	if w.Debug != nil {
-		fmt.Fprintln(w.Debug, j)
+	if rec.err != nil {
+		return nil, 0, rec.err
 	}
 
-	err = ParseResponseReader(buf, a)
-	if err != nil {
-		return j, fmt.Errorf("error parsing response: %w", err)
+	return rec.body, rec.response.StatusCode, nil
+}
+
+// instrumentCall reports the completed call rec to the client's metrics
+// and ends its span.
+func (w *Client) instrumentCall(rec callRecord, span Span) {
+	outcome := OutcomeSuccess
+	switch {
+	case rec.err != nil:
+		outcome = OutcomeTransportError
+	case rec.code != "":
+		outcome = OutcomeAPIError
 	}
 
-	if w.Debug != nil {
-		fmt.Fprintln(w.Debug, "-----")
-		b, _ = json.MarshalIndent(a, "", "  ")
-		fmt.Fprintln(w.Debug, string(b))
+	w.metrics().RequestDone(rec.values["action"], outcome, rec.duration)
+
+	attrs := map[string]string{
+		"mediawiki.retries": strconv.Itoa(rec.retries),
 	}
+	if rec.response != nil {
+		attrs["http.status_code"] = strconv.Itoa(rec.response.StatusCode)
+	}
+	if rec.code != "" {
+		attrs["mediawiki.error_code"] = rec.code
+	}
+	span.SetAttributes(attrs)
 
-	return j, nil
+	err := rec.err
+	if err == nil && rec.code != "" {
+		err = fmt.Errorf("api error: %s", rec.code)
+	}
+	span.End(err)
+}
+
+// sleep pauses for d, returning early with the context's error if ctx
+// is cancelled first.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// peekErrorCode returns the API error code contained in the response
+// body b, or an empty string if there isn't one.
+func peekErrorCode(b []byte) string {
+	var r CoreResponse
+
+	if err := json.Unmarshal(b, &r); err != nil {
+		return ""
+	}
+
+	r.normalize()
+	if r.Error == nil {
+		return ""
+	}
+
+	return r.Error.Code
+}
+
+// retryAfter returns the delay requested by the Retry-After header
+// of resp, or def if the header is absent or malformed.
+func retryAfter(resp *http.Response, def time.Duration) time.Duration {
+	s, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || s < 0 {
+		return def
+	}
+
+	return time.Duration(s) * time.Second
 }
 
 // checkKeepAlive checks for the presence of an active session cookie,
@@ -297,6 +515,8 @@ func (w *Client) checkKeepAlive(ctx context.Context) error {
 		return fmt.Errorf("keep-alive re-init failure: %w", err)
 	}
 
+	w.metrics().Relogin()
+
 	if w.loginBot {
 		if _, err := w.BotLogin(ctx, w.username, w.password); err != nil {
 			return fmt.Errorf("keep-alive login failure: %w", err)
@@ -339,6 +559,20 @@ func (w *Client) init(apiurl *url.URL, ua string) error {
 	w.apiURL = apiurl
 	w.UserAgent = ua
 	w.Tokens = &Tokens{m: map[Token]string{}}
+	w.SiteCache = NewMemorySiteCache()
 
 	return nil
 }
+
+// or returns the first of vals that isn't the zero value. It stands in
+// for cmp.Or, which needs Go 1.22.
+func or[T comparable](vals ...T) T {
+	var zero T
+	for _, v := range vals {
+		if v != zero {
+			return v
+		}
+	}
+
+	return zero
+}
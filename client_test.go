@@ -61,10 +61,9 @@ func TestClientParseResponse(t *testing.T) {
 	err := ParseResponse([]byte(mock), &r)
 	require.NoError(t, err)
 
-	assert.Equal(t, "Foo!", r.BatchComplete)
+	assert.True(t, r.BatchComplete, "formatversion=1 flags are upgraded")
 
-	assert.NotNil(t, r.Warnings)
-	assert.Equal(t, "Warning!", r.Warnings.Tokens["*"])
+	assert.Equal(t, ResponseWarnings{{Module: "tokens", Text: "Warning!"}}, r.Warnings)
 
 	assert.NotNil(t, r.Query)
 	assert.Equal(t, "!!TOKEN!!", r.Query.Tokens["logintoken"])
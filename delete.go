@@ -28,8 +28,9 @@ type DeleteDeleteResponse struct {
 type DeleteOption func(map[string]string)
 
 type DeleteClient struct {
-	o []DeleteOption
-	c *Client
+	o      []DeleteOption
+	c      *Client
+	dryRun *bool
 }
 
 func (c *Client) Delete() *DeleteClient {
@@ -95,9 +96,9 @@ func (w *DeleteClient) Watch(b bool) *DeleteClient {
 // Unconditionally add or remove the page from the current user's watchlist, use preferences (ignored for bot users) or do not change watch.
 // One of the following values: nochange, preferences, unwatch, watch
 // Default: preferences
-func (w *DeleteClient) Watchlist(s string) *DeleteClient {
+func (w *DeleteClient) Watchlist(s Watchlist) *DeleteClient {
 	w.o = append(w.o, func(m map[string]string) {
-		m["watchlist"] = s
+		m["watchlist"] = string(s)
 	})
 	return w
 }
@@ -129,7 +130,32 @@ func (w *DeleteClient) Oldimage(s string) *DeleteClient {
 	return w
 }
 
+// DryRun
+// Record the request in the client's Plan instead of sending it.
+// Overrides Client.DryRun for this call.
+func (w *DeleteClient) DryRun(b bool) *DeleteClient {
+	w.dryRun = &b
+	return w
+}
+
+// Validate checks the parameters of the request without sending it,
+// and returns all problems found. Do calls it before sending the request.
+func (w *DeleteClient) Validate() error {
+	parameters := Values{}
+	for _, o := range w.o {
+		o(parameters)
+	}
+
+	return checkParams(parameters,
+		exactlyOne("title", "pageid"),
+		oneOf("watchlist", watchlistValues...))
+}
+
 func (w *DeleteClient) Do(ctx context.Context) (DeleteResponse, error) {
+	if err := w.Validate(); err != nil {
+		return DeleteResponse{}, err
+	}
+
 	if err := w.c.checkKeepAlive(ctx); err != nil {
 		return DeleteResponse{}, err
 	}
@@ -149,6 +175,14 @@ func (w *DeleteClient) Do(ctx context.Context) (DeleteResponse, error) {
 		o(parameters)
 	}
 
+	if w.c.isDryRun(w.dryRun) {
+		w.c.plan(ctx, parameters, nil)
+
+		r := DeleteResponse{Delete: &DeleteDeleteResponse{Title: parameters["title"], Resaon: parameters["reason"]}}
+		simulate(&r.CoreResponse, r)
+		return r, nil
+	}
+
 	// Make the request.
 	r := DeleteResponse{}
 	j, err := w.c.PostInto(ctx, parameters, &r)
@@ -0,0 +1,166 @@
+package mediawiki
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PlannedRequest is a write request that was recorded instead of being
+// sent because dry-run mode was active. Params and Body contain the
+// real CSRF token, exactly as they would have been sent.
+type PlannedRequest struct {
+	Time   time.Time
+	Method string
+	URL    string
+	Action string
+	Params Values
+
+	// Body is the URL-encoded request body. Uploads are sent as
+	// multipart/form-data instead, with the same parameters and the
+	// file; ContentType and ContentLength are those of the body that
+	// would have been sent.
+	Body          string
+	ContentType   string
+	ContentLength int64
+
+	// File is the name of the file that would have been uploaded along
+	// with the request, if any, and FileSize its size. ContentLength and
+	// FileSize are -1 if the size of the file can't be known without
+	// reading it.
+	File     string
+	FileSize int64
+}
+
+// Plan collects the requests recorded in dry-run mode. It is safe for
+// concurrent use.
+type Plan struct {
+	mu       sync.Mutex
+	requests []PlannedRequest
+}
+
+// Requests returns a copy of the recorded requests, in the order they
+// were planned.
+func (p *Plan) Requests() []PlannedRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]PlannedRequest(nil), p.requests...)
+}
+
+// Reset discards all recorded requests.
+func (p *Plan) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.requests = nil
+}
+
+// String returns a human-readable listing of the plan, one request per
+// line. Tokens are redacted.
+func (p *Plan) String() string {
+	b := &strings.Builder{}
+
+	for i, r := range p.Requests() {
+		m := redactValues(r.Params)
+
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		fmt.Fprintf(b, "%d. %s %s", i+1, r.Method, r.Action)
+		for _, k := range keys {
+			fmt.Fprintf(b, " %s=%q", k, m[k])
+		}
+		if r.File != "" {
+			fmt.Fprintf(b, " file=%q", r.File)
+			if r.FileSize >= 0 {
+				fmt.Fprintf(b, " (%d bytes)", r.FileSize)
+			}
+		}
+		b.WriteRune('\n')
+	}
+
+	return b.String()
+}
+
+func (p *Plan) add(r PlannedRequest) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.requests = append(p.requests, r)
+}
+
+// isDryRun reports whether a write request should be planned rather
+// than sent. A per-call setting, if present, overrides Client.DryRun.
+func (w *Client) isDryRun(perCall *bool) bool {
+	if perCall != nil {
+		return *perCall
+	}
+
+	return w.DryRun
+}
+
+// plan records the write request v in the client's plan, with the
+// parameters that call would add to it. body is the multipart body of an
+// upload, or nil.
+func (w *Client) plan(ctx context.Context, v Values, body *uploadBody) {
+	v["format"] = "json"
+	w.prepare(v)
+
+	params := make(Values, len(v))
+	for k, s := range v {
+		params[k] = s
+	}
+
+	r := PlannedRequest{
+		Time:        time.Now(),
+		Method:      "POST",
+		URL:         w.apiURL.String(),
+		Action:      v["action"],
+		Params:      params,
+		Body:        v.Encode(),
+		ContentType: "application/x-www-form-urlencoded",
+	}
+	r.ContentLength = int64(len(r.Body))
+
+	if body != nil {
+		r.ContentType = body.contentType()
+		r.ContentLength = body.length(ctx)
+		if body.r != nil {
+			r.File, r.FileSize = body.filename, body.size
+		}
+	}
+
+	// The plan of a Client not made by New is created on first use.
+	w.planMutex.Lock()
+	if w.Plan == nil {
+		w.Plan = &Plan{}
+	}
+	p := w.Plan
+	w.planMutex.Unlock()
+
+	p.add(r)
+
+	if l, _ := w.logger(); l != nil {
+		l.InfoContext(ctx, "mediawiki dry run",
+			"action", v["action"],
+			"module", moduleOf(v),
+			"title", titleOf(v))
+	}
+}
+
+// simulate fills r with a synthetic response and flags it as simulated.
+func simulate(core *CoreResponse, r any) {
+	core.Simulated = true
+
+	if b, err := json.MarshalIndent(r, "", "  "); err == nil {
+		core.RawJSON = string(b)
+	}
+}
@@ -0,0 +1,112 @@
+package mediawiki
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/clockworksoul/mediawiki/internal/wikitest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRunClient(t *testing.T) {
+	s := wikitest.New(t, nil)
+
+	c, err := New(s.URL, agent)
+	require.NoError(t, err)
+	c.DryRun = true
+	c.Maxlag.On = true
+
+	ctx := context.Background()
+
+	er, err := c.Edit().Title("Foo").Text("Bar").Summary("test").Do(ctx)
+	require.NoError(t, err)
+	assert.True(t, er.Simulated)
+	require.NotNil(t, er.Edit)
+	assert.Equal(t, Success, er.Edit.Result)
+	assert.Equal(t, "Foo", er.Edit.Title)
+
+	dr, err := c.Delete().Title("Foo").Reason("cleanup").Do(ctx)
+	require.NoError(t, err)
+	assert.True(t, dr.Simulated)
+
+	mr, err := c.Move().From("Foo").To("Bar").Do(ctx)
+	require.NoError(t, err)
+	assert.True(t, mr.Simulated)
+	assert.Equal(t, "Bar", mr.Move.To)
+
+	pr, err := c.Protect().Title("Foo").Protections(Protection{ProtectionEdit, ProtectionSysop}).Do(ctx)
+	require.NoError(t, err)
+	assert.True(t, pr.Simulated)
+
+	ur, err := c.Upload().Filename("Foo.jpg").File(strings.NewReader("data")).Do(ctx)
+	require.NoError(t, err)
+	assert.True(t, ur.Simulated)
+
+	reqs := c.Plan.Requests()
+	require.Len(t, reqs, 5)
+
+	assert.Equal(t, "edit", reqs[0].Action)
+	assert.Equal(t, "POST", reqs[0].Method)
+	assert.Equal(t, wikitest.CSRFToken, reqs[0].Params["token"])
+	assert.Equal(t, "action=edit&errorformat=plaintext&format=json&formatversion=2&maxlag=5&summary=test&text=Bar&title=Foo&token=abc%2B%5C", reqs[0].Body)
+	assert.Equal(t, "application/x-www-form-urlencoded", reqs[0].ContentType)
+	assert.Equal(t, int64(len(reqs[0].Body)), reqs[0].ContentLength)
+	assert.Equal(t, "Foo.jpg", reqs[4].File)
+	assert.Equal(t, int64(4), reqs[4].FileSize)
+	assert.True(t, strings.HasPrefix(reqs[4].ContentType, "multipart/form-data; boundary="))
+	assert.Greater(t, reqs[4].ContentLength, int64(4))
+
+	out := c.Plan.String()
+	assert.Contains(t, out, `1. POST edit`)
+	assert.Contains(t, out, `file="Foo.jpg" (4 bytes)`)
+	assert.NotContains(t, out, "abc+")
+
+	c.Plan.Reset()
+	assert.Empty(t, c.Plan.Requests())
+}
+
+func TestDryRunPerCall(t *testing.T) {
+	s := wikitest.New(t, nil)
+
+	c, err := New(s.URL, agent)
+	require.NoError(t, err)
+
+	r, err := c.Edit().Title("Foo").AppendText("Bar").DryRun(true).Do(context.Background())
+	require.NoError(t, err)
+	assert.True(t, r.Simulated)
+	assert.Len(t, c.Plan.Requests(), 1)
+}
+
+func TestDryRunNilPlan(t *testing.T) {
+	s := wikitest.New(t, nil)
+
+	c, err := New(s.URL, agent)
+	require.NoError(t, err)
+	c.DryRun = true
+	c.Plan = nil
+
+	_, err = c.Delete().Title("Foo").Do(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, c.Plan)
+	assert.Len(t, c.Plan.Requests(), 1)
+}
+
+func TestDryRunValidation(t *testing.T) {
+	s := wikitest.New(t, nil)
+
+	c, err := New(s.URL, agent)
+	require.NoError(t, err)
+	c.DryRun = true
+
+	_, err = c.Edit().Title("Foo").PageId(1).Do(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "mutually exclusive")
+	assert.Contains(t, err.Error(), "text")
+
+	_, err = c.Move().From("Foo").Do(context.Background())
+	require.Error(t, err)
+
+	assert.Empty(t, c.Plan.Requests())
+}
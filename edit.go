@@ -20,15 +20,18 @@ type EditEditResponse struct {
 	OldRevId     int        `json:"oldrevid,omitempty"`
 	NewRevId     int        `json:"newrevid,omitempty"`
 	NewTimestamp *time.Time `json:"newtimestamp,omitempty"`
-	Watched      any        `json:"watched,omitempty"`
-	NoChange     any        `json:"nochange,omitempty"`
+	Watched      bool       `json:"watched,omitempty"`
+	NoChange     bool       `json:"nochange,omitempty"`
+	New          bool       `json:"new,omitempty"`
 }
 
 type EditOption func(map[string]string)
 
 type EditClient struct {
-	o []EditOption
-	c *Client
+	o       []EditOption
+	c       *Client
+	dryRun  *bool
+	resolve bool
 }
 
 func (c *Client) Edit() *EditClient {
@@ -117,7 +120,7 @@ func (w *EditClient) Minor(b bool) *EditClient {
 // Type: boolean (details)
 func (w *EditClient) NotMinor(b bool) *EditClient {
 	w.o = append(w.o, func(m map[string]string) {
-		m["motminor"] = strconv.FormatBool(b)
+		m["notminor"] = strconv.FormatBool(b)
 	})
 	return w
 }
@@ -218,9 +221,9 @@ func (w *EditClient) Unwatch(b bool) *EditClient {
 // Unconditionally add or remove the page from the current user's watchlist, use preferences (ignored for bot users) or do not change watch.
 // One of the following values: nochange, preferences, unwatch, watch
 // Default: preferences
-func (w *EditClient) Watchlist(s string) *EditClient {
+func (w *EditClient) Watchlist(s Watchlist) *EditClient {
 	w.o = append(w.o, func(m map[string]string) {
-		m["watchlist"] = s
+		m["watchlist"] = string(s)
 	})
 	return w
 }
@@ -298,9 +301,9 @@ func (w *EditClient) Redirect(b bool) *EditClient {
 // ContentFormat
 // Content serialization format used for the input text.
 // One of the following values: application/json, application/octet-stream, application/unknown, application/x-binary, text/css, text/javascript, text/plain, text/unknown, text/x-wiki, unknown/unknown
-func (w *EditClient) ContentFormat(s string) *EditClient {
+func (w *EditClient) ContentFormat(s ContentFormat) *EditClient {
 	w.o = append(w.o, func(m map[string]string) {
-		m["contentformat"] = s
+		m["contentformat"] = string(s)
 	})
 	return w
 }
@@ -308,9 +311,9 @@ func (w *EditClient) ContentFormat(s string) *EditClient {
 // ContentModel
 // Content model of the new content.
 // One of the following values: GadgetDefinition, Json.JsonConfig, JsonSchema, Map.JsonConfig, MassMessageListContent, NewsletterContent, Scribunto, SecurePoll, Tabular.JsonConfig, css, flow-board, javascript, json, sanitized-css, text, translate-messagebundle, unknown, wikitext
-func (w *EditClient) ContentModel(s string) *EditClient {
+func (w *EditClient) ContentModel(s ContentModel) *EditClient {
 	w.o = append(w.o, func(m map[string]string) {
-		m["contentmodel"] = s
+		m["contentmodel"] = string(s)
 	})
 	return w
 }
@@ -333,7 +336,44 @@ func (w *EditClient) CaptchaId(s string) *EditClient {
 	return w
 }
 
+// DryRun
+// Record the request in the client's Plan instead of sending it.
+// Overrides Client.DryRun for this call.
+func (w *EditClient) DryRun(b bool) *EditClient {
+	w.dryRun = &b
+	return w
+}
+
+// ResolveConflicts
+// On an edit conflict, merge our text with the edits made since the base
+// revision and retry. Requires BaseRevId and Text. If the changes
+// overlap, Do returns an *EditConflictError. The text of a new section
+// is retried as it is.
+func (w *EditClient) ResolveConflicts(b bool) *EditClient {
+	w.resolve = b
+	return w
+}
+
+// Validate checks the parameters of the edit without sending it, and
+// returns all problems found. Do calls it before sending the edit.
+func (w *EditClient) Validate() error {
+	parameters := Values{}
+	for _, o := range w.o {
+		o(parameters)
+	}
+
+	return checkParams(parameters,
+		exactlyOne("title", "pageid"),
+		atLeastOne("text", "appendtext", "prependtext", "undo"),
+		atMostOne("text", "undo"),
+		oneOf("watchlist", watchlistValues...))
+}
+
 func (w *EditClient) Do(ctx context.Context) (EditResponse, error) {
+	if err := w.Validate(); err != nil {
+		return EditResponse{}, err
+	}
+
 	if err := w.c.checkKeepAlive(ctx); err != nil {
 		return EditResponse{}, err
 	}
@@ -353,6 +393,15 @@ func (w *EditClient) Do(ctx context.Context) (EditResponse, error) {
 		o(parameters)
 	}
 
+	if w.c.isDryRun(w.dryRun) {
+		w.c.plan(ctx, parameters, nil)
+
+		r := EditResponse{Edit: &EditEditResponse{Result: Success, Title: parameters["title"]}}
+		r.Edit.PageId, _ = strconv.Atoi(parameters["pageid"])
+		simulate(&r.CoreResponse, r)
+		return r, nil
+	}
+
 	// Make the request.
 	r := EditResponse{}
 	j, err := w.c.PostInto(ctx, parameters, &r)
@@ -361,6 +410,12 @@ func (w *EditClient) Do(ctx context.Context) (EditResponse, error) {
 		return r, fmt.Errorf("failed to post: %w", err)
 	}
 
+	for attempt := 0; w.resolve && attempt < maxConflictRetries && r.Error != nil && r.Error.Code == "editconflict"; attempt++ {
+		if r, err = w.merge(ctx, parameters); err != nil {
+			return r, err
+		}
+	}
+
 	if e := r.Error; e != nil {
 		return r, fmt.Errorf("%s: %s", e.Code, e.Info)
 	} else if r.Edit == nil {
@@ -371,3 +426,109 @@ func (w *EditClient) Do(ctx context.Context) (EditResponse, error) {
 
 	return r, nil
 }
+
+// maxConflictRetries is the number of times an edit is merged and
+// retried before the conflict is reported.
+const maxConflictRetries = 3
+
+// merge merges the text of the edit in parameters with the current
+// revision of the page, updates parameters to be based on the current
+// revision, and retries the edit.
+func (w *EditClient) merge(ctx context.Context, parameters Values) (EditResponse, error) {
+	base, err := strconv.Atoi(parameters["baserevid"])
+	if err != nil {
+		return EditResponse{}, fmt.Errorf("editconflict: cannot merge without a base revision")
+	}
+	if _, ok := parameters["text"]; !ok {
+		return EditResponse{}, fmt.Errorf("editconflict: cannot merge without text")
+	}
+
+	// A new section is added to the end of the page, so there is
+	// nothing to merge: the edit is only rebased on the current
+	// revision.
+	section := parameters["section"]
+	isNew := section == "new"
+	if isNew {
+		section = ""
+	}
+
+	// The base revision.
+	var baseText string
+	if !isNew {
+		rc := w.c.Revisions().Revids(base).Prop("content")
+		if section != "" {
+			rc.Section(section)
+		}
+		br, err := rc.Do(ctx)
+		if err != nil {
+			return EditResponse{}, fmt.Errorf("failed to get base revision: %w", err)
+		}
+		if br.Query == nil || len(br.Query.Pages) == 0 || len(br.Query.Pages[0].Revisions) == 0 {
+			return EditResponse{}, fmt.Errorf("base revision %d not found", base)
+		}
+		baseText = br.Query.Pages[0].Revisions[0].Slots["main"].Content
+	}
+
+	// The current revision.
+	rc := w.c.Revisions().Prop("ids", "timestamp", "content").Curtimestamp(true)
+	if id, err := strconv.Atoi(parameters["pageid"]); err == nil {
+		rc.Pageids(id)
+	} else {
+		rc.Titles(parameters["title"])
+	}
+	if section != "" {
+		rc.Section(section)
+	}
+	cr, err := rc.Do(ctx)
+	if err != nil {
+		return EditResponse{}, fmt.Errorf("failed to get current revision: %w", err)
+	}
+	if cr.Query == nil || len(cr.Query.Pages) == 0 || len(cr.Query.Pages[0].Revisions) == 0 {
+		return EditResponse{}, fmt.Errorf("current revision not found")
+	}
+
+	cur := cr.Query.Pages[0].Revisions[0]
+	theirs := cur.Slots["main"].Content
+	ours := parameters["text"]
+
+	merged, hunks := ours, []ConflictHunk(nil)
+	if !isNew {
+		merged, hunks = Merge3(baseText, ours, theirs)
+	}
+	if len(hunks) > 0 {
+		return EditResponse{}, &EditConflictError{
+			Title:        cr.Query.Pages[0].Title,
+			BaseRevID:    base,
+			CurrentRevID: cur.Revid,
+			Base:         baseText,
+			Ours:         ours,
+			Theirs:       theirs,
+			Merged:       merged,
+			Hunks:        hunks,
+		}
+	}
+
+	if l, _ := w.c.logger(); l != nil {
+		l.DebugContext(ctx, "mediawiki edit conflict merged",
+			"title", cr.Query.Pages[0].Title, "base", base, "current", cur.Revid)
+	}
+
+	parameters["text"] = merged
+	parameters["baserevid"] = strconv.Itoa(cur.Revid)
+	if cur.Timestamp != nil {
+		parameters["basetimestamp"] = cur.Timestamp.Format(time.RFC3339)
+	}
+	if cr.Curtimestamp != nil {
+		parameters["starttimestamp"] = cr.Curtimestamp.Format(time.RFC3339)
+	}
+	delete(parameters, "md5")
+
+	r := EditResponse{}
+	j, err := w.c.PostInto(ctx, parameters, &r)
+	r.RawJSON = j
+	if err != nil {
+		return r, fmt.Errorf("failed to post: %w", err)
+	}
+
+	return r, nil
+}
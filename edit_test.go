@@ -53,18 +53,18 @@ func TestEditRepeated(t *testing.T) {
 	require.NoError(t, err)
 
 	text := "This is a test. " + time.Now().String()
-	r, err := c.Edit().Title("TestMediawikiEditRepeated").Text(text).Summary("Automated test.").Watchlist("unwatch").Do(context.Background())
+	r, err := c.Edit().Title("TestMediawikiEditRepeated").Text(text).Summary("Automated test.").Watchlist(WatchlistUnwatch).Do(context.Background())
 	require.NoError(t, err)
 	assert.Nil(t, r.Error)
 	require.NotNil(t, r.Edit)
-	assert.Nil(t, r.Edit.NoChange)
+	assert.False(t, r.Edit.NoChange)
 	assert.Equal(t, Success, r.Edit.Result)
 
-	r, err = c.Edit().Title("TestMediawikiEditRepeated").Text(text).Summary("Automated test.").Watchlist("unwatch").Do(context.Background())
+	r, err = c.Edit().Title("TestMediawikiEditRepeated").Text(text).Summary("Automated test.").Watchlist(WatchlistUnwatch).Do(context.Background())
 	require.NoError(t, err)
 	assert.Nil(t, r.Error)
 	require.NotNil(t, r.Edit)
-	assert.NotNil(t, r.Edit.NoChange)
+	assert.True(t, r.Edit.NoChange)
 	assert.Equal(t, Success, r.Edit.Result)
 
 	CompareJSON(t, r.RawJSON, r, false)
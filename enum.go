@@ -0,0 +1,115 @@
+package mediawiki
+
+import "time"
+
+// This contains the types of parameters that take one of a documented
+// set of values. Parameters whose values depend on the configuration of
+// the wiki, such as content models and protection levels, also accept
+// values other than the constants below.
+
+// Watchlist is the watchlist parameter of write modules.
+type Watchlist string
+
+const (
+	WatchlistNoChange    Watchlist = "nochange"
+	WatchlistPreferences Watchlist = "preferences"
+	WatchlistUnwatch     Watchlist = "unwatch"
+	WatchlistWatch       Watchlist = "watch"
+)
+
+// watchlistValues are the values of the watchlist parameter of all
+// modules but upload, which has no unwatch.
+var watchlistValues = []string{
+	string(WatchlistNoChange),
+	string(WatchlistPreferences),
+	string(WatchlistUnwatch),
+	string(WatchlistWatch),
+}
+
+// Direction is the order in which lists are returned. Alphabetical lists
+// use DirAscending and DirDescending, and lists of revisions use DirNewer
+// and DirOlder.
+type Direction string
+
+const (
+	DirAscending  Direction = "ascending"
+	DirDescending Direction = "descending"
+	DirNewer      Direction = "newer"
+	DirOlder      Direction = "older"
+)
+
+// RedirectFilter selects pages by whether they are redirects.
+type RedirectFilter string
+
+const (
+	RedirectFilterAll          RedirectFilter = "all"
+	RedirectFilterNonredirects RedirectFilter = "nonredirects"
+	RedirectFilterRedirects    RedirectFilter = "redirects"
+)
+
+// ContentModel is the content model of a page, such as wikitext or
+// JSON. Extensions add their own content models.
+type ContentModel string
+
+const (
+	ContentModelCSS          ContentModel = "css"
+	ContentModelJavaScript   ContentModel = "javascript"
+	ContentModelJSON         ContentModel = "json"
+	ContentModelSanitizedCSS ContentModel = "sanitized-css"
+	ContentModelScribunto    ContentModel = "Scribunto"
+	ContentModelText         ContentModel = "text"
+	ContentModelWikitext     ContentModel = "wikitext"
+)
+
+// ContentFormat is the serialization format of page content.
+type ContentFormat string
+
+const (
+	ContentFormatCSS        ContentFormat = "text/css"
+	ContentFormatJavaScript ContentFormat = "text/javascript"
+	ContentFormatJSON       ContentFormat = "application/json"
+	ContentFormatText       ContentFormat = "text/plain"
+	ContentFormatWikitext   ContentFormat = "text/x-wiki"
+)
+
+// ProtectionType is the action a protection applies to.
+type ProtectionType string
+
+const (
+	ProtectionCreate ProtectionType = "create"
+	ProtectionEdit   ProtectionType = "edit"
+	ProtectionMove   ProtectionType = "move"
+	ProtectionUpload ProtectionType = "upload"
+)
+
+// ProtectionLevel is the user right needed to take a protected action.
+// The levels of a wiki are listed by Site.ProtectionLevels.
+type ProtectionLevel string
+
+const (
+	// ProtectionAll removes the protection.
+	ProtectionAll           ProtectionLevel = "all"
+	ProtectionAutoconfirmed ProtectionLevel = "autoconfirmed"
+	ProtectionSysop         ProtectionLevel = "sysop"
+)
+
+// Protection is the protection of a page for one action.
+type Protection struct {
+	Type  ProtectionType
+	Level ProtectionLevel
+}
+
+func (p Protection) String() string {
+	return string(p.Type) + "=" + string(p.Level)
+}
+
+// Expiry is the expiry of a protection, either ExpiryInfinite, a
+// timestamp, or a relative time such as "1 week".
+type Expiry string
+
+const ExpiryInfinite Expiry = "infinite"
+
+// ExpiryAt returns the expiry at time t.
+func ExpiryAt(t time.Time) Expiry {
+	return Expiry(t.UTC().Format(time.RFC3339))
+}
@@ -0,0 +1,168 @@
+package mediawiki
+
+import (
+	"context"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// FileVersion is a version of a file, as listed by FileHistory.
+type FileVersion struct {
+	// Filename is the name of the file, without the File: prefix.
+	Filename string
+
+	// Archivename is the name under which the version is archived,
+	// which identifies it to RevertFile and DeleteFileVersion. It is
+	// empty for the current version.
+	Archivename string
+
+	Timestamp time.Time
+	User      string
+	Comment   string
+	Size      int
+	Sha1      string
+
+	// Url is the address of the contents of the version.
+	Url string
+}
+
+// Current reports whether v is the current version of the file.
+func (v FileVersion) Current() bool {
+	return v.Archivename == ""
+}
+
+// ChecksumError is returned by DownloadFileVersion when the downloaded
+// bytes don't match the SHA-1 of the version.
+type ChecksumError struct {
+	Url  string
+	Sha1 string
+	Got  string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("checksum mismatch for %s: expected sha1 %s, got %s", e.Url, e.Sha1, e.Got)
+}
+
+// fileHistoryProps are the imageinfo properties of a FileVersion.
+var fileHistoryProps = []string{"timestamp", "user", "comment", "size", "sha1", "url", "archivename"}
+
+// FileHistory returns every version of the file name, without the File:
+// prefix, from the current one to the oldest.
+func (c *Client) FileHistory(ctx context.Context, name string) ([]FileVersion, error) {
+	var versions []FileVersion
+
+	cont := ""
+	for {
+		ii := c.Imageinfo().Titles("File:" + name).Prop(fileHistoryProps...).Limit(500)
+		if cont != "" {
+			ii.Continue(cont)
+		}
+
+		r, err := ii.Do(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the history of %s: %w", name, err)
+		}
+
+		for _, p := range r.Query.Pages {
+			if p.Missing && len(p.Imageinfo) == 0 {
+				return nil, fmt.Errorf("file %s does not exist", name)
+			}
+
+			for _, info := range p.Imageinfo {
+				v := FileVersion{
+					Filename:    name,
+					Archivename: info.Archivename,
+					User:        info.User,
+					Comment:     info.Comment,
+					Size:        info.Size,
+					Sha1:        info.Sha1,
+					Url:         info.Url,
+				}
+				if info.Timestamp != nil {
+					v.Timestamp = *info.Timestamp
+				}
+				versions = append(versions, v)
+			}
+		}
+
+		if r.Continue == nil || r.Continue.Iicontinue == "" {
+			return versions, nil
+		}
+		cont = r.Continue.Iicontinue
+	}
+}
+
+// RevertFile makes the old version v the current version of its file,
+// by uploading it again with the comment.
+func (c *Client) RevertFile(ctx context.Context, v FileVersion, comment string) (FilerevertResponse, error) {
+	if v.Current() {
+		return FilerevertResponse{}, fmt.Errorf("%s is already the current version of %s", v.Timestamp.Format(time.RFC3339), v.Filename)
+	}
+
+	fr := c.Filerevert().Filename(v.Filename).Archivename(v.Archivename)
+	if comment != "" {
+		fr.Comment(comment)
+	}
+
+	return fr.Do(ctx)
+}
+
+// DeleteFileVersion deletes the old version v of its file, giving the
+// reason. The current version can't be deleted this way, since that
+// would delete the whole file.
+func (c *Client) DeleteFileVersion(ctx context.Context, v FileVersion, reason string) (DeleteResponse, error) {
+	if v.Current() {
+		return DeleteResponse{}, fmt.Errorf("the current version of %s can't be deleted on its own", v.Filename)
+	}
+
+	d := c.Delete().Title("File:" + v.Filename).Oldimage(v.Archivename)
+	if reason != "" {
+		d.Reason(reason)
+	}
+
+	return d.Do(ctx)
+}
+
+// DownloadFileVersion writes the contents of the version v to w, and
+// checks them against its SHA-1. The contents are written as they are
+// received, so w holds the bad contents if a *ChecksumError is
+// returned.
+func (c *Client) DownloadFileVersion(ctx context.Context, v FileVersion, w io.Writer) error {
+	if v.Url == "" {
+		return fmt.Errorf("version %s of %s has no url", v.Timestamp.Format(time.RFC3339), v.Filename)
+	} else if v.Sha1 == "" {
+		return errors.New("the version has no sha1 to check the download against")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", v.Url, nil)
+	if err != nil {
+		return fmt.Errorf("error downloading %s: %w", v.Url, err)
+	}
+	req.Header.Set("User-Agent", c.UserAgent)
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error downloading %s: %w", v.Url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error downloading %s: %s", v.Url, resp.Status)
+	}
+
+	h := sha1.New()
+	if _, err := io.Copy(io.MultiWriter(w, h), resp.Body); err != nil {
+		return fmt.Errorf("error downloading %s: %w", v.Url, err)
+	}
+
+	if got := fmt.Sprintf("%x", h.Sum(nil)); !strings.EqualFold(got, v.Sha1) {
+		return &ChecksumError{Url: v.Url, Sha1: v.Sha1, Got: got}
+	}
+
+	return nil
+}
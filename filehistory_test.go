@@ -0,0 +1,187 @@
+package mediawiki
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/clockworksoul/mediawiki/internal/wikitest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fileServer is a wiki with a file of three versions, which lists one
+// version per request.
+type fileServer struct {
+	contents map[string][]byte
+	versions []map[string]any
+
+	// forms are the forms of the write requests.
+	forms []map[string]string
+}
+
+func newFileServer(t *testing.T) (*fileServer, *Client) {
+	t.Helper()
+
+	f := &fileServer{contents: map[string][]byte{
+		"/images/Foo.png":                        []byte("three"),
+		"/images/archive/20240201000000!Foo.png": []byte("two"),
+		"/images/archive/20240101000000!Foo.png": []byte("one"),
+	}}
+
+	// write records the form of a write request, and answers it.
+	write := func(rw http.ResponseWriter, r *http.Request) {
+		form := map[string]string{}
+		for k := range r.PostForm {
+			form[k] = r.PostForm.Get(k)
+		}
+		f.forms = append(f.forms, form)
+
+		switch r.Form.Get("action") {
+		case "filerevert":
+			wikitest.Reply(rw, map[string]any{"filerevert": map[string]any{"result": "Success"}})
+		case "delete":
+			wikitest.Reply(rw, map[string]any{"delete": map[string]any{"title": r.Form.Get("title"), "reason": r.Form.Get("reason")}})
+		}
+	}
+
+	s := wikitest.New(t, map[string]http.HandlerFunc{
+		"*": func(rw http.ResponseWriter, r *http.Request) {
+			if b, ok := f.contents[r.URL.Path]; ok {
+				rw.Write(b)
+				return
+			}
+			http.NotFound(rw, r)
+		},
+		"query+imageinfo": func(rw http.ResponseWriter, r *http.Request) {
+			if r.Form.Get("titles") != "File:Foo.png" {
+				wikitest.Reply(rw, map[string]any{"query": map[string]any{"pages": []any{map[string]any{"ns": 6, "title": r.Form.Get("titles"), "missing": true, "imagerepository": ""}}}})
+				return
+			}
+
+			i := 0
+			fmt.Sscan(r.Form.Get("iicontinue"), &i)
+			resp := map[string]any{"query": map[string]any{"pages": []any{map[string]any{
+				"ns": 6, "title": "File:Foo.png", "imagerepository": "local",
+				"imageinfo": []any{f.versions[i]},
+			}}}}
+			if i+1 < len(f.versions) {
+				resp["continue"] = map[string]any{"iicontinue": fmt.Sprint(i + 1), "continue": "||"}
+			}
+			wikitest.Reply(rw, resp)
+		},
+		"filerevert": write,
+		"delete":     write,
+	})
+
+	version := func(timestamp, user, archivename, path string) map[string]any {
+		b := f.contents[path]
+		v := map[string]any{
+			"timestamp": timestamp,
+			"user":      user,
+			"comment":   "version " + string(b),
+			"size":      len(b),
+			"sha1":      fmt.Sprintf("%x", sha1.Sum(b)),
+			"url":       s.URL + path,
+		}
+		if archivename != "" {
+			v["archivename"] = archivename
+		}
+		return v
+	}
+	f.versions = []map[string]any{
+		version("2024-03-01T00:00:00Z", "Carol", "", "/images/Foo.png"),
+		version("2024-02-01T00:00:00Z", "Bob", "20240201000000!Foo.png", "/images/archive/20240201000000!Foo.png"),
+		version("2024-01-01T00:00:00Z", "Alice", "20240101000000!Foo.png", "/images/archive/20240101000000!Foo.png"),
+	}
+
+	c, err := New(s.URL, agent)
+	require.NoError(t, err)
+
+	return f, c
+}
+
+func TestFileHistory(t *testing.T) {
+	_, c := newFileServer(t)
+
+	versions, err := c.FileHistory(context.Background(), "Foo.png")
+	require.NoError(t, err)
+	require.Len(t, versions, 3)
+
+	assert.True(t, versions[0].Current())
+	assert.Equal(t, "Carol", versions[0].User)
+	assert.Equal(t, "Foo.png", versions[2].Filename)
+	assert.Equal(t, "20240101000000!Foo.png", versions[2].Archivename)
+	assert.Equal(t, "version one", versions[2].Comment)
+	assert.Equal(t, 3, versions[2].Size)
+	assert.Equal(t, fmt.Sprintf("%x", sha1.Sum([]byte("one"))), versions[2].Sha1)
+	assert.Equal(t, 2024, versions[2].Timestamp.Year())
+
+	_, err = c.FileHistory(context.Background(), "Missing.png")
+	assert.ErrorContains(t, err, "does not exist")
+}
+
+func TestRevertFile(t *testing.T) {
+	f, c := newFileServer(t)
+
+	versions, err := c.FileHistory(context.Background(), "Foo.png")
+	require.NoError(t, err)
+
+	r, err := c.RevertFile(context.Background(), versions[1], "Back to two")
+	require.NoError(t, err)
+	assert.Equal(t, "Success", r.Filerevert.Result)
+
+	_, err = c.RevertFile(context.Background(), versions[0], "")
+	assert.ErrorContains(t, err, "already the current version")
+
+	require.Len(t, f.forms, 1)
+	assert.Equal(t, "Foo.png", f.forms[0]["filename"])
+	assert.Equal(t, "20240201000000!Foo.png", f.forms[0]["archivename"])
+	assert.Equal(t, "Back to two", f.forms[0]["comment"])
+}
+
+func TestDeleteFileVersion(t *testing.T) {
+	f, c := newFileServer(t)
+
+	versions, err := c.FileHistory(context.Background(), "Foo.png")
+	require.NoError(t, err)
+
+	_, err = c.DeleteFileVersion(context.Background(), versions[2], "Copyright violation")
+	require.NoError(t, err)
+
+	// Deleting the current version would delete the file.
+	_, err = c.DeleteFileVersion(context.Background(), versions[0], "")
+	assert.Error(t, err)
+
+	require.Len(t, f.forms, 1)
+	assert.Equal(t, "File:Foo.png", f.forms[0]["title"])
+	assert.Equal(t, "20240101000000!Foo.png", f.forms[0]["oldimage"])
+	assert.Equal(t, "Copyright violation", f.forms[0]["reason"])
+}
+
+func TestDownloadFileVersion(t *testing.T) {
+	f, c := newFileServer(t)
+
+	versions, err := c.FileHistory(context.Background(), "Foo.png")
+	require.NoError(t, err)
+
+	b := &bytes.Buffer{}
+	require.NoError(t, c.DownloadFileVersion(context.Background(), versions[1], b))
+	assert.Equal(t, "two", b.String())
+
+	// The archived file was corrupted.
+	f.contents["/images/archive/20240101000000!Foo.png"] = []byte("uno")
+
+	var cerr *ChecksumError
+	err = c.DownloadFileVersion(context.Background(), versions[2], &bytes.Buffer{})
+	require.ErrorAs(t, err, &cerr)
+	assert.Equal(t, versions[2].Sha1, cerr.Sha1)
+	assert.Equal(t, fmt.Sprintf("%x", sha1.Sum([]byte("uno"))), cerr.Got)
+
+	versions[2].Url += ".missing"
+	err = c.DownloadFileVersion(context.Background(), versions[2], &bytes.Buffer{})
+	assert.ErrorContains(t, err, "404")
+}
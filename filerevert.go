@@ -0,0 +1,120 @@
+// Code generated by import; DO NOT EDIT.
+
+package mediawiki
+
+import (
+	"context"
+	"fmt"
+)
+
+// Revert a file to an old version.
+// https://www.mediawiki.org/wiki/Special:MyLanguage/API:Filerevert
+//
+// Flags:
+// * This module requires write rights.
+// * This module only accepts POST requests.
+
+// Filerevert
+
+type FilerevertResponse struct {
+	CoreResponse
+	Filerevert *FilerevertFilerevertResponse `json:"filerevert,omitempty"`
+}
+
+type FilerevertFilerevertResponse struct {
+	Result string `json:"result"`
+}
+
+type FilerevertOption func(map[string]string)
+
+type FilerevertClient struct {
+	o []FilerevertOption
+	c *Client
+}
+
+func (c *Client) Filerevert() *FilerevertClient {
+	return &FilerevertClient{c: c}
+}
+
+// Filename
+// Target filename, without the File: prefix.
+// This parameter is required.
+func (w *FilerevertClient) Filename(s string) *FilerevertClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["filename"] = s
+	})
+	return w
+}
+
+// Comment
+// Upload comment.
+func (w *FilerevertClient) Comment(s string) *FilerevertClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["comment"] = s
+	})
+	return w
+}
+
+// Archivename
+// Archive name of the revision to revert to.
+// This parameter is required.
+func (w *FilerevertClient) Archivename(s string) *FilerevertClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["archivename"] = s
+	})
+	return w
+}
+
+// Validate checks the parameters of the request without sending it,
+// and returns all problems found. Do calls it before sending the request.
+func (w *FilerevertClient) Validate() error {
+	parameters := Values{}
+	for _, o := range w.o {
+		o(parameters)
+	}
+
+	return checkParams(parameters,
+		required("filename"),
+		required("archivename"))
+}
+
+func (w *FilerevertClient) Do(ctx context.Context) (FilerevertResponse, error) {
+	if err := w.Validate(); err != nil {
+		return FilerevertResponse{}, err
+	}
+
+	if err := w.c.checkKeepAlive(ctx); err != nil {
+		return FilerevertResponse{}, err
+	}
+
+	token, err := w.c.GetToken(ctx, CSRFToken)
+	if err != nil {
+		return FilerevertResponse{}, err
+	}
+
+	// Specify parameters to send.
+	parameters := Values{
+		"action": "filerevert",
+		"token":  token,
+	}
+
+	for _, o := range w.o {
+		o(parameters)
+	}
+
+	// Make the request.
+	r := FilerevertResponse{}
+	j, err := w.c.PostInto(ctx, parameters, &r)
+	r.RawJSON = j
+	if err != nil {
+		return r, fmt.Errorf("failed to post: %w", err)
+	}
+
+	if e := r.Error; e != nil {
+		return r, fmt.Errorf("%s: %s", e.Code, e.Info)
+	} else if r.Filerevert == nil {
+		return r, fmt.Errorf("unexpected error in filerevert")
+	}
+
+	return r, nil
+}
@@ -0,0 +1,274 @@
+package mediawiki
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// The response types of this package follow formatversion=2, which the
+// client asks for in every request: flags are booleans, pages are
+// slices, and text is held in named fields. Wikis before MediaWiki 1.25
+// ignore formatversion and answer with formatversion=1 shapes instead:
+// flags are empty strings that are only present when set, lists of
+// pages are objects keyed by page ID, and text is held in "*" fields.
+// ParseResponse rewrites those shapes into formatversion=2 ones, guided
+// by the type being decoded into.
+
+// starFields are the string fields, in order of preference, that hold
+// the "*" member of formatversion=1 objects in formatversion=2.
+var starFields = []string{"content", "name", "alias", "text"}
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// needsUpgrade reports whether the response body b must be rewritten
+// before it's decoded, given the error of decoding it as is.
+func needsUpgrade(b []byte, err error) bool {
+	var te *json.UnmarshalTypeError
+	if errors.As(err, &te) {
+		return true
+	}
+	return err == nil && bytes.Contains(b, []byte(`"*"`))
+}
+
+// upgradeFormat rewrites the formatversion=1 shapes in the JSON
+// document b to the formatversion=2 shapes expected by type t.
+func upgradeFormat(b []byte, t reflect.Type) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	v, err := readJSON(dec)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(upgradeValue(v, t))
+}
+
+// upgradeValue rewrites v, a value read by readJSON, for type t.
+func upgradeValue(v any, t reflect.Type) any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	p := reflect.PointerTo(t)
+	if p.Implements(jsonUnmarshalerType) || p.Implements(textUnmarshalerType) {
+		return v
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		if _, ok := v.(string); ok {
+			return true
+		}
+
+	case reflect.Slice:
+		var items []any
+		switch v := v.(type) {
+		case jsonObject:
+			for _, m := range v {
+				items = append(items, m.Value)
+			}
+		case []any:
+			items = v
+		default:
+			return v
+		}
+
+		out := make([]any, len(items))
+		for i, item := range items {
+			out[i] = upgradeValue(item, t.Elem())
+		}
+		return out
+
+	case reflect.Map:
+		o, ok := v.(jsonObject)
+		if !ok {
+			return v
+		}
+
+		out := make(jsonObject, len(o))
+		for i, m := range o {
+			out[i] = jsonMember{m.Key, upgradeValue(m.Value, t.Elem())}
+		}
+		return out
+
+	case reflect.Struct:
+		o, ok := v.(jsonObject)
+		if !ok {
+			return v
+		}
+
+		fields := jsonFields(t)
+		star := ""
+		if _, ok := fields["*"]; !ok {
+			for _, f := range starFields {
+				if ft, ok := fields[f]; ok && ft.Kind() == reflect.String && !o.has(f) {
+					star = f
+					break
+				}
+			}
+		}
+
+		out := make(jsonObject, 0, len(o))
+		for _, m := range o {
+			if m.Key == "*" && star != "" {
+				m.Key = star
+			}
+			if ft, ok := fields[strings.ToLower(m.Key)]; ok {
+				m.Value = upgradeValue(m.Value, ft)
+			}
+			out = append(out, m)
+		}
+		return out
+	}
+
+	return v
+}
+
+// jsonFields returns the types of the fields of struct type t, by
+// lowercased JSON name, including the promoted fields of embedded
+// structs that aren't shadowed.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+
+	var embedded []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded = append(embedded, ft)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+		fields[strings.ToLower(name)] = f.Type
+	}
+
+	for _, e := range embedded {
+		for name, ft := range jsonFields(e) {
+			if _, ok := fields[name]; !ok {
+				fields[name] = ft
+			}
+		}
+	}
+
+	return fields
+}
+
+// jsonObject is a JSON object that keeps the order of its members, so
+// that pages keyed by ID stay in the order the wiki returned them.
+type jsonObject []jsonMember
+
+type jsonMember struct {
+	Key   string
+	Value any
+}
+
+func (o jsonObject) has(key string) bool {
+	for _, m := range o {
+		if m.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteByte('{')
+
+	for i, m := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		k, err := json.Marshal(m.Key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(m.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// readJSON reads the next JSON value from dec. Objects are read as
+// jsonObject, arrays as []any, and numbers as json.Number.
+func readJSON(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		o := jsonObject{}
+		for dec.More() {
+			k, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			v, err := readJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			o = append(o, jsonMember{k.(string), v})
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return o, nil
+
+	case json.Delim('['):
+		a := []any{}
+		for dec.More() {
+			v, err := readJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, v)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return a, nil
+
+	case json.Delim('}'), json.Delim(']'):
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	return tok, nil
+}
@@ -0,0 +1,69 @@
+package mediawiki
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseResponseFormatversion1(t *testing.T) {
+	var r LinkshereResponse
+	require.NoError(t, ParseResponse([]byte(`{
+		"batchcomplete": "",
+		"query": {"pages": {
+			"-1": {"ns": 0, "title": "Gone", "missing": ""},
+			"12": {"pageid": 12, "ns": 0, "title": "Foo", "linkshere": [
+				{"pageid": 3, "ns": 0, "title": "Bar", "redirect": ""},
+				{"pageid": 4, "ns": 0, "title": "Baz"}
+			]},
+			"7": {"pageid": 7, "ns": 0, "title": "Qux"}
+		}}
+	}`), &r))
+
+	assert.True(t, r.BatchComplete)
+	require.Len(t, r.Query.Pages, 3)
+	assert.Equal(t, "Gone", r.Query.Pages[0].Title, "pages keep their order")
+	assert.True(t, r.Query.Pages[0].Missing)
+	assert.Equal(t, "Foo", r.Query.Pages[1].Title)
+	assert.False(t, r.Query.Pages[1].Missing)
+	assert.True(t, r.Query.Pages[1].Linkshere[0].Redirect)
+	assert.False(t, r.Query.Pages[1].Linkshere[1].Redirect)
+	assert.Equal(t, "Qux", r.Query.Pages[2].Title)
+}
+
+func TestParseResponseFormatversion1Star(t *testing.T) {
+	var r RevisionsResponse
+	require.NoError(t, ParseResponse([]byte(`{"query":{"pages":{"1":{"pageid":1,"ns":0,"title":"Foo",
+		"revisions":[{"revid":5,"minor":"","slots":{"main":{"contentmodel":"wikitext","*":"Hello"}}}]}}}}`), &r))
+	rev := r.Query.Pages[0].Revisions[0]
+	assert.True(t, rev.Minor)
+	assert.Equal(t, "Hello", rev.Slots["main"].Content)
+
+	var v1, v2 SiteinfoResponse
+	require.NoError(t, ParseResponse([]byte(`{"query":{
+		"general":{"writeapi":"","case":"first-letter"},
+		"namespaces":{"0":{"id":0,"content":"","*":""},"2":{"id":2,"subpages":"","canonical":"User","*":"Benutzer"}},
+		"namespacealiases":[{"id":2,"*":"Benutzerin"}],
+		"interwikimap":[{"prefix":"de","local":"","url":"https://de.wikipedia.org/wiki/$1"}]
+	}}`), &v1))
+	require.NoError(t, ParseResponse([]byte(`{"query":{
+		"general":{"writeapi":true,"case":"first-letter"},
+		"namespaces":{"0":{"id":0,"content":true,"name":""},"2":{"id":2,"subpages":true,"canonical":"User","name":"Benutzer"}},
+		"namespacealiases":[{"id":2,"alias":"Benutzerin"}],
+		"interwikimap":[{"prefix":"de","local":true,"url":"https://de.wikipedia.org/wiki/$1"}]
+	}}`), &v2))
+	assert.Equal(t, v2, v1)
+	assert.Equal(t, "Benutzer", v1.Query.Namespaces["2"].Name)
+}
+
+func TestParseResponseKeepsErrors(t *testing.T) {
+	var r Response
+	require.NoError(t, ParseResponse([]byte(`{"error":{"code":"badtoken","*":"See the docs."}}`), &r))
+	assert.Equal(t, "See the docs.", r.Error.Star)
+
+	err := ParseResponse([]byte(`{"batchcomplete":[1]}`), &r)
+	var te *json.UnmarshalTypeError
+	assert.ErrorAs(t, err, &te)
+}
@@ -0,0 +1,10 @@
+package mediawiki
+
+// The clients below are generated by the import command from the
+// paraminfo and sample responses committed in import/testdata. Run
+// go generate after changing either, or the generator; the tests of the
+// import command fail while a generated client is out of date.
+
+//go:generate go run ./import -paraminfo import/testdata/paraminfo.json -sample import/testdata/samples/rollback.json -o rollback.go rollback
+//go:generate go run ./import -paraminfo import/testdata/paraminfo.json -sample import/testdata/samples/query_allimages.json -o allimages.go query+allimages
+//go:generate go run ./import -paraminfo import/testdata/paraminfo.json -sample import/testdata/samples/filerevert.json -o filerevert.go filerevert
@@ -1,10 +1,9 @@
 module github.com/clockworksoul/mediawiki
 
-go 1.18
+go 1.21
 
 require (
 	github.com/stretchr/testify v1.8.0
-	golang.org/x/net v0.1.0
 	golang.org/x/text v0.4.0
 )
 
@@ -18,12 +18,18 @@ import (
 
 type ImageinfoResponse struct {
 	CoreResponse
-	BatchComplete any             `json:"batchcomplete,omitempty"`
-	Query         *ImageinfoQuery `json:"query,omitempty"`
+	BatchComplete bool               `json:"batchcomplete,omitempty"`
+	Continue      *ImageinfoContinue `json:"continue,omitempty"`
+	Query         *ImageinfoQuery    `json:"query,omitempty"`
+}
+
+type ImageinfoContinue struct {
+	Iicontinue string `json:"iicontinue"`
+	Continue   string `json:"continue"`
 }
 
 type ImageinfoQuery struct {
-	Pages map[string]ImageinfoPage `json:"pages"`
+	Pages []ImageinfoPage `json:"pages"`
 }
 
 type ImageinfoPage struct {
@@ -31,7 +37,7 @@ type ImageinfoPage struct {
 	Ns              Namespace                `json:"ns"`
 	Title           string                   `json:"title"`
 	ImageRepository string                   `json:"imagerepository"`
-	Missing         any                      `json:"missing,omitempty"`
+	Missing         bool                     `json:"missing,omitempty"`
 	Imageinfo       []ImageinfoPageImageinfo `json:"imageinfo,omitempty"`
 }
 
@@ -54,6 +60,7 @@ type ImageinfoPageImageinfo struct {
 	Extmetadata         map[string]any   `json:"extmetadata,omitempty"`
 	Mime                string           `json:"mime,omitempty"`
 	Mediatype           string           `json:"mediatype,omitempty"`
+	Archivename         string           `json:"archivename,omitempty"`
 }
 
 type ImageinfoOption func(map[string]string)
@@ -160,7 +167,22 @@ func (w *ImageinfoClient) Titles(s ...string) *ImageinfoClient {
 	return w
 }
 
+// Validate checks that the files to describe are given.
+func (w *ImageinfoClient) Validate() error {
+	parameters := Values{}
+	for _, o := range w.o {
+		o(parameters)
+	}
+
+	return checkParams(parameters,
+		required("titles"))
+}
+
 func (w *ImageinfoClient) Do(ctx context.Context) (ImageinfoResponse, error) {
+	if err := w.Validate(); err != nil {
+		return ImageinfoResponse{}, err
+	}
+
 	if err := w.c.checkKeepAlive(ctx); err != nil {
 		return ImageinfoResponse{}, err
 	}
@@ -17,7 +17,7 @@ import (
 
 type ImagesResponse struct {
 	CoreResponse
-	BatchComplete any             `json:"batchcomplete,omitempty"`
+	BatchComplete bool            `json:"batchcomplete,omitempty"`
 	Continue      *ImagesContinue `json:"continue,omitempty"`
 	Query         *ImagesQuery    `json:"query,omitempty"`
 }
@@ -28,14 +28,14 @@ type ImagesContinue struct {
 }
 
 type ImagesQuery struct {
-	Pages map[string]ImagesPage `json:"pages"`
+	Pages []ImagesPage `json:"pages"`
 }
 
 type ImagesPage struct {
 	Pageid  int               `json:"pageid"`
 	Ns      Namespace         `json:"ns"`
 	Title   string            `json:"title"`
-	Missing any               `json:"missing,omitempty"`
+	Missing bool              `json:"missing,omitempty"`
 	Images  []ImagesPageImage `json:"images,omitempty"`
 }
 
@@ -107,7 +107,24 @@ func (w *ImagesClient) Titles(s ...string) *ImagesClient {
 	return w
 }
 
+// Validate checks that the pages are given and that Dir is a valid
+// direction.
+func (w *ImagesClient) Validate() error {
+	parameters := Values{}
+	for _, o := range w.o {
+		o(parameters)
+	}
+
+	return checkParams(parameters,
+		required("titles"),
+		oneOf("imdir", string(DirAscending), string(DirDescending)))
+}
+
 func (w *ImagesClient) Do(ctx context.Context) (ImagesResponse, error) {
+	if err := w.Validate(); err != nil {
+		return ImagesResponse{}, err
+	}
+
 	if err := w.c.checkKeepAlive(ctx); err != nil {
 		return ImagesResponse{}, err
 	}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// This generates the clients of all modules of a wiki into a directory,
+// and reports how they differ from the files already there. Files that
+// weren't generated are never overwritten: a module whose client would
+// redeclare one of their identifiers is kept as written by hand.
+
+// Statuses of the modules in the index report.
+const (
+	statusNew       = "new"
+	statusChanged   = "changed"
+	statusUnchanged = "unchanged"
+	statusRemoved   = "removed"
+	statusKept      = "kept"
+	statusSkipped   = "skipped"
+	statusFailed    = "failed"
+)
+
+// indexEntry is a line of the index report.
+type indexEntry struct {
+	status string
+	module string
+	file   string
+	note   string
+}
+
+// runAll generates the clients of all modules into the directory of the
+// options, or checks them in check mode, and writes the index report to
+// w.
+func runAll(o options, w io.Writer) error {
+	var b []byte
+	var err error
+
+	if o.paraminfo != "" {
+		b, err = os.ReadFile(o.paraminfo)
+	} else {
+		b, err = fetchParaminfo(context.Background(), o.api, "**")
+		if err == nil && o.save != "" {
+			err = os.WriteFile(o.save, append(b, '\n'), 0o644)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	mods, err := readParaminfo(bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	sort.Slice(mods, func(i, j int) bool { return mods[i].Path < mods[j].Path })
+
+	existing, err := readDir(o.dir)
+	if err != nil {
+		return err
+	}
+
+	var index []indexEntry
+	files := map[string][]byte{}
+	clients := map[string]bool{}
+
+	for _, pm := range mods {
+		switch {
+		case pm.Path == "main", pm.Group == "format":
+			continue
+		case pm.Path == "query":
+			index = append(index, indexEntry{statusSkipped, pm.Path, "", "its submodules are generated"})
+			continue
+		case strings.Contains(pm.Path, "+") && !strings.HasPrefix(pm.Path, "query+"):
+			index = append(index, indexEntry{statusSkipped, pm.Path, "", "only submodules of query are supported"})
+			continue
+		}
+
+		m := moduleFromParaminfo(pm)
+		if clients[clientName(m)] {
+			m.Client = methodName(m.Group) + clientName(m)
+		}
+		clients[clientName(m)] = true
+
+		file := strings.ToLower(clientName(m)) + ".go"
+		e := indexEntry{module: m.Path, file: file}
+		if pm.Source != "" && pm.Source != "MediaWiki" {
+			e.note = pm.Source
+		}
+
+		if o.sampleDir != "" {
+			s, err := os.ReadFile(filepath.Join(o.sampleDir, strings.ReplaceAll(m.Path, "+", "_")+".json"))
+			if err == nil {
+				m.Samples = append(m.Samples, s)
+			} else if !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+
+		text, err := Generate(m)
+		if err != nil {
+			e.status, e.note = statusFailed, err.Error()
+			index = append(index, e)
+			continue
+		}
+		src := []byte(generatedHeader + text)
+
+		if f, id := existing.declares(src); f != "" {
+			e.status, e.file, e.note = statusKept, f, "declares "+id
+			index = append(index, e)
+			continue
+		}
+
+		switch old, ok := existing.generated[file]; {
+		case !ok:
+			e.status = statusNew
+		case !bytes.Equal(old, src):
+			e.status = statusChanged
+		default:
+			e.status = statusUnchanged
+		}
+		index = append(index, e)
+		files[file] = src
+	}
+
+	for f := range existing.generated {
+		if _, ok := files[f]; !ok {
+			index = append(index, indexEntry{status: statusRemoved, file: f})
+		}
+	}
+
+	writeIndex(w, index)
+
+	var drift, failed int
+	for _, e := range index {
+		switch e.status {
+		case statusNew, statusChanged, statusRemoved:
+			drift++
+		case statusFailed:
+			failed++
+		}
+	}
+
+	if o.check {
+		if drift > 0 {
+			return fmt.Errorf("%s %w", o.dir, errDrift)
+		}
+	} else {
+		if err := os.MkdirAll(o.dir, 0o755); err != nil {
+			return err
+		}
+		for _, e := range index {
+			switch e.status {
+			case statusNew, statusChanged:
+				err = os.WriteFile(filepath.Join(o.dir, e.file), files[e.file], 0o644)
+			case statusRemoved:
+				err = os.Remove(filepath.Join(o.dir, e.file))
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to generate %d modules", failed)
+	}
+
+	return nil
+}
+
+// writeIndex writes the index report, grouped by status.
+func writeIndex(w io.Writer, index []indexEntry) {
+	order := []string{statusNew, statusChanged, statusRemoved, statusKept, statusSkipped, statusFailed, statusUnchanged}
+	rank := map[string]int{}
+	for i, s := range order {
+		rank[s] = i
+	}
+
+	sort.SliceStable(index, func(i, j int) bool {
+		if a, b := rank[index[i].status], rank[index[j].status]; a != b {
+			return a < b
+		}
+		return index[i].module+index[i].file < index[j].module+index[j].file
+	})
+
+	for _, e := range index {
+		line := fmt.Sprintf("%-9s %s", e.status, e.module)
+		if e.file != "" {
+			line = strings.TrimRight(fmt.Sprintf("%-36s %s", line, e.file), " ")
+		}
+		if e.note != "" {
+			line += " (" + e.note + ")"
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+// dirFiles are the Go files of a directory.
+type dirFiles struct {
+	// generated maps the names of generated files to their contents.
+	generated map[string][]byte
+
+	// declared maps the identifiers declared by the other files to
+	// their names. The fields and methods of Client are included.
+	declared map[string]string
+}
+
+// readDir reads the Go files of dir, which may not exist.
+func readDir(dir string) (dirFiles, error) {
+	d := dirFiles{generated: map[string][]byte{}, declared: map[string]string{}}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return d, err
+	}
+
+	for _, p := range paths {
+		if strings.HasSuffix(p, "_test.go") {
+			continue
+		}
+
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return d, err
+		}
+
+		name := filepath.Base(p)
+		if bytes.HasPrefix(b, []byte(generatedHeader)) {
+			d.generated[name] = b
+			continue
+		}
+
+		f, err := parser.ParseFile(token.NewFileSet(), p, b, parser.SkipObjectResolution)
+		if err != nil {
+			return d, err
+		}
+		for _, id := range declarations(f) {
+			d.declared[id] = name
+		}
+	}
+
+	return d, nil
+}
+
+// declares returns the file and the identifier of the first identifier
+// declared by both the source and a file that wasn't generated.
+func (d dirFiles) declares(src []byte) (string, string) {
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, parser.SkipObjectResolution)
+	if err != nil {
+		return "", ""
+	}
+
+	for _, id := range declarations(f) {
+		if file, ok := d.declared[id]; ok {
+			return file, id
+		}
+	}
+
+	return "", ""
+}
+
+// declarations returns the package-level identifiers declared by the
+// file, and the fields and methods of Client as "Client.Name".
+func declarations(f *ast.File) []string {
+	var ids []string
+
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv == nil {
+				ids = append(ids, decl.Name.Name)
+			} else if receiverName(decl.Recv.List[0].Type) == "Client" {
+				ids = append(ids, "Client."+decl.Name.Name)
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					ids = append(ids, spec.Name.Name)
+					if st, ok := spec.Type.(*ast.StructType); ok && spec.Name.Name == "Client" {
+						for _, fl := range st.Fields.List {
+							for _, n := range fl.Names {
+								ids = append(ids, "Client."+n.Name)
+							}
+						}
+					}
+				case *ast.ValueSpec:
+					for _, n := range spec.Names {
+						ids = append(ids, n.Name)
+					}
+				}
+			}
+		}
+	}
+
+	return ids
+}
+
+// receiverName returns the name of the type of a method receiver.
+func receiverName(e ast.Expr) string {
+	if s, ok := e.(*ast.StarExpr); ok {
+		e = s.X
+	}
+	if id, ok := e.(*ast.Ident); ok {
+		return id.Name
+	}
+
+	return ""
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunAll(t *testing.T) {
+	dir := t.TempDir()
+	o := options{
+		all:       true,
+		dir:       dir,
+		paraminfo: filepath.Join("testdata", "paraminfo.json"),
+		sampleDir: filepath.Join("testdata", "samples"),
+	}
+
+	// A hand-written client, a hand-written Client with a field named
+	// like a constructor, and a stale generated client.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "templates.go"), []byte("package mediawiki\n\ntype TemplatesClient struct{}\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "client.go"), []byte("package mediawiki\n\ntype Client struct {\n\tUserinfo string\n}\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "purge.go"), []byte(generatedHeader+"package mediawiki\n"), 0o644))
+
+	w := &bytes.Buffer{}
+	require.NoError(t, runAll(o, w))
+	assert.Equal(t, `new       createaccount              createaccount.go
+new       filerevert                 filerevert.go
+new       query+allimages            allimages.go
+new       query+backlinks            backlinks.go
+new       rollback                   rollback.go
+removed                              purge.go
+kept      query+templates            templates.go (declares TemplatesClient)
+kept      query+userinfo             client.go (declares Client.Userinfo)
+skipped   query (its submodules are generated)
+`, w.String())
+
+	assert.NoFileExists(t, filepath.Join(dir, "purge.go"))
+	assert.FileExists(t, filepath.Join(dir, "backlinks.go"))
+
+	// The generated rollback client is the one of the package.
+	b, err := os.ReadFile(filepath.Join(dir, "rollback.go"))
+	require.NoError(t, err)
+	want, err := os.ReadFile(filepath.Join("..", "rollback.go"))
+	require.NoError(t, err)
+	assert.Equal(t, string(want), string(b))
+
+	o.check = true
+	w.Reset()
+	require.NoError(t, runAll(o, w))
+	assert.Contains(t, w.String(), "unchanged query+backlinks")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "backlinks.go"), []byte(generatedHeader+"package mediawiki\n"), 0o644))
+	w.Reset()
+	assert.ErrorIs(t, runAll(o, w), errDrift)
+	assert.Contains(t, w.String(), "changed   query+backlinks")
+}
+
+func TestEnums(t *testing.T) {
+	e := enumOf("Foo", &Param{Name: "dir", Type: String, Values: []string{"newer", "older"}})
+	require.NotNil(t, e)
+	assert.Equal(t, "Direction", e.typ)
+	assert.Equal(t, "DirOlder", e.constant("older"))
+
+	e = enumOf("Foo", &Param{Name: "dir", Type: String, Values: []string{"up", "down"}})
+	require.NotNil(t, e)
+	assert.Equal(t, "FooDir", e.typ)
+
+	e = enumOf("Foo", &Param{Name: "show", Type: String, Values: []string{"", "!redirect", "redirect", "*"}})
+	require.NotNil(t, e)
+	assert.Equal(t, []enumConst{
+		{"FooShowNone", ""},
+		{"FooShowNotRedirect", "!redirect"},
+		{"FooShowRedirect", "redirect"},
+		{"FooShow4", "*"},
+	}, e.consts)
+
+	assert.Nil(t, enumOf("Foo", &Param{Name: "show", Type: ListOfStrings, Values: []string{"a"}}))
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// enum is the Go type of a parameter taking one of a set of values.
+type enum struct {
+	typ    string
+	consts []enumConst
+
+	// shared is set for the types of the package that several modules
+	// use, which aren't generated.
+	shared bool
+}
+
+type enumConst struct {
+	name, value string
+}
+
+// constant returns the name of the constant of the value, if any.
+func (e *enum) constant(value string) string {
+	for _, c := range e.consts {
+		if c.value == value {
+			return c.name
+		}
+	}
+
+	return ""
+}
+
+// sharedEnums are the enum types of the package, by parameter name.
+// They are used if the parameter takes a subset of their values.
+var sharedEnums = map[string]*enum{
+	"watchlist": {typ: "Watchlist", shared: true, consts: []enumConst{
+		{"WatchlistNoChange", "nochange"},
+		{"WatchlistPreferences", "preferences"},
+		{"WatchlistUnwatch", "unwatch"},
+		{"WatchlistWatch", "watch"},
+	}},
+	"dir": {typ: "Direction", shared: true, consts: []enumConst{
+		{"DirAscending", "ascending"},
+		{"DirDescending", "descending"},
+		{"DirNewer", "newer"},
+		{"DirOlder", "older"},
+	}},
+	"filterredir": {typ: "RedirectFilter", shared: true, consts: []enumConst{
+		{"RedirectFilterAll", "all"},
+		{"RedirectFilterNonredirects", "nonredirects"},
+		{"RedirectFilterRedirects", "redirects"},
+	}},
+}
+
+// enumOf returns the enum type of a parameter of the client, or nil if
+// the parameter doesn't take one of a set of values.
+func enumOf(client string, p *Param) *enum {
+	if p.Type != String || len(p.Values) == 0 {
+		return nil
+	}
+
+	if e, ok := sharedEnums[p.Name]; ok {
+		shared := true
+		for _, v := range p.Values {
+			shared = shared && e.constant(v) != ""
+		}
+		if shared {
+			return e
+		}
+	}
+
+	e := &enum{typ: client + methodName(p.Name)}
+	used := map[string]bool{}
+	for i, v := range p.Values {
+		n := constName(v)
+		if n == "" || used[n] {
+			n += strconv.Itoa(i + 1)
+		}
+		used[n] = true
+		e.consts = append(e.consts, enumConst{e.typ + n, v})
+	}
+
+	return e
+}
+
+// constName returns the part of the name of a constant derived from its
+// value.
+func constName(v string) string {
+	switch {
+	case v == "":
+		return "None"
+	case strings.HasPrefix(v, "!"):
+		return "Not" + methodName(v[1:])
+	}
+
+	return methodName(v)
+}
+
+// writeEnums writes the enum types of the parameters of the client.
+func writeEnums(client string, params []*Param) string {
+	b := &bytes.Buffer{}
+
+	for _, p := range params {
+		e := enumOf(client, p)
+		if e == nil || e.shared {
+			continue
+		}
+
+		fmt.Fprintf(b, "\n// %s is the %s parameter of %s.\ntype %s string\n\nconst (\n", e.typ, p.Name, client, e.typ)
+		for _, c := range e.consts {
+			fmt.Fprintf(b, "\t%s %s = %q\n", c.name, e.typ, c.value)
+		}
+		b.WriteString(")\n")
+	}
+
+	return b.String()
+}
@@ -3,7 +3,10 @@ package main
 import (
 	"bytes"
 	"fmt"
-	"sort"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
 	"strings"
 
 	"golang.org/x/text/cases"
@@ -12,22 +15,24 @@ import (
 
 var caser = cases.Title(language.English)
 
-func Generate(m Module) (string, error) {
-	b := &bytes.Buffer{}
-
-	imports, err := gatherImports(m)
-	if err != nil {
-		return "", err
-	}
-
-	fmt.Fprintln(b, `package mediawiki
+// tokenConstants are the Token constants of the package, by token type.
+var tokenConstants = map[string]string{
+	"csrf":                   "CSRFToken",
+	"deleteglobalaccount":    "DeleteGlobalAccountToken",
+	"login":                  "LoginToken",
+	"patrol":                 "PatrolToken",
+	"rollback":               "RollbackToken",
+	"setglobalaccountstatus": "SetGlobalAccountStatusToken",
+	"userrights":             "UserRightsToken",
+	"watch":                  "WatchToken",
+}
 
-import (`)
-	for _, i := range imports {
-		fmt.Fprintf(b, "\t%q\n", i)
-	}
+// importCandidates are the packages generated code may use.
+var importCandidates = []string{"context", "encoding/json", "fmt", "strconv", "strings", "time"}
 
-	fmt.Fprint(b, ")\n\n")
+// Generate returns the gofmt-ed source of the client of the module.
+func Generate(m Module) (string, error) {
+	b := &bytes.Buffer{}
 
 	for _, d := range strings.Split(m.Description, "\n") {
 		fmt.Fprintf(b, "// %s\n", d)
@@ -44,79 +49,463 @@ import (`)
 
 	b.WriteRune('\n')
 
-	caser := cases.Title(language.English)
-	name := strings.ReplaceAll(caser.String(m.Name), "+", "")
+	name := clientName(m)
 
 	fmt.Fprintln(b, "//", name)
-	fmt.Fprintf(b, `
+	resp, err := writeResponse(m, name)
+	if err != nil {
+		return "", err
+	}
+	b.WriteString(resp)
+	b.WriteString(writeEnums(name, m.Parameters))
+	b.WriteString(writeClient(name, m.Query()))
+
+	if err := writeParameters(b, name, name, m.Prefix, m.Parameters); err != nil {
+		return "", err
+	}
+
+	titles := m.Group == "prop" && !hasParam(m, "titles")
+	if titles {
+		b.WriteString(writeVariadicStringParameter(name, "titles", titlesParam))
+		b.WriteRune('\n')
+	}
+
+	b.WriteString(writeValidate(name, m.Prefix, m.Parameters))
+
+	if m.Query() {
+		b.WriteString(writeContinue(name))
+		b.WriteString(writeQueryDo(name, m.Group, m.Name, true))
+	} else {
+		b.WriteString(writeActionDo(m, name))
+	}
+
+	if m.Query() && m.Generator {
+		gen := name + "Generator"
+		prefix := "g" + m.Prefix
+
+		fmt.Fprintf(b, "\n// %s uses %s as a generator.\n", gen, m.Name)
+		fmt.Fprintf(b, `
+type %sResponse struct {
+	QueryResponse
+	Continue map[string]string   `+"`json:\"continue,omitempty\"`"+`
+	Query    *QueryResponseQuery `+"`json:\"query,omitempty\"`"+`
+}
+`, gen)
+		b.WriteString(writeClient(gen, true))
+
+		if err := writeParameters(b, gen, name, prefix, m.Parameters); err != nil {
+			return "", err
+		}
+
+		if titles {
+			b.WriteString(writeVariadicStringParameter(gen, "titles", titlesParam))
+			b.WriteRune('\n')
+		}
+		b.WriteString(writeVariadicStringParameter(gen, "prop", pagePropParam))
+		b.WriteRune('\n')
+		b.WriteString(writeValidate(gen, prefix, m.Parameters))
+		b.WriteString(writeContinue(gen))
+		b.WriteString(writeQueryDo(gen, "generator", m.Name, false))
+	}
+
+	body := b.String()
+
+	imps, err := gatherImports(body)
+	if err != nil {
+		return "", fmt.Errorf("generated invalid code for %s: %w", m.Path, err)
+	}
+
+	h := &bytes.Buffer{}
+	fmt.Fprintln(h, `package mediawiki
+
+import (`)
+	for _, i := range imps {
+		fmt.Fprintf(h, "\t%q\n", i)
+	}
+	fmt.Fprint(h, ")\n\n")
+
+	src, err := format.Source([]byte(h.String() + body))
+	if err != nil {
+		return "", fmt.Errorf("generated invalid code for %s: %w", m.Path, err)
+	}
+
+	return string(src), nil
+}
+
+// clientName returns the name of the client of the module, such as
+// "Edit" or "Allpages".
+func clientName(m Module) string {
+	if m.Client != "" {
+		return m.Client
+	}
+	return methodName(m.Name)
+}
+
+// hasParam reports whether the module has a parameter with the name.
+func hasParam(m Module, name string) bool {
+	for _, p := range m.Parameters {
+		if p.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// methodName returns the name of the builder method of a parameter.
+func methodName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, caser.String(name))
+}
+
+// gatherImports returns the packages used by the generated body. They
+// are found in the selectors of the parsed code, so that mentions in
+// comments or strings, such as "at a time.", don't count.
+func gatherImports(body string) ([]string, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), "", "package mediawiki\n\n"+body, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	used := map[string]bool{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		if s, ok := n.(*ast.SelectorExpr); ok {
+			// Identifiers declared in the body, unlike packages, are
+			// resolved.
+			if id, ok := s.X.(*ast.Ident); ok && id.Obj == nil {
+				used[id.Name] = true
+			}
+		}
+		return true
+	})
+
+	var imps []string
+	for _, i := range importCandidates {
+		if used[i[strings.LastIndex(i, "/")+1:]] {
+			imps = append(imps, i)
+		}
+	}
+
+	return imps, nil
+}
+
+var (
+	titlesParam = &Param{
+		Name:        "titles",
+		Description: "A list of titles to work on.\nSeparate values with | or alternative.\nMaximum number of values is 50 (500 for clients allowed higher limits).",
+	}
+	pagePropParam = &Param{
+		Name:        "pageprop",
+		Description: "Which properties to get for the generated pages.\nSeparate values with | or alternative.",
+	}
+)
+
+// writeResponse writes the response type of the module. The results of
+// the module are typed after its samples, if any, and else kept as raw
+// JSON.
+func writeResponse(m Module, name string) (string, error) {
+	b := &bytes.Buffer{}
+	field := methodName(m.Name)
+
+	root := name + field + "Response"
+	if m.Query() {
+		root = name + "Result"
+	}
+
+	typ, decls := "json.RawMessage", ""
+	if len(m.Samples) > 0 {
+		var err error
+		if typ, decls, err = inferResponse(m, m.Samples, root); err != nil {
+			return "", err
+		}
+	}
+
+	switch {
+	case !m.Query():
+		fmt.Fprintf(b, `
+type %sResponse struct {
+	CoreResponse
+	%s %s `+"`json:\"%s,omitempty\"`"+`
+}
+`, name, field, typ, m.Name)
+	case m.Group == "prop":
+		fmt.Fprintf(b, `
+type %sResponse struct {
+	QueryResponse
+	Continue map[string]string `+"`json:\"continue,omitempty\"`"+`
+	Query    *%sQuery          `+"`json:\"query,omitempty\"`"+`
+}
+
+type %sQuery struct {
+	Pages []%sPage `+"`json:\"pages\"`"+`
+}
+
+type %sPage struct {
+	QueryResponseQueryPage
+	%s %s `+"`json:\"%s,omitempty\"`"+`
+}
+`, name, name, name, name, name, field, typ, m.Name)
+	default:
+		fmt.Fprintf(b, `
 type %sResponse struct {
- 	CoreResponse
+	QueryResponse
+	Continue map[string]string `+"`json:\"continue,omitempty\"`"+`
+	Query    *%sQuery          `+"`json:\"query,omitempty\"`"+`
 }
 
+type %sQuery struct {
+	%s %s `+"`json:\"%s,omitempty\"`"+`
+}
+`, name, name, name, field, typ, m.Name)
+	}
+
+	b.WriteString(decls)
+
+	return b.String(), nil
+}
+
+// writeClient writes the option type, the client type and the
+// constructor of a client.
+func writeClient(name string, query bool) string {
+	cont := ""
+	if query {
+		cont = "\n\tcont map[string]string"
+	}
+
+	return fmt.Sprintf(`
 type %sOption func(map[string]string)
 
 type %sClient struct {
 	o []%sOption
-	c *Client
+	c *Client%s
 }
 
 func (c *Client) %s() *%sClient {
 	return &%sClient{c: c}
 }
 
-`, name, name, name, name, name, name, name)
+`, name, name, name, cont, name, name, name)
+}
+
+// writeParameters writes the builder methods of the parameters, sent
+// with the given prefix. Enumerated parameters take the enum types of
+// base.
+func writeParameters(b *bytes.Buffer, name, base, prefix string, params []*Param) error {
+	for _, p := range params {
+		key := prefix + p.Name
 
-	for _, p := range m.Parameters {
 		if p.Name == "*" {
-			fmt.Fprintln(b, writeAdditionalParameter(m, p))
+			fmt.Fprintln(b, writeAdditionalParameter(name, p))
+			continue
+		}
+
+		if e := enumOf(base, p); e != nil {
+			fmt.Fprintln(b, writeEnumParameter(name, key, p, e))
 			continue
 		}
 
 		switch p.Type {
 		case Boolean:
-			fmt.Fprintln(b, writeBooleanParameter(m, p))
+			fmt.Fprintln(b, writeBooleanParameter(name, key, p))
 		case Integer:
-			fmt.Fprintln(b, writeIntegerParameter(m, p))
+			fmt.Fprintln(b, writeIntegerParameter(name, key, p))
 		case Expiry, String:
-			fmt.Fprintln(b, writeStringParameter(m, p))
+			fmt.Fprintln(b, writeStringParameter(name, key, p))
 		case ListOfStrings:
-			fmt.Fprintln(b, writeVariadicStringParameter(m, p))
+			fmt.Fprintln(b, writeVariadicStringParameter(name, key, p))
 		case ListOfIntegers:
-			fmt.Fprintln(b, writeVariadicIntParameter(m, p))
+			fmt.Fprintln(b, writeVariadicIntParameter(name, key, p))
 		case Timestamp:
-			fmt.Fprintln(b, writeTimestampParameter(m, p))
+			fmt.Fprintln(b, writeTimestampParameter(name, key, p))
 		default:
-			return "", fmt.Errorf("unsupported parameter type for parameter %s: %s", p.Name, p.Type)
+			return fmt.Errorf("unsupported parameter type for parameter %s: %s", p.Name, p.Type)
 		}
 	}
 
-	fmt.Fprintf(b,
-		`func (w *%sClient) Do(ctx context.Context) (%sResponse, error) {
+	return nil
+}
+
+// writeValidate writes the Validate method, which checks required and
+// enumerated parameters.
+func writeValidate(name, prefix string, params []*Param) string {
+	var rules []string
+	for _, p := range params {
+		key := prefix + p.Name
+		if p.Required {
+			rules = append(rules, fmt.Sprintf("required(%q)", key))
+		}
+		if p.Type == String && len(p.Values) > 0 {
+			values := make([]string, len(p.Values))
+			for i, v := range p.Values {
+				values[i] = fmt.Sprintf("%q", v)
+			}
+			rules = append(rules, fmt.Sprintf("oneOf(%q, %s)", key, strings.Join(values, ", ")))
+		}
+	}
+
+	args := ""
+	for _, r := range rules {
+		args += ",\n\t\t" + r
+	}
+
+	return fmt.Sprintf(`// Validate checks the parameters of the request without sending it,
+// and returns all problems found. Do calls it before sending the request.
+func (w *%sClient) Validate() error {
+	parameters := Values{}
+	for _, o := range w.o {
+		o(parameters)
+	}
+
+	return checkParams(parameters%s)
+}
+
+`, name, args)
+}
+
+// writeContinue writes the methods that continue a query.
+func writeContinue(name string) string {
+	return fmt.Sprintf(`// ContinueFrom
+// Continues the query where the response with the given continue values
+// left off. The values replace those of earlier calls.
+func (w *%sClient) ContinueFrom(c map[string]string) *%sClient {
+	w.cont = c
+	return w
+}
+
+// DoAll sends the request, continuing it until all results are fetched,
+// and calls f with every response. It stops at the first error, from the
+// request or from f.
+func (w *%sClient) DoAll(ctx context.Context, f func(%sResponse) error) error {
+	for {
+		r, err := w.Do(ctx)
+		if err != nil {
+			return err
+		}
+
+		if err := f(r); err != nil {
+			return err
+		}
+
+		if r.Continue == nil {
+			return nil
+		}
+		w.ContinueFrom(r.Continue)
+	}
+}
+
+`, name, name, name, name)
+}
+
+// writeQueryDo writes the Do method of a query client, which selects the
+// module with the group parameter, such as list or generator. If check
+// is set, a response without query results is an error.
+func writeQueryDo(name, group, module string, check bool) string {
+	b := &bytes.Buffer{}
+
+	fmt.Fprintf(b, `func (w *%sClient) Do(ctx context.Context) (%sResponse, error) {
+	if err := w.Validate(); err != nil {
+		return %sResponse{}, err
+	}
+
 	if err := w.c.checkKeepAlive(ctx); err != nil {
 		return %sResponse{}, err
 	}
 
-	token, err := w.c.GetToken(ctx, CSRFToken)
+	// Specify parameters to send.
+	parameters := Values{
+		"action": "query",
+		%q: %q,
+	}
+
+	for _, o := range w.o {
+		o(parameters)
+	}
+	for k, v := range w.cont {
+		parameters[k] = v
+	}
+
+	// Make the request.
+	r := %sResponse{}
+	j, err := w.c.GetInto(ctx, parameters, &r)
+	r.RawJSON = j
 	if err != nil {
+		return r, fmt.Errorf("failed to get: %%w", err)
+	}
+
+	if e := r.Error; e != nil {
+		return r, fmt.Errorf("%%s: %%s", e.Code, e.Info)
+	}`, name, name, name, name, group, module, name)
+
+	if check {
+		b.WriteString(` else if r.Query == nil {
+		return r, fmt.Errorf("unexpected error in query")
+	}`)
+	}
+
+	b.WriteString("\n\n\treturn r, nil\n}\n")
+
+	return b.String()
+}
+
+// writeActionDo writes the Do method of an action module, which fetches
+// the token of the module, if any.
+func writeActionDo(m Module, name string) string {
+	b := &bytes.Buffer{}
+
+	fmt.Fprintf(b, `func (w *%sClient) Do(ctx context.Context) (%sResponse, error) {
+	if err := w.Validate(); err != nil {
+		return %sResponse{}, err
+	}
+
+	if err := w.c.checkKeepAlive(ctx); err != nil {
 		return %sResponse{}, err
 	}
+`, name, name, name, name)
+
+	if m.Token != "" {
+		token, ok := tokenConstants[m.Token]
+		if !ok {
+			token = fmt.Sprintf("Token(%q)", m.Token)
+		}
+
+		fmt.Fprintf(b, `
+	token, err := w.c.GetToken(ctx, %s)
+	if err != nil {
+		return %sResponse{}, err
+	}
+`, token, name)
+	}
 
+	fmt.Fprintf(b, `
 	// Specify parameters to send.
 	parameters := Values{
-		"action": "%s",
-		"token":  token,
+		"action": %q,
+`, m.Name)
+	if m.Token != "" {
+		b.WriteString("\t\t\"token\":  token,\n")
+	}
+
+	method, verb := "GetInto", "get"
+	if m.Post {
+		method, verb = "PostInto", "post"
 	}
 
+	fmt.Fprintf(b, `	}
+
 	for _, o := range w.o {
 		o(parameters)
 	}
 
 	// Make the request.
 	r := %sResponse{}
-	j, err := w.c.PostInto(ctx, parameters, &r)
+	j, err := w.c.%s(ctx, parameters, &r)
 	r.RawJSON = j
 	if err != nil {
-		return r, fmt.Errorf("failed to post: %%w", err)
+		return r, fmt.Errorf("failed to %s: %%w", err)
 	}
 
 	if e := r.Error; e != nil {
@@ -127,62 +516,53 @@ func (c *Client) %s() *%sClient {
 
 	return r, nil
 }
-`, name, name, name, name, m.Name, name, name, m.Name)
+`, name, method, verb, methodName(m.Name), m.Name)
 
-	return b.String(), nil
+	return b.String()
 }
 
-func gatherImports(mod Module) ([]string, error) {
-	m := map[string]interface{}{
-		"context": true,
-		"fmt":     true,
-	}
+func writeHeaders(p *Param) string {
+	b := &bytes.Buffer{}
+	pn := methodName(p.Name)
 
-	for _, p := range mod.Parameters {
-		switch p.Type {
-		case Boolean:
-			m["strconv"] = true
-		case Integer:
-			m["strconv"] = true
-		case Expiry, String:
-		case ListOfIntegers:
-			m["strconv"] = true
-			m["strings"] = true
-		case ListOfStrings:
-			m["strings"] = true
-		case Timestamp:
-			m["time"] = true
-		default:
-			return nil, fmt.Errorf("unsupported parameter type for parameter %s: %s", p.Name, p.Type)
-		}
+	fmt.Fprintf(b, "// %s\n", pn)
+	for _, d := range strings.Split(p.Description, "\n") {
+		fmt.Fprintf(b, "// %s\n", d)
 	}
-
-	var imps []string
-	for k := range m {
-		imps = append(imps, k)
+	if p.Default != "" {
+		fmt.Fprintf(b, "// If %s isn't called, the wiki uses %s.\n", pn, p.Default)
 	}
-	sort.Strings(imps)
 
-	return imps, nil
+	return b.String()
 }
 
-func writeHeaders(m Module, p *Param) string {
+func writeEnumParameter(mn, key string, p *Param, e *enum) string {
 	b := &bytes.Buffer{}
+	pn := methodName(p.Name)
 
-	fmt.Fprintf(b, "// %s\n", caser.String(p.Name))
-	for _, d := range strings.Split(p.Description, "\n") {
-		fmt.Fprintf(b, "// %s\n", d)
+	// The default is documented with its constant.
+	q := *p
+	if c := e.constant(p.Default); c != "" {
+		q.Default = c
 	}
+	fmt.Fprint(b, writeHeaders(&q))
+
+	fmt.Fprintf(b, `func (w *%sClient) %s(s %s) *%sClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["%s"] = string(s)
+	})
+	return w
+}
+`, mn, pn, e.typ, mn, key)
 
 	return b.String()
 }
 
-func writeBooleanParameter(m Module, p *Param) string {
+func writeBooleanParameter(mn, key string, p *Param) string {
 	b := &bytes.Buffer{}
-	mn := caser.String(m.Name)
-	pn := caser.String(p.Name)
+	pn := methodName(p.Name)
 
-	fmt.Fprint(b, writeHeaders(m, p))
+	fmt.Fprint(b, writeHeaders(p))
 
 	fmt.Fprintf(b, `func (w *%sClient) %s(b bool) *%sClient {
 	w.o = append(w.o, func(m map[string]string) {
@@ -190,17 +570,16 @@ func writeBooleanParameter(m Module, p *Param) string {
 	})
 	return w
 }
-`, mn, pn, mn, p.Name)
+`, mn, pn, mn, key)
 
 	return b.String()
 }
 
-func writeIntegerParameter(m Module, p *Param) string {
+func writeIntegerParameter(mn, key string, p *Param) string {
 	b := &bytes.Buffer{}
-	mn := caser.String(m.Name)
-	pn := caser.String(p.Name)
+	pn := methodName(p.Name)
 
-	fmt.Fprint(b, writeHeaders(m, p))
+	fmt.Fprint(b, writeHeaders(p))
 
 	fmt.Fprintf(b, `func (w *%sClient) %s(i int) *%sClient {
 	w.o = append(w.o, func(m map[string]string) {
@@ -208,17 +587,16 @@ func writeIntegerParameter(m Module, p *Param) string {
 	})
 	return w
 }
-`, mn, pn, mn, p.Name)
+`, mn, pn, mn, key)
 
 	return b.String()
 }
 
-func writeStringParameter(m Module, p *Param) string {
+func writeStringParameter(mn, key string, p *Param) string {
 	b := &bytes.Buffer{}
-	mn := caser.String(m.Name)
-	pn := caser.String(p.Name)
+	pn := methodName(p.Name)
 
-	fmt.Fprint(b, writeHeaders(m, p))
+	fmt.Fprint(b, writeHeaders(p))
 
 	fmt.Fprintf(b, `func (w *%sClient) %s(s string) *%sClient {
 	w.o = append(w.o, func(m map[string]string) {
@@ -226,17 +604,16 @@ func writeStringParameter(m Module, p *Param) string {
 	})
 	return w
 }
-`, mn, pn, mn, p.Name)
+`, mn, pn, mn, key)
 
 	return b.String()
 }
 
-func writeTimestampParameter(m Module, p *Param) string {
+func writeTimestampParameter(mn, key string, p *Param) string {
 	b := &bytes.Buffer{}
-	mn := caser.String(m.Name)
-	pn := caser.String(p.Name)
+	pn := methodName(p.Name)
 
-	fmt.Fprint(b, writeHeaders(m, p))
+	fmt.Fprint(b, writeHeaders(p))
 
 	fmt.Fprintf(b, `func (w *%sClient) %s(t time.Time) *%sClient {
 	w.o = append(w.o, func(m map[string]string) {
@@ -244,42 +621,39 @@ func writeTimestampParameter(m Module, p *Param) string {
 	})
 	return w
 }
-`, mn, pn, mn, p.Name)
+`, mn, pn, mn, key)
 
 	return b.String()
 }
 
-func writeVariadicIntParameter(m Module, p *Param) string {
+func writeVariadicIntParameter(mn, key string, p *Param) string {
 	b := &bytes.Buffer{}
-	mn := caser.String(m.Name)
-	pn := caser.String(p.Name)
+	pn := methodName(p.Name)
 
-	fmt.Fprint(b, writeHeaders(m, p))
+	fmt.Fprint(b, writeHeaders(p))
 
 	fmt.Fprintf(b, `func (w *%sClient) %s(i ...int) *%sClient {
-		w.o = append(w.o, func(m map[string]string) {
-			var s []string
-	
-			for _, n := range i {
-				s = append(s, strconv.FormatInt(int64(n), 10))
-			}
-	
-			m["%s"] = strings.Join(s, "|")
-		})
-		return w
-	}
+	w.o = append(w.o, func(m map[string]string) {
+		var s []string
+
+		for _, n := range i {
+			s = append(s, strconv.FormatInt(int64(n), 10))
+		}
+
+		m["%s"] = strings.Join(s, "|")
+	})
+	return w
 }
-`, mn, pn, mn, p.Name)
+`, mn, pn, mn, key)
 
 	return b.String()
 }
 
-func writeVariadicStringParameter(m Module, p *Param) string {
+func writeVariadicStringParameter(mn, key string, p *Param) string {
 	b := &bytes.Buffer{}
-	mn := caser.String(m.Name)
-	pn := caser.String(p.Name)
+	pn := methodName(p.Name)
 
-	fmt.Fprint(b, writeHeaders(m, p))
+	fmt.Fprint(b, writeHeaders(p))
 
 	fmt.Fprintf(b, `func (w *%sClient) %s(s ...string) *%sClient {
 	w.o = append(w.o, func(m map[string]string) {
@@ -287,24 +661,24 @@ func writeVariadicStringParameter(m Module, p *Param) string {
 	})
 	return w
 }
-`, mn, pn, mn, p.Name)
+`, mn, pn, mn, key)
 
 	return b.String()
 }
 
-func writeAdditionalParameter(m Module, p *Param) string {
+func writeAdditionalParameter(mn string, p *Param) string {
 	b := &bytes.Buffer{}
-	mn := caser.String(m.Name)
 
 	fmt.Fprintf(b, "// AdditionalParam\n")
 	for _, d := range strings.Split(p.Description, "\n") {
 		fmt.Fprintf(b, "// %s\n", d)
 	}
 
-	fmt.Fprintf(b, `func (w *%sClient) AdditionalParam(key, s string) *%sOption {
+	fmt.Fprintf(b, `func (w *%sClient) AdditionalParam(key, s string) *%sClient {
 	w.o = append(w.o, func(m map[string]string) {
 		m[key] = s
 	})
+	return w
 }
 `, mn, mn)
 
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+// goldenCases are the modules of testdata/paraminfo.json that are
+// generated into testdata/golden, with their samples.
+var goldenCases = []struct {
+	module  string
+	samples []string
+}{
+	{"query+backlinks", []string{"query_backlinks.json"}},
+	{"query+templates", []string{"query_templates.json"}},
+	{"query+userinfo", []string{"query_userinfo.json"}},
+	{"createaccount", nil},
+}
+
+func goldenFile(module string) string {
+	return filepath.Join("testdata", "golden", strings.ReplaceAll(module, "+", "_")+".go.golden")
+}
+
+func TestGenerateGolden(t *testing.T) {
+	for _, c := range goldenCases {
+		t.Run(c.module, func(t *testing.T) {
+			o := options{paraminfo: filepath.Join("testdata", "paraminfo.json")}
+			for _, s := range c.samples {
+				o.samples = append(o.samples, filepath.Join("testdata", "samples", s))
+			}
+
+			src, err := generateFile(o, c.module)
+			require.NoError(t, err)
+
+			if *update {
+				require.NoError(t, os.WriteFile(goldenFile(c.module), src, 0o644))
+			}
+
+			want, err := os.ReadFile(goldenFile(c.module))
+			require.NoError(t, err)
+			assert.Equal(t, string(want), string(src))
+		})
+	}
+}
+
+func TestGenerateImports(t *testing.T) {
+	m := Module{
+		Name:        "rollback",
+		Path:        "rollback",
+		Group:       "action",
+		Description: "Undo the last edit to the page, at a time.",
+		Parameters: []*Param{
+			{Name: "title", Type: String, Description: "Title of the page to roll back, one at a time."},
+		},
+	}
+
+	src, err := Generate(m)
+	require.NoError(t, err)
+
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, parser.ImportsOnly)
+	require.NoError(t, err)
+
+	var imps []string
+	for _, i := range f.Imports {
+		imps = append(imps, strings.Trim(i.Path.Value, `"`))
+	}
+	assert.Contains(t, imps, "context")
+	assert.NotContains(t, imps, "time", "descriptions don't import packages")
+}
+
+// TestGoldenCompiles builds the package with the golden files added to
+// it.
+func TestGoldenCompiles(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the package")
+	}
+
+	root, err := filepath.Abs("..")
+	require.NoError(t, err)
+
+	overlay := map[string]map[string]string{"Replace": {}}
+	for _, c := range goldenCases {
+		src, err := filepath.Abs(goldenFile(c.module))
+		require.NoError(t, err)
+		overlay["Replace"][filepath.Join(root, "golden_"+filepath.Base(strings.TrimSuffix(src, ".golden")))] = src
+	}
+
+	b, err := json.Marshal(overlay)
+	require.NoError(t, err)
+	f := filepath.Join(t.TempDir(), "overlay.json")
+	require.NoError(t, os.WriteFile(f, b, 0o644))
+
+	cmd := exec.Command("go", "vet", "-overlay", f, ".")
+	cmd.Dir = root
+	out, err := cmd.CombinedOutput()
+	assert.NoError(t, err, string(out))
+}
+
+// TestGeneratedClientsUpToDate checks every client generated by the
+// go:generate directives of the package.
+func TestGeneratedClientsUpToDate(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("..", "generate.go"))
+	require.NoError(t, err)
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(".."))
+	defer os.Chdir(wd)
+
+	n := 0
+	for _, l := range strings.Split(string(b), "\n") {
+		args, ok := strings.CutPrefix(l, "//go:generate go run ./import ")
+		if !ok {
+			continue
+		}
+		n++
+
+		o, module, err := parseArgs(strings.Fields(args), io.Discard)
+		require.NoError(t, err, l)
+		o.check = true
+
+		assert.NoError(t, run(o, module))
+	}
+
+	assert.NotZero(t, n)
+}
+
+func TestCheckDrift(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "createaccount.go")
+	require.NoError(t, os.WriteFile(out, []byte("package mediawiki\n"), 0o644))
+
+	o, module, err := parseArgs([]string{"-paraminfo", filepath.Join("testdata", "paraminfo.json"), "-o", out, "-check", "createaccount"}, io.Discard)
+	require.NoError(t, err)
+	assert.ErrorIs(t, run(o, module), errDrift)
+
+	o.check = false
+	require.NoError(t, run(o, module))
+
+	o.check = true
+	assert.NoError(t, run(o, module))
+
+	_, _, err = parseArgs([]string{"-check", "createaccount"}, io.Discard)
+	assert.Error(t, err)
+}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// This infers the Go types of API responses from recorded samples. The
+// shapes of all samples are merged, so that a field is only required if
+// every sample has it.
+
+// shape is the merged shape of the JSON values at one place in the
+// samples.
+type shape struct {
+	null, boolean, integer, float, timestamp, text bool
+
+	// array is set if arrays were seen, and elem is the shape of their
+	// elements, if any.
+	array bool
+	elem  *shape
+
+	// objects is the number of objects seen, and fields their members,
+	// in the order they first appeared.
+	objects int
+	fields  []*field
+}
+
+type field struct {
+	key   string
+	count int
+	shape *shape
+}
+
+// timestampPattern matches the timestamps of the API.
+var timestampPattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}Z$`)
+
+// member is a member of a decoded JSON object.
+type member struct {
+	key   string
+	value any
+}
+
+// decodeOrdered decodes the next JSON value of dec, keeping the members
+// of objects in order. Numbers are decoded as json.Number.
+func decodeOrdered(dec *json.Decoder) (any, error) {
+	t, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t {
+	case json.Delim('{'):
+		var o []member
+		for dec.More() {
+			k, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			v, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			o = append(o, member{k.(string), v})
+		}
+		_, err := dec.Token()
+		return o, err
+	case json.Delim('['):
+		a := []any{}
+		for dec.More() {
+			v, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, v)
+		}
+		_, err := dec.Token()
+		return a, err
+	}
+
+	return t, nil
+}
+
+// readSamples decodes recorded responses. b holds either one response
+// or an array of responses.
+func readSamples(b []byte) ([]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	v, err := decodeOrdered(dec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sample: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid sample: trailing data")
+	}
+
+	if a, ok := v.([]any); ok {
+		return a, nil
+	}
+
+	return []any{v}, nil
+}
+
+// lookup returns the member of the object v with the key.
+func lookup(v any, key string) (any, bool) {
+	o, _ := v.([]member)
+	for _, m := range o {
+		if m.key == key {
+			return m.value, true
+		}
+	}
+
+	return nil, false
+}
+
+// moduleValues returns the values the module returned in the sample
+// response: the member named after an action module, the member of the
+// query of a list or meta module, or the members of the pages of a prop
+// module.
+func moduleValues(m Module, sample any) []any {
+	if !m.Query() {
+		v, ok := lookup(sample, m.Name)
+		if !ok {
+			return nil
+		}
+		return []any{v}
+	}
+
+	q, _ := lookup(sample, "query")
+	if m.Group != "prop" {
+		v, ok := lookup(q, m.Name)
+		if !ok {
+			return nil
+		}
+		return []any{v}
+	}
+
+	var vs []any
+	pages, _ := lookup(q, "pages")
+	for _, p := range asArray(pages) {
+		if v, ok := lookup(p, m.Name); ok {
+			vs = append(vs, v)
+		}
+	}
+
+	return vs
+}
+
+func asArray(v any) []any {
+	a, _ := v.([]any)
+	return a
+}
+
+// shapeOf returns the shape of a decoded value.
+func shapeOf(v any) *shape {
+	s := &shape{}
+
+	switch v := v.(type) {
+	case nil:
+		s.null = true
+	case bool:
+		s.boolean = true
+	case json.Number:
+		if _, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			s.integer = true
+		} else {
+			s.float = true
+		}
+	case string:
+		if timestampPattern.MatchString(v) {
+			s.timestamp = true
+		} else {
+			s.text = true
+		}
+	case []any:
+		s.array = true
+		for _, e := range v {
+			s.elem = s.elem.merge(shapeOf(e))
+		}
+	case []member:
+		s.objects = 1
+		for _, m := range v {
+			s.fields = append(s.fields, &field{m.key, 1, shapeOf(m.value)})
+		}
+	}
+
+	return s
+}
+
+// merge merges o into s and returns the result. s may be nil.
+func (s *shape) merge(o *shape) *shape {
+	if s == nil {
+		return o
+	} else if o == nil {
+		return s
+	}
+
+	s.null = s.null || o.null
+	s.boolean = s.boolean || o.boolean
+	s.integer = s.integer || o.integer
+	s.float = s.float || o.float
+	s.timestamp = s.timestamp || o.timestamp
+	s.text = s.text || o.text
+	s.array = s.array || o.array
+	s.elem = s.elem.merge(o.elem)
+
+	s.objects += o.objects
+	for _, of := range o.fields {
+		if f := s.field(of.key); f != nil {
+			f.count += of.count
+			f.shape = f.shape.merge(of.shape)
+		} else {
+			s.fields = append(s.fields, of)
+		}
+	}
+
+	return s
+}
+
+func (s *shape) field(key string) *field {
+	for _, f := range s.fields {
+		if f.key == key {
+			return f
+		}
+	}
+
+	return nil
+}
+
+// kinds returns the number of kinds of values seen, not counting null.
+// Integers and floats are both numbers, and timestamps are strings.
+func (s *shape) kinds() int {
+	n := 0
+	for _, k := range []bool{s.boolean, s.integer || s.float, s.timestamp || s.text, s.array, s.objects > 0} {
+		if k {
+			n++
+		}
+	}
+
+	return n
+}
+
+// numericKeys reports whether all keys of the objects are numbers, such
+// as page IDs, in which case the objects are maps.
+func (s *shape) numericKeys() bool {
+	for _, f := range s.fields {
+		if _, err := strconv.Atoi(f.key); err != nil {
+			return false
+		}
+	}
+
+	return len(s.fields) > 0
+}
+
+// typeWriter writes the struct types of inferred shapes.
+type typeWriter struct {
+	decls []string
+	names map[string]bool
+}
+
+// goType returns the Go type of s, writing the struct types it needs,
+// named after name.
+func (w *typeWriter) goType(s *shape, name string) string {
+	if s == nil || s.kinds() != 1 {
+		return "json.RawMessage"
+	}
+
+	switch {
+	case s.boolean:
+		return "bool"
+	case s.float:
+		return "float64"
+	case s.integer:
+		return "int"
+	case s.text:
+		return "string"
+	case s.timestamp:
+		return "*time.Time"
+	case s.array:
+		if s.elem == nil {
+			return "[]json.RawMessage"
+		}
+		return "[]" + w.goType(s.elem, name)
+	case s.numericKeys():
+		var v *shape
+		for _, f := range s.fields {
+			v = v.merge(f.shape)
+		}
+		return "map[string]" + w.goType(v, name)
+	}
+
+	return w.writeStruct(s, name)
+}
+
+// writeStruct writes the struct type of an object shape, and returns its
+// name.
+func (w *typeWriter) writeStruct(s *shape, name string) string {
+	for i, base := 2, name; w.names[name]; i++ {
+		name = base + strconv.Itoa(i)
+	}
+	w.names[name] = true
+
+	// Types are written in the order they are first needed.
+	i := len(w.decls)
+	w.decls = append(w.decls, "")
+
+	// Fields are named after the struct, without the suffix of the
+	// outermost type.
+	base := strings.TrimSuffix(strings.TrimSuffix(name, "Response"), "Result")
+
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "\ntype %s struct {\n", name)
+
+	used := map[string]bool{}
+	for _, f := range s.fields {
+		fn := fieldName(f.key)
+		for i := 2; used[fn]; i++ {
+			fn = fieldName(f.key) + strconv.Itoa(i)
+		}
+		used[fn] = true
+
+		optional := f.count < s.objects || f.shape.null
+		t := w.goType(f.shape, base+fieldName(f.key))
+		if f.key == "ns" && t == "int" {
+			t = "Namespace"
+		}
+
+		tag := f.key
+		switch {
+		case strings.HasPrefix(t, "*"):
+			tag += ",omitempty"
+		case optional && f.shape.objects > 0 && !strings.HasPrefix(t, "map["):
+			t = "*" + t
+			tag += ",omitempty"
+		case optional:
+			tag += ",omitempty"
+		}
+
+		fmt.Fprintf(b, "\t%s %s `json:%q`\n", fn, t, tag)
+	}
+	b.WriteString("}\n")
+
+	w.decls[i] = b.String()
+
+	return name
+}
+
+// fieldName returns the name of the struct field of a JSON key.
+func fieldName(key string) string {
+	n := methodName(key)
+	if n == "" || n[0] >= '0' && n[0] <= '9' {
+		n = "F" + n
+	}
+
+	return n
+}
+
+// inferResponse infers the type of the values the module returned in
+// the samples. It returns the type of the field holding them, and the
+// struct types it needs. Structs are named after name.
+func inferResponse(m Module, samples [][]byte, name string) (string, string, error) {
+	var s *shape
+	for i, b := range samples {
+		vs, err := readSamples(b)
+		if err != nil {
+			return "", "", fmt.Errorf("sample %d: %w", i+1, err)
+		}
+
+		for _, v := range vs {
+			for _, mv := range moduleValues(m, v) {
+				s = s.merge(shapeOf(mv))
+			}
+		}
+	}
+
+	if s == nil {
+		return "", "", fmt.Errorf("no sample has a result of %s", m.Path)
+	}
+
+	w := &typeWriter{names: map[string]bool{}}
+	t := w.goType(s, name)
+
+	// Results of action modules are checked for nil.
+	switch {
+	case strings.HasPrefix(t, "*"), strings.HasPrefix(t, "[]"), strings.HasPrefix(t, "map["), t == "json.RawMessage":
+	default:
+		t = "*" + t
+	}
+
+	return t, strings.Join(w.decls, ""), nil
+}
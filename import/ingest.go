@@ -1,175 +1,259 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"html"
 	"io"
-	"net/http"
-	"net/url"
+	"regexp"
 	"strings"
 
-	"golang.org/x/net/html"
+	"github.com/clockworksoul/mediawiki"
 )
 
-type TextMode int
-
-const (
-	None TextMode = iota
-	ModuleName
-	ModuleFlag
-	ModuleFlags
-	ModuleDescription
-	ModuleHelpUrl
-	Parameters
-	Var
-	Description
-	Info
-)
+// defaultAPI is the wiki whose modules are imported by default.
+const defaultAPI = "https://www.mediawiki.org/w/api.php"
 
-func attrMap(attr []html.Attribute) map[string]string {
-	m := map[string]string{}
+// fetchParaminfo fetches the parameter information of the modules from
+// the wiki at api, and returns the JSON response. Help texts are
+// requested as wikitext.
+func fetchParaminfo(ctx context.Context, api string, modules ...string) ([]byte, error) {
+	c, err := mediawiki.New(api, mediawiki.DefaultUserAgent)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, a := range attr {
-		m[a.Key] = a.Val
+	r, err := c.Paraminfo().Modules(modules...).Helpformat("wikitext").Do(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	return m
+	return []byte(r.RawJSON), nil
 }
 
-func getModulePage(module string) (io.ReadCloser, error) {
-	url := fmt.Sprintf("https://www.mediawiki.org/w/api.php?action=help&modules=%s", url.PathEscape(module))
-
-	r, err := http.Get(url)
+// readParaminfo decodes a paraminfo response, as returned by
+// fetchParaminfo or saved from
+// api.php?action=paraminfo&format=json&formatversion=2&helpformat=wikitext.
+func readParaminfo(r io.Reader) ([]mediawiki.ParaminfoModule, error) {
+	b, err := io.ReadAll(r)
 	if err != nil {
 		return nil, err
 	}
 
-	fmt.Println(r.StatusCode)
+	var resp mediawiki.ParaminfoResponse
+	if err := mediawiki.ParseResponse(b, &resp); err != nil {
+		return nil, err
+	}
 
-	return r.Body, nil
+	if len(resp.Errors) > 0 && resp.Error == nil {
+		resp.Error = &resp.Errors[0]
+	}
+
+	if e := resp.Error; e != nil {
+		info := e.Info
+		if info == "" {
+			info = e.Text
+		}
+		if info == "" {
+			info = e.Star
+		}
+		return nil, fmt.Errorf("%s: %s", e.Code, info)
+	} else if resp.Paraminfo == nil {
+		return nil, fmt.Errorf("not a paraminfo response")
+	}
+
+	return resp.Paraminfo.Modules, nil
 }
 
-func parseModulePage(r io.ReadCloser) (Module, error) {
-	defer r.Close()
-
-	module := Module{}
-	mode := None
-	z := html.NewTokenizer(r)
-	b := strings.Builder{}
-	p := &Param{}
-
-	for i := 1; ; i++ {
-		tt := z.Next()
-
-		switch tt {
-		case html.ErrorToken:
-			return module, nil
-
-		case html.DoctypeToken:
-		case html.SelfClosingTagToken:
-			// No-op
-
-		case html.EndTagToken:
-			t := z.Token()
-
-			if mode == None {
-				continue
-			}
-
-			switch {
-			case mode == ModuleFlag && t.Data == "li":
-				mode = ModuleFlags
-			case mode == ModuleFlags && t.Data == "div":
-				b.Reset()
-				mode = ModuleDescription
-			case mode == Var && t.Data == "dt":
-				p = &Param{Name: strings.TrimSpace(b.String()), Type: String}
-				module.Parameters = append(module.Parameters, p)
-				b.Reset()
-				mode = Parameters
-			case mode == Description && t.Data == "dd":
-				p.Description = strings.TrimSpace(b.String())
-				b.Reset()
-				mode = Parameters
-			case mode == Info && t.Data == "dd":
-				s := b.String()
-
-				switch {
-				case s == "Deprecated.":
-					p.Deprecated = true
-				case strings.Contains(s, "equired"):
-					p.Required = true
-				case strings.Contains(s, "separate with |"):
-					p.Type = ListOfStrings
-				case strings.HasPrefix(s, "Type: "):
-					s := s[6:]
-					if i := strings.Index(s, " ("); i != -1 {
-						s = s[:i]
-					}
-
-					switch s {
-					case "boolean":
-						p.Type = Boolean
-					case "expiry":
-						p.Type = Expiry
-					case "integer":
-						p.Type = Integer
-					case "timestamp":
-						p.Type = Timestamp
-					case "list of integers":
-						p.Type = ListOfIntegers
-					default:
-						fmt.Printf("Warning: Unhandled type for parameter %s: %s\n", p.Name, s)
-						continue
-						// return Module{}, fmt.Errorf("unhandled type for parameter %s: %s", p.Name, s)
-					}
-				default:
-					b.Reset()
-					b.WriteString(p.Description)
-					b.WriteRune('\n')
-					b.WriteString(s)
-					p.Description = b.String()
-				}
-
-				b.Reset()
-				mode = Parameters
-			}
-
-		case html.StartTagToken:
-			t := z.Token()
-			m := attrMap(t.Attr)
-
-			switch {
-			case t.Data == "h2" && m["class"] == "apihelp-header apihelp-module-name":
-				module.Name = m["id"]
-			case t.Data == "span" && strings.HasPrefix(m["class"], "apihelp-flag"):
-				mode = ModuleFlag
-			case m["class"] == "apihelp-block apihelp-help-urls":
-				module.Description = strings.TrimSpace(b.String())
-				b.Reset()
-				mode = ModuleHelpUrl
-			case m["class"] == "apihelp-block apihelp-parameters":
-				mode = Parameters
-			case m["class"] == "apihelp-block apihelp-examples":
-				mode = None
-			case mode != None && t.Data == "dt":
-				b.Reset()
-				mode = Var
-			case mode != None && t.Data == "dd" && m["class"] == "description":
-				b.Reset()
-				mode = Description
-			case mode != None && t.Data == "dd" && m["class"] == "info":
-				b.Reset()
-				mode = Info
-			}
-
-		case html.TextToken:
-			text := string(z.Text())
-
-			if mode == ModuleFlag {
-				module.Flags = append(module.Flags, text)
-			} else if mode != None {
-				b.WriteString(text)
-			}
+// findModule returns the module with the given path, such as "edit"
+// or "query+allpages".
+func findModule(mods []mediawiki.ParaminfoModule, path string) (mediawiki.ParaminfoModule, error) {
+	for _, m := range mods {
+		if m.Path == path {
+			return m, nil
 		}
 	}
+
+	return mediawiki.ParaminfoModule{}, fmt.Errorf("no such module: %s", path)
+}
+
+// moduleFromParaminfo converts the parameter information of a module.
+// Parameters that can't be set by a builder method, such as file
+// uploads, and tokens, which Do fetches itself, are skipped.
+func moduleFromParaminfo(pm mediawiki.ParaminfoModule) Module {
+	m := Module{
+		Name:        pm.Name,
+		Path:        pm.Path,
+		Group:       pm.Group,
+		Prefix:      pm.Prefix,
+		Description: cleanWikitext(string(pm.Description)),
+		Generator:   pm.Generator,
+		Post:        pm.Mustbeposted,
+	}
+
+	if m.Group == "" {
+		m.Group = "action"
+	}
+
+	if len(pm.Helpurls) > 0 {
+		m.Description = strings.TrimSpace(m.Description + "\n" + pm.Helpurls[0])
+	}
+
+	for _, f := range []struct {
+		set  bool
+		text string
+	}{
+		{pm.Deprecated, "This module is deprecated."},
+		{pm.Internal, "This module is internal or unstable, and its operation may change without notice."},
+		{pm.Readrights, "This module requires read rights."},
+		{pm.Writerights, "This module requires write rights."},
+		{pm.Mustbeposted, "This module only accepts POST requests."},
+		{pm.Generator, "This module can be used as a generator."},
+	} {
+		if f.set {
+			m.Flags = append(m.Flags, f.text)
+		}
+	}
+	if pm.Source != "" && pm.Source != "MediaWiki" {
+		m.Flags = append(m.Flags, "Source: "+pm.Source)
+	}
+
+	if pm.Dynamicparameters != nil {
+		d, _ := pm.Dynamicparameters.(string)
+		if d = cleanWikitext(d); d == "" {
+			d = "This module accepts additional parameters."
+		}
+		m.Parameters = append(m.Parameters, &Param{
+			Name:        "*",
+			Type:        String,
+			Description: d,
+		})
+	}
+
+	for _, pp := range pm.Parameters {
+		if pp.Tokentype != "" {
+			m.Token = pp.Tokentype
+		}
+		if p, ok := paramFromParaminfo(pp); ok {
+			m.Parameters = append(m.Parameters, p)
+		}
+	}
+
+	return m
+}
+
+// paramFromParaminfo converts the information of a parameter. It
+// reports false if the parameter can't be set by a builder method.
+func paramFromParaminfo(pp mediawiki.ParaminfoParameter) (*Param, bool) {
+	p := &Param{
+		Name:       pp.Name,
+		Type:       String,
+		Deprecated: pp.Deprecated,
+		Required:   pp.Required,
+		Values:     pp.Values(),
+	}
+
+	typ, _ := pp.Type.(string)
+	switch {
+	case p.Values != nil:
+		typ = "enum"
+	case typ == "upload", pp.Tokentype != "":
+		return nil, false
+	}
+
+	switch typ {
+	case "boolean":
+		p.Type = Boolean
+	case "integer", "limit", "namespace":
+		p.Type = Integer
+		if pp.Multi {
+			p.Type = ListOfIntegers
+		}
+	case "timestamp":
+		p.Type = Timestamp
+	case "expiry":
+		p.Type = Expiry
+	}
+	if pp.Multi && p.Type != ListOfIntegers {
+		p.Type = ListOfStrings
+	}
+
+	if pp.Default != nil && typ != "boolean" {
+		p.Default = fmt.Sprint(pp.Default)
+	}
+
+	p.Description = describeParam(pp, typ)
+
+	return p, true
+}
+
+// describeParam returns the documentation of a parameter, in the style
+// of the API help pages. The default is documented by the generator.
+func describeParam(pp mediawiki.ParaminfoParameter, typ string) string {
+	var lines []string
+
+	if pp.Deprecated {
+		lines = append(lines, "Deprecated.")
+	}
+	if d := cleanWikitext(string(pp.Description)); d != "" {
+		lines = append(lines, d)
+	}
+	if pp.Required {
+		lines = append(lines, "This parameter is required.")
+	}
+
+	switch {
+	case typ == "enum" && pp.Multi:
+		lines = append(lines, "Values (separate with | or alternative): "+strings.Join(pp.Values(), ", "))
+	case typ == "enum":
+		lines = append(lines, "One of the following values: "+strings.Join(pp.Values(), ", "))
+	case pp.Multi:
+		lines = append(lines, "Separate values with | or alternative.")
+	}
+
+	if pp.Multi && pp.Lowlimit > 0 {
+		lines = append(lines, fmt.Sprintf("Maximum number of values is %d (%d for clients allowed higher limits).", pp.Lowlimit, pp.Highlimit))
+	}
+
+	switch typ {
+	case "", "enum", "string", "text":
+	default:
+		lines = append(lines, "Type: "+typ)
+	}
+
+	switch {
+	case pp.Min != nil && pp.Max != nil:
+		lines = append(lines, fmt.Sprintf("The value must be between %v and %v.", pp.Min, pp.Max))
+	case pp.Min != nil:
+		lines = append(lines, fmt.Sprintf("The value must be no less than %v.", pp.Min))
+	case pp.Max != nil:
+		lines = append(lines, fmt.Sprintf("The value must be no greater than %v.", pp.Max))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+var (
+	wikiLink     = regexp.MustCompile(`\[\[(?:[^|\]]*\|)?([^\]]*)\]\]`)
+	externalLink = regexp.MustCompile(`\[(?:https?:)?//[^ \]]+ ([^\]]*)\]`)
+	htmlTag      = regexp.MustCompile(`<[^>]*>`)
+	emphasis     = regexp.MustCompile(`'{2,}`)
+)
+
+// cleanWikitext turns a wikitext help text into plain text lines.
+func cleanWikitext(s string) string {
+	s = wikiLink.ReplaceAllString(s, "$1")
+	s = externalLink.ReplaceAllString(s, "$1")
+	s = htmlTag.ReplaceAllString(s, "")
+	s = emphasis.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+
+	var lines []string
+	for _, l := range strings.Split(s, "\n") {
+		if l = strings.Join(strings.Fields(l), " "); l != "" {
+			lines = append(lines, l)
+		}
+	}
+
+	return strings.Join(lines, "\n")
 }
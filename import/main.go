@@ -1,33 +1,175 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 )
 
+// options are the command line flags.
+type options struct {
+	api       string
+	paraminfo string
+	save      string
+	samples   fileList
+	out       string
+	check     bool
+
+	// all, dir and sampleDir are the options of batch mode.
+	all       bool
+	dir       string
+	sampleDir string
+}
+
+// fileList is a flag that can be given more than once.
+type fileList []string
+
+func (l *fileList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *fileList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// errDrift is returned in check mode if the generated files differ from
+// those on disk.
+var errDrift = errors.New("is out of date")
+
 func main() {
-	if len(os.Args) != 2 {
-		fmt.Println("Syntax: import <module>")
+	o, module, err := parseArgs(os.Args[1:], os.Stderr)
+	if err != nil {
+		os.Exit(2)
+	}
+
+	if o.all {
+		err = runAll(o, os.Stdout)
+	} else {
+		err = run(o, module)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "import:", err)
 		os.Exit(1)
-		return
 	}
+}
+
+// parseArgs parses the command line arguments, and returns the options
+// and the module to generate. Usage errors are written to w.
+func parseArgs(args []string, w io.Writer) (options, string, error) {
+	var o options
+
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.SetOutput(w)
+	fs.StringVar(&o.api, "api", defaultAPI, "`URL` of the api.php of the wiki to import from")
+	fs.StringVar(&o.paraminfo, "paraminfo", "", "read the paraminfo JSON from `file` instead of fetching it")
+	fs.StringVar(&o.save, "save", "", "save the fetched paraminfo JSON to `file`")
+	fs.Var(&o.samples, "sample", "infer the response types from the recorded responses in `file`, which holds one response or an array of them; may be repeated")
+	fs.StringVar(&o.out, "o", "", "write the client to `file` instead of the standard output")
+	fs.BoolVar(&o.check, "check", false, "don't write the client, but fail if the file of -o, or the directory of -dir, differs from it")
+	fs.BoolVar(&o.all, "all", false, "generate the clients of all modules into the directory of -dir, and report the changes")
+	fs.StringVar(&o.dir, "dir", "", "write the clients of all modules to `directory`")
+	fs.StringVar(&o.sampleDir, "samples", "", "with -all, read the recorded responses of each module from `directory`, named after the path of the module with + replaced by _, such as query_allpages.json")
+
+	fs.Usage = func() {
+		fmt.Fprintln(w, "Syntax: import [flags] <module>")
+		fmt.Fprintln(w, "        import -all -dir <directory> [flags]")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return o, "", err
+	}
+
+	valid := fs.NArg() == 1 && (!o.check || o.out != "") && o.dir == "" && o.sampleDir == ""
+	if o.all {
+		valid = fs.NArg() == 0 && o.dir != "" && o.out == "" && len(o.samples) == 0
+	}
+	if !valid {
+		fs.Usage()
+		return o, "", errors.New("invalid arguments")
+	}
+
+	return o, fs.Arg(0), nil
+}
+
+// run generates the client of module, from the paraminfo in the file of
+// the options if it is set, or else fetched from the api and saved if
+// save is set. The client is written to the output file if it is set,
+// or compared to it in check mode, and else printed.
+func run(o options, module string) error {
+	src, err := generateFile(o, module)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case o.check:
+		old, err := os.ReadFile(o.out)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(old, src) {
+			return fmt.Errorf("%s %w", o.out, errDrift)
+		}
+		return nil
+	case o.out != "":
+		return os.WriteFile(o.out, src, 0o644)
+	}
+
+	_, err = os.Stdout.Write(src)
+	return err
+}
+
+// generatedHeader marks generated files, as recognized by go vet and
+// other tools.
+const generatedHeader = "// Code generated by import; DO NOT EDIT.\n\n"
+
+// generateFile returns the source file of the client of module.
+func generateFile(o options, module string) ([]byte, error) {
+	var b []byte
+	var err error
 
-	p := os.Args[1]
+	if o.paraminfo != "" {
+		b, err = os.ReadFile(o.paraminfo)
+	} else {
+		b, err = fetchParaminfo(context.Background(), o.api, module)
+		if err == nil && o.save != "" {
+			err = os.WriteFile(o.save, append(b, '\n'), 0o644)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
 
-	r, err := getModulePage(p)
+	mods, err := readParaminfo(bytes.NewReader(b))
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	m, err := parseModulePage(r)
+	pm, err := findModule(mods, module)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+
+	m := moduleFromParaminfo(pm)
+	for _, f := range o.samples {
+		s, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		m.Samples = append(m.Samples, s)
 	}
 
 	text, err := Generate(m)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	fmt.Println(text)
+	return []byte(generatedHeader + text), nil
 }
@@ -1,10 +1,39 @@
 package main
 
+import "strings"
+
 type Module struct {
-	Name        string
+	// Name is the name of the module, such as "edit" or "allpages", and
+	// Path its path, such as "edit" or "query+allpages".
+	Name, Path string
+
+	// Client is the name of the client, if not derived from Name.
+	Client string
+
+	// Group is the parameter that selects the module, such as "action"
+	// or "list", and Prefix the prefix of its parameters.
+	Group, Prefix string
+
 	Description string
 	Parameters  []*Param
 	Flags       []string
+
+	// Generator is set if a query module can be used as a generator.
+	Generator bool
+
+	// Post is set if the module only accepts POST requests, and Token
+	// is the type of the token it requires, if any.
+	Post  bool
+	Token string
+
+	// Samples are recorded responses of the module, from which the types
+	// of its results are inferred.
+	Samples [][]byte
+}
+
+// Query reports whether the module is a submodule of action=query.
+func (m Module) Query() bool {
+	return strings.HasPrefix(m.Path, "query+")
 }
 
 type ParamType string
@@ -20,7 +49,15 @@ const (
 )
 
 type Param struct {
+	// Name is the name of the parameter without the prefix of the
+	// module.
 	Name, Description    string
 	Type                 ParamType
 	Deprecated, Required bool
+
+	// Values are the allowed values of an enumerated parameter.
+	Values []string
+
+	// Default is the value used when the parameter isn't sent.
+	Default string
 }
@@ -0,0 +1,96 @@
+package mediawiki
+
+import (
+	"context"
+	"time"
+)
+
+// Outcomes reported to Metrics.RequestDone.
+const (
+	OutcomeSuccess        = "success"
+	OutcomeAPIError       = "api_error"
+	OutcomeTransportError = "transport_error"
+)
+
+// Metrics receives measurements about the API calls made by a Client.
+// Implementations must be safe for concurrent use. The mwprom module
+// provides a Prometheus implementation, so that only its users depend
+// on the Prometheus client.
+type Metrics interface {
+	// RequestDone is called once for every API call, after any retries,
+	// with the call's action, its outcome (one of the Outcome constants)
+	// and its total duration.
+	RequestDone(action, outcome string, d time.Duration)
+
+	// Retry is called every time a call is retried.
+	Retry(action string)
+
+	// MaxlagWait is called every time the client waits because the
+	// server reported replication lag.
+	MaxlagWait(d time.Duration)
+
+	// TokenRefresh is called every time a token is fetched from the
+	// server instead of the token cache.
+	TokenRefresh(token Token)
+
+	// Relogin is called every time the client logs in again because its
+	// session expired.
+	Relogin()
+}
+
+// Tracer creates spans for API calls. The mwotel module provides an
+// OpenTelemetry implementation.
+type Tracer interface {
+	// Start creates a span named name with the given attributes, and
+	// returns a context containing it.
+	Start(ctx context.Context, name string, attrs map[string]string) (context.Context, Span)
+}
+
+// Span is a single traced operation.
+type Span interface {
+	// SetAttributes adds attributes to the span.
+	SetAttributes(attrs map[string]string)
+
+	// End completes the span. If err is non-nil, the span is marked
+	// as failed.
+	End(err error)
+}
+
+// NopMetrics is a Metrics implementation that discards everything.
+type NopMetrics struct{}
+
+func (NopMetrics) RequestDone(string, string, time.Duration) {}
+func (NopMetrics) Retry(string)                              {}
+func (NopMetrics) MaxlagWait(time.Duration)                  {}
+func (NopMetrics) TokenRefresh(Token)                        {}
+func (NopMetrics) Relogin()                                  {}
+
+// NopTracer is a Tracer implementation whose spans do nothing.
+type NopTracer struct{}
+
+func (NopTracer) Start(ctx context.Context, _ string, _ map[string]string) (context.Context, Span) {
+	return ctx, nopSpan{}
+}
+
+type nopSpan struct{}
+
+func (nopSpan) SetAttributes(map[string]string) {}
+func (nopSpan) End(error)                       {}
+
+// metrics returns the client's Metrics, or NopMetrics if none is set.
+func (w *Client) metrics() Metrics {
+	if w.Metrics == nil {
+		return NopMetrics{}
+	}
+
+	return w.Metrics
+}
+
+// tracer returns the client's Tracer, or NopTracer if none is set.
+func (w *Client) tracer() Tracer {
+	if w.Tracer == nil {
+		return NopTracer{}
+	}
+
+	return w.Tracer
+}
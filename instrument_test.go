@@ -0,0 +1,153 @@
+package mediawiki
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/clockworksoul/mediawiki/internal/wikitest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingMetrics struct {
+	sync.Mutex
+	requests []string
+	retries  int
+	waits    int
+	tokens   []Token
+	relogins int
+}
+
+func (m *recordingMetrics) RequestDone(action, outcome string, _ time.Duration) {
+	m.Lock()
+	defer m.Unlock()
+	m.requests = append(m.requests, action+":"+outcome)
+}
+
+func (m *recordingMetrics) Retry(string) {
+	m.Lock()
+	defer m.Unlock()
+	m.retries++
+}
+
+func (m *recordingMetrics) MaxlagWait(time.Duration) {
+	m.Lock()
+	defer m.Unlock()
+	m.waits++
+}
+
+func (m *recordingMetrics) TokenRefresh(t Token) {
+	m.Lock()
+	defer m.Unlock()
+	m.tokens = append(m.tokens, t)
+}
+
+func (m *recordingMetrics) Relogin() {
+	m.Lock()
+	defer m.Unlock()
+	m.relogins++
+}
+
+type recordingTracer struct {
+	spans []*recordingSpan
+}
+
+type recordingSpan struct {
+	name  string
+	attrs map[string]string
+	ended bool
+	err   error
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, attrs map[string]string) (context.Context, Span) {
+	s := &recordingSpan{name: name, attrs: attrs}
+	t.spans = append(t.spans, s)
+	return ctx, s
+}
+
+func (s *recordingSpan) SetAttributes(attrs map[string]string) {
+	for k, v := range attrs {
+		s.attrs[k] = v
+	}
+}
+
+func (s *recordingSpan) End(err error) {
+	s.ended = true
+	s.err = err
+}
+
+func TestInstrumentEdit(t *testing.T) {
+	s := wikitest.New(t, map[string]http.HandlerFunc{
+		"edit": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"edit":{"result":"Success","title":"Foo","newrevid":2}}`))
+		},
+	})
+
+	c, err := New(s.URL, agent)
+	require.NoError(t, err)
+
+	m := &recordingMetrics{}
+	tr := &recordingTracer{}
+	c.Metrics = m
+	c.Tracer = tr
+
+	_, err = c.Edit().Title("Foo").Text("Bar").Do(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"query:success", "edit:success"}, m.requests)
+	assert.Equal(t, []Token{CSRFToken}, m.tokens)
+
+	require.Len(t, tr.spans, 2)
+	span := tr.spans[1]
+	assert.Equal(t, "mediawiki.edit", span.name)
+	assert.Equal(t, "edit", span.attrs["mediawiki.action"])
+	assert.Equal(t, "Foo", span.attrs["mediawiki.title"])
+	assert.Equal(t, "200", span.attrs["http.status_code"])
+	assert.True(t, span.ended)
+	assert.NoError(t, span.err)
+}
+
+func TestInstrumentAPIError(t *testing.T) {
+	calls := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Retry-After", "0")
+		w.Write([]byte(`{"error":{"code":"maxlag","info":"Waiting for a database server"}}`))
+	}))
+	defer s.Close()
+
+	c, err := New(s.URL, agent)
+	require.NoError(t, err)
+
+	m := &recordingMetrics{}
+	tr := &recordingTracer{}
+	c.Metrics = m
+	c.Tracer = tr
+	c.Maxlag.On = true
+	c.Maxlag.Retries = 2
+
+	_, err = c.Revisions().Titles("Foo").Do(context.Background())
+	require.Error(t, err)
+
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, 2, m.retries)
+	assert.Equal(t, 2, m.waits)
+	assert.Equal(t, []string{"query:api_error"}, m.requests)
+
+	require.Len(t, tr.spans, 1)
+	assert.Equal(t, "revisions", tr.spans[0].attrs["mediawiki.module"])
+	assert.Equal(t, "maxlag", tr.spans[0].attrs["mediawiki.error_code"])
+	assert.Error(t, tr.spans[0].err)
+}
+
+func TestInstrumentDefaultsAreNoops(t *testing.T) {
+	c, err := New("https://example.org/w/api.php", agent)
+	require.NoError(t, err)
+
+	assert.Equal(t, NopMetrics{}, c.metrics())
+	assert.Equal(t, NopTracer{}, c.tracer())
+}
@@ -0,0 +1,142 @@
+// Package wikitest provides the fake wiki of the tests of the client and
+// its companion modules.
+package wikitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// CSRFToken is the CSRF token handed out by the wiki. Like real tokens,
+// it ends with +\, which breaks requests that don't encode it.
+const CSRFToken = `abc+\`
+
+// Wiki is a fake wiki. It hands out CSRF tokens, and passes every other
+// request to the handler of its module in Handlers:
+//
+//   - an action, such as "edit", has the handler of its name;
+//   - a query has the handler of its first prop, list, meta or generator
+//     module that has one, as in "query+revisions", or else of "query";
+//   - any other request, including those without an action such as the
+//     downloads of files, has the handler of "*".
+//
+// Requests without a handler fail the test. The forms of the requests,
+// other than those for tokens, are recorded. Handlers may be called
+// concurrently.
+type Wiki struct {
+	*httptest.Server
+
+	t        testing.TB
+	handlers map[string]http.HandlerFunc
+
+	mu       sync.Mutex
+	requests []url.Values
+}
+
+// New returns a wiki with the handlers, which is closed at the end of
+// the test.
+func New(t testing.TB, handlers map[string]http.HandlerFunc) *Wiki {
+	t.Helper()
+
+	w := &Wiki{t: t, handlers: handlers}
+	w.Server = httptest.NewServer(w)
+	t.Cleanup(w.Close)
+
+	return w
+}
+
+// Requests returns the forms of the requests received so far, other than
+// those for tokens.
+func (w *Wiki) Requests() []url.Values {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return append([]url.Values{}, w.requests...)
+}
+
+func (w *Wiki) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	// The body is parsed from a copy, so that handlers can read it as
+	// well. A body that can't be read or parsed was cut short by a client
+	// that gave up on the request, which isn't passed on.
+	b, err := io.ReadAll(r.Body)
+	p := r.Clone(r.Context())
+	p.Body = io.NopCloser(bytes.NewReader(b))
+	if perr := p.ParseMultipartForm(1 << 20); err == nil && !errors.Is(perr, http.ErrNotMultipart) {
+		err = perr
+	}
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.Form, r.PostForm, r.MultipartForm = p.Form, p.PostForm, p.MultipartForm
+	r.Body = io.NopCloser(bytes.NewReader(b))
+	v := r.Form
+
+	h := w.handler(v)
+	if h == nil && v.Get("action") == "query" && has(v.Get("meta"), "tokens") {
+		Reply(rw, map[string]any{"batchcomplete": true, "query": map[string]any{"tokens": map[string]any{"csrftoken": CSRFToken}}})
+		return
+	}
+
+	w.mu.Lock()
+	w.requests = append(w.requests, v)
+	w.mu.Unlock()
+
+	if h == nil {
+		w.t.Errorf("unexpected %s request: %s", r.Method, v.Encode())
+		Reply(rw, map[string]any{"errors": []any{map[string]any{"code": "unexpected", "text": "Unexpected request."}}})
+		return
+	}
+
+	h(rw, r)
+}
+
+// handler returns the handler of the request with the form v, or nil if
+// there is none.
+func (w *Wiki) handler(v url.Values) http.HandlerFunc {
+	action := v.Get("action")
+
+	if action == "query" {
+		for _, k := range []string{"prop", "list", "meta", "generator"} {
+			for _, m := range strings.Split(v.Get(k), "|") {
+				if h := w.handlers["query+"+m]; m != "" && h != nil {
+					return h
+				}
+			}
+		}
+		if has(v.Get("meta"), "tokens") {
+			return nil
+		}
+	}
+
+	if h := w.handlers[action]; action != "" && h != nil {
+		return h
+	}
+
+	return w.handlers["*"]
+}
+
+// Reply writes v as the JSON response of a request.
+func Reply(rw http.ResponseWriter, v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rw.Write(b)
+}
+
+// has reports whether the list of values separated by | has the value.
+func has(list, value string) bool {
+	return slices.Contains(strings.Split(list, "|"), value)
+}
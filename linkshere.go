@@ -17,7 +17,7 @@ import (
 
 type LinkshereResponse struct {
 	CoreResponse
-	BatchComplete any                `json:"batchcomplete,omitempty"`
+	BatchComplete bool               `json:"batchcomplete,omitempty"`
 	Continue      *LinkshereContinue `json:"continue,omitempty"`
 	Query         *LinkshereQuery    `json:"query,omitempty"`
 }
@@ -28,14 +28,14 @@ type LinkshereContinue struct {
 }
 
 type LinkshereQuery struct {
-	Pages map[string]LinkshereFromPage `json:"pages"`
+	Pages []LinkshereFromPage `json:"pages"`
 }
 
 type LinkshereFromPage struct {
 	Pageid    int             `json:"pageid"`
 	Ns        Namespace       `json:"ns"`
 	Title     string          `json:"title"`
-	Missing   any             `json:"missing,omitempty"`
+	Missing   bool            `json:"missing,omitempty"`
 	Linkshere []LinksherePage `json:"linkshere,omitempty"`
 }
 
@@ -43,7 +43,7 @@ type LinksherePage struct {
 	Pageid   int       `json:"pageid"`
 	Ns       Namespace `json:"ns"`
 	Title    string    `json:"title"`
-	Redirect any       `json:"redirect"`
+	Redirect bool      `json:"redirect"`
 }
 
 type LinkshereOption func(map[string]string)
@@ -138,7 +138,22 @@ func (w *LinkshereClient) Titles(s ...string) *LinkshereClient {
 	return w
 }
 
+// Validate checks that the pages whose links to list are given.
+func (w *LinkshereClient) Validate() error {
+	parameters := Values{}
+	for _, o := range w.o {
+		o(parameters)
+	}
+
+	return checkParams(parameters,
+		required("titles"))
+}
+
 func (w *LinkshereClient) Do(ctx context.Context) (LinkshereResponse, error) {
+	if err := w.Validate(); err != nil {
+		return LinkshereResponse{}, err
+	}
+
 	if err := w.c.checkKeepAlive(ctx); err != nil {
 		return LinkshereResponse{}, err
 	}
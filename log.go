@@ -0,0 +1,202 @@
+package mediawiki
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// BodyLogLevel controls which request and response bodies are logged.
+type BodyLogLevel int
+
+const (
+	// BodyLogNone never logs bodies. This is the default.
+	BodyLogNone BodyLogLevel = iota
+
+	// BodyLogErrors logs bodies only for calls that failed, either
+	// because of a transport error or because the API returned an error.
+	BodyLogErrors
+
+	// BodyLogAll logs bodies for every call.
+	BodyLogAll
+)
+
+// redacted replaces the value of every sensitive field in log output.
+const redacted = "[REDACTED]"
+
+// callRecord describes a completed API call for logging purposes.
+type callRecord struct {
+	values   Values
+	request  *http.Request
+	response *http.Response
+	body     []byte
+	code     string
+	retries  int
+	duration time.Duration
+	err      error
+}
+
+// logger returns the logger to use for this client, or nil if logging
+// is disabled.
+func (w *Client) logger() (*slog.Logger, BodyLogLevel) {
+	if w.Logger != nil {
+		return w.Logger, w.LogBodies
+	}
+
+	if w.Debug != nil {
+		h := slog.NewTextHandler(w.Debug, &slog.HandlerOptions{Level: slog.LevelDebug})
+		return slog.New(h), BodyLogAll
+	}
+
+	return nil, BodyLogNone
+}
+
+// logCall writes one structured record describing rec.
+func (w *Client) logCall(ctx context.Context, rec callRecord) {
+	l, bodies := w.logger()
+	if l == nil {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("action", rec.values["action"]),
+		slog.String("module", moduleOf(rec.values)),
+		slog.String("title", titleOf(rec.values)),
+		slog.Duration("duration", rec.duration),
+		slog.Int("retries", rec.retries),
+	}
+
+	if rec.response != nil {
+		attrs = append(attrs,
+			slog.Int("status", rec.response.StatusCode),
+			slog.Int("size", len(rec.body)))
+	}
+
+	level, msg := slog.LevelDebug, "mediawiki api call"
+
+	switch {
+	case rec.err != nil:
+		level, msg = slog.LevelError, "mediawiki api call failed"
+		attrs = append(attrs, slog.String("error", rec.err.Error()))
+	case rec.code != "":
+		level, msg = slog.LevelWarn, "mediawiki api error"
+		attrs = append(attrs, slog.String("code", rec.code))
+	}
+
+	failed := rec.err != nil || rec.code != ""
+	if bodies == BodyLogAll || (bodies == BodyLogErrors && failed) {
+		attrs = append(attrs, slog.Any("request", redactValues(rec.values)))
+
+		if rec.request != nil {
+			attrs = append(attrs, slog.Any("request_headers", redactHeader(rec.request.Header)))
+		}
+
+		if rec.response != nil {
+			attrs = append(attrs,
+				slog.Any("response_headers", redactHeader(rec.response.Header)),
+				slog.String("response", string(redactJSON(rec.body))))
+		}
+	}
+
+	l.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// moduleOf returns the query submodules named in v, if any.
+func moduleOf(v Values) string {
+	var mods []string
+
+	for _, k := range []string{"prop", "list", "meta", "generator"} {
+		if s := v[k]; s != "" {
+			mods = append(mods, s)
+		}
+	}
+
+	return strings.Join(mods, "|")
+}
+
+// titleOf returns the page title or titles named in v, if any.
+func titleOf(v Values) string {
+	for _, k := range []string{"title", "titles", "filename", "from"} {
+		if s := v[k]; s != "" {
+			return s
+		}
+	}
+
+	return ""
+}
+
+// isSensitive reports whether the parameter or JSON field named k holds
+// a password or token.
+func isSensitive(k string) bool {
+	k = strings.ToLower(k)
+	return strings.Contains(k, "password") || strings.HasSuffix(k, "token") || k == "retype"
+}
+
+// redactValues returns a copy of v with all passwords and tokens
+// replaced.
+func redactValues(v Values) map[string]string {
+	m := make(map[string]string, len(v))
+
+	for k, s := range v {
+		if isSensitive(k) {
+			s = redacted
+		}
+		m[k] = s
+	}
+
+	return m
+}
+
+// redactHeader returns a copy of h with cookies and credentials removed.
+func redactHeader(h http.Header) map[string]string {
+	m := make(map[string]string, len(h))
+
+	for k := range h {
+		switch http.CanonicalHeaderKey(k) {
+		case "Cookie", "Set-Cookie", "Authorization", "Proxy-Authorization":
+			m[k] = redacted
+		default:
+			m[k] = h.Get(k)
+		}
+	}
+
+	return m
+}
+
+// redactJSON returns b with the values of all sensitive fields replaced.
+// If b is not valid JSON, it is returned unchanged.
+func redactJSON(b []byte) []byte {
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return b
+	}
+
+	out, err := json.Marshal(redactAny(v))
+	if err != nil {
+		return b
+	}
+
+	return out
+}
+
+func redactAny(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, e := range t {
+			if _, ok := e.(string); ok && isSensitive(k) {
+				t[k] = redacted
+			} else {
+				t[k] = redactAny(e)
+			}
+		}
+	case []any:
+		for i, e := range t {
+			t[i] = redactAny(e)
+		}
+	}
+
+	return v
+}
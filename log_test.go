@@ -0,0 +1,132 @@
+package mediawiki
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/clockworksoul/mediawiki/internal/wikitest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newLogTestClient returns a client logging to the returned buffer, of a
+// wiki that passes every request to h.
+func newLogTestClient(t *testing.T, h http.HandlerFunc) (*Client, *bytes.Buffer) {
+	t.Helper()
+
+	s := wikitest.New(t, map[string]http.HandlerFunc{"*": h})
+
+	c, err := New(s.URL, agent)
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	c.Logger = slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	return c, buf
+}
+
+func decodeLogRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var recs []map[string]any
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		m := map[string]any{}
+		require.NoError(t, dec.Decode(&m))
+		recs = append(recs, m)
+	}
+
+	return recs
+}
+
+func TestLogCallFields(t *testing.T) {
+	c, buf := newLogTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"batchcomplete":true,"query":{"pages":[]}}`))
+	})
+
+	r := Response{}
+	_, err := c.GetInto(context.Background(), Values{"action": "query", "prop": "revisions", "titles": "Main Page"}, &r)
+	require.NoError(t, err)
+
+	recs := decodeLogRecords(t, buf)
+	require.Len(t, recs, 1)
+
+	assert.Equal(t, "DEBUG", recs[0]["level"])
+	assert.Equal(t, "query", recs[0]["action"])
+	assert.Equal(t, "revisions", recs[0]["module"])
+	assert.Equal(t, "Main Page", recs[0]["title"])
+	assert.EqualValues(t, 200, recs[0]["status"])
+	assert.EqualValues(t, 0, recs[0]["retries"])
+	assert.Contains(t, recs[0], "duration")
+	assert.Contains(t, recs[0], "size")
+	assert.NotContains(t, recs[0], "request")
+}
+
+func TestLogRedactsSecrets(t *testing.T) {
+	c, buf := newLogTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "wiki_session", Value: "s3cr3t-cookie"})
+		w.Write([]byte(`{"query":{"tokens":{"csrftoken":"s3cr3t-token+\\"}}}`))
+	})
+	c.LogBodies = BodyLogAll
+
+	r := Response{}
+	_, err := c.PostInto(context.Background(), Values{
+		"action":     "login",
+		"lgname":     "Bot",
+		"lgpassword": "s3cr3t-password",
+		"lgtoken":    "s3cr3t-login-token",
+	}, &r)
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.NotContains(t, out, "s3cr3t")
+	assert.Contains(t, out, redacted)
+	assert.Contains(t, out, "Bot")
+}
+
+func TestLogAPIError(t *testing.T) {
+	c, buf := newLogTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"error":{"code":"badtoken","info":"Invalid CSRF token."}}`))
+	})
+	c.LogBodies = BodyLogErrors
+
+	r := Response{}
+	_, err := c.PostInto(context.Background(), Values{"action": "edit", "title": "Foo", "token": "abc"}, &r)
+	require.NoError(t, err)
+
+	recs := decodeLogRecords(t, buf)
+	require.Len(t, recs, 1)
+	assert.Equal(t, "WARN", recs[0]["level"])
+	assert.Equal(t, "badtoken", recs[0]["code"])
+	assert.Contains(t, recs[0], "response")
+}
+
+func TestMaxlagRetry(t *testing.T) {
+	calls := 0
+	c, buf := newLogTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		assert.Equal(t, "5", r.URL.Query().Get("maxlag"))
+
+		if calls < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.Write([]byte(`{"error":{"code":"maxlag","info":"Waiting for a database server"}}`))
+			return
+		}
+		w.Write([]byte(`{"batchcomplete":true}`))
+	})
+	c.Maxlag.On = true
+
+	r := Response{}
+	_, err := c.GetInto(context.Background(), Values{"action": "query"}, &r)
+	require.NoError(t, err)
+	assert.Nil(t, r.Error)
+	assert.Equal(t, 3, calls)
+
+	recs := decodeLogRecords(t, buf)
+	require.Len(t, recs, 1)
+	assert.EqualValues(t, 2, recs[0]["retries"])
+}
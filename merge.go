@@ -0,0 +1,295 @@
+package mediawiki
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ConflictHunk is a region of a three-way merge that was changed
+// differently on both sides.
+type ConflictHunk struct {
+	// Line is the 1-based line in the base text where the hunk starts.
+	Line int
+
+	Base   string
+	Ours   string
+	Theirs string
+}
+
+// EditConflictError is returned by EditClient.Do when conflict
+// resolution is enabled and our text couldn't be merged cleanly with
+// the edits made since the base revision. It holds all three versions,
+// a merged text with conflict markers, and the conflicting hunks.
+type EditConflictError struct {
+	Title        string
+	BaseRevID    int
+	CurrentRevID int
+
+	Base   string
+	Ours   string
+	Theirs string
+
+	// Merged is the merge result, with each conflicting hunk written
+	// between "<<<<<<< ours", "=======" and ">>>>>>> theirs" lines.
+	Merged string
+
+	Hunks []ConflictHunk
+}
+
+func (e *EditConflictError) Error() string {
+	return fmt.Sprintf("editconflict: %s: %d conflicting hunks between revisions %d and %d",
+		e.Title, len(e.Hunks), e.BaseRevID, e.CurrentRevID)
+}
+
+// Merge3 merges the changes made from base to ours and from base to
+// theirs, line by line. Lines changed in the same place on both sides
+// are merged word by word if possible; otherwise they are returned as
+// conflicts and written into the result between conflict markers.
+func Merge3(base, ours, theirs string) (string, []ConflictHunk) {
+	b := &strings.Builder{}
+	var hunks []ConflictHunk
+
+	merge3(splitLines(base), splitLines(ours), splitLines(theirs), func(line int, cb, co, ct []string) {
+		if c, ok := clean(cb, co, ct); ok {
+			writeAll(b, c)
+			return
+		}
+
+		// Try again at the word level.
+		sb, so, st := strings.Join(cb, ""), strings.Join(co, ""), strings.Join(ct, "")
+		if m, ok := mergeTokens(sb, so, st); ok {
+			b.WriteString(m)
+			return
+		}
+
+		hunks = append(hunks, ConflictHunk{Line: line + 1, Base: sb, Ours: so, Theirs: st})
+
+		b.WriteString("<<<<<<< ours\n")
+		b.WriteString(so)
+		if so != "" && !strings.HasSuffix(so, "\n") {
+			b.WriteByte('\n')
+		}
+		b.WriteString("=======\n")
+		b.WriteString(st)
+		if st != "" && !strings.HasSuffix(st, "\n") {
+			b.WriteByte('\n')
+		}
+		b.WriteString(">>>>>>> theirs\n")
+	})
+
+	return b.String(), hunks
+}
+
+// mergeTokens merges a conflicting hunk word by word, and reports
+// whether that was possible without conflicts.
+func mergeTokens(base, ours, theirs string) (string, bool) {
+	b := &strings.Builder{}
+	ok := true
+
+	merge3(splitTokens(base), splitTokens(ours), splitTokens(theirs), func(_ int, cb, co, ct []string) {
+		c, clean := clean(cb, co, ct)
+		ok = ok && clean
+		writeAll(b, c)
+	})
+
+	return b.String(), ok
+}
+
+// clean resolves a chunk of a three-way merge if only one side changed
+// it, or both changed it the same way.
+func clean(base, ours, theirs []string) ([]string, bool) {
+	switch {
+	case slices.Equal(ours, theirs), slices.Equal(base, theirs):
+		return ours, true
+	case slices.Equal(base, ours):
+		return theirs, true
+	}
+	return nil, false
+}
+
+// merge3 splits a three-way merge into chunks, in order. Stable chunks,
+// where all three agree, are passed as a single line in all three.
+// line is the index in base where the chunk starts.
+func merge3(base, ours, theirs []string, chunk func(line int, base, ours, theirs []string)) {
+	mo := matches(base, ours)
+	mt := matches(base, theirs)
+
+	i, j, k := 0, 0, 0
+	for {
+		if oj, ok := mo[i]; ok && oj == j {
+			if tk, ok := mt[i]; ok && tk == k {
+				chunk(i, base[i:i+1], ours[j:j+1], theirs[k:k+1])
+				i, j, k = i+1, j+1, k+1
+				continue
+			}
+		}
+
+		// Find the next line that is unchanged on both sides.
+		next, nj, nk := len(base), len(ours), len(theirs)
+		for n := i; n < len(base); n++ {
+			oj, ok1 := mo[n]
+			tk, ok2 := mt[n]
+			if ok1 && ok2 {
+				next, nj, nk = n, oj, tk
+				break
+			}
+		}
+
+		if next > i || nj > j || nk > k {
+			chunk(i, base[i:next], ours[j:nj], theirs[k:nk])
+		}
+		if next == len(base) {
+			return
+		}
+		i, j, k = next, nj, nk
+	}
+}
+
+// matches returns a map from indices in a to the indices in b of the
+// elements of a longest common subsequence of a and b.
+func matches(a, b []string) map[int]int {
+	m := map[int]int{}
+
+	// Common prefix and suffix.
+	p := 0
+	for p < len(a) && p < len(b) && a[p] == b[p] {
+		m[p] = p
+		p++
+	}
+	s := 0
+	for s < len(a)-p && s < len(b)-p && a[len(a)-1-s] == b[len(b)-1-s] {
+		m[len(a)-1-s] = len(b) - 1 - s
+		s++
+	}
+
+	for _, pr := range myers(a[p:len(a)-s], b[p:len(b)-s]) {
+		m[pr[0]+p] = pr[1] + p
+	}
+
+	return m
+}
+
+// myers returns the index pairs of a longest common subsequence of a
+// and b, using Myers' O(ND) difference algorithm.
+func myers(a, b []string) [][2]int {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return nil
+	}
+
+	total := n + m
+	off := total + 1
+	v := make([]int, 2*total+3)
+
+	// trace[d] holds v[-d-1..d+1] as it was before step d.
+	var trace [][]int
+
+	for d := 0; d <= total; d++ {
+		trace = append(trace, append([]int(nil), v[off-d-1:off+d+2]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && v[off+k-1] < v[off+k+1] {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[off+k] = x
+
+			if x >= n && y >= m {
+				return backtrack(trace, n, m, d)
+			}
+		}
+	}
+
+	return nil
+}
+
+func backtrack(trace [][]int, x, y, d int) [][2]int {
+	var pairs [][2]int
+
+	for ; d > 0; d-- {
+		v := trace[d]
+		at := func(k int) int { return v[k+d+1] }
+
+		k := x - y
+		var pk int
+		if k == -d || k != d && at(k-1) < at(k+1) {
+			pk = k + 1
+		} else {
+			pk = k - 1
+		}
+		px := at(pk)
+		py := px - pk
+
+		for x > px && y > py {
+			x, y = x-1, y-1
+			pairs = append(pairs, [2]int{x, y})
+		}
+		x, y = px, py
+	}
+
+	for x > 0 && y > 0 {
+		x, y = x-1, y-1
+		pairs = append(pairs, [2]int{x, y})
+	}
+
+	for i, j := 0, len(pairs)-1; i < j; i, j = i+1, j-1 {
+		pairs[i], pairs[j] = pairs[j], pairs[i]
+	}
+
+	return pairs
+}
+
+// splitLines splits s into lines, keeping the line endings.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// splitTokens splits s into words, runs of whitespace and single other
+// characters.
+func splitTokens(s string) []string {
+	var out []string
+
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+
+		var n int
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			n = strings.IndexFunc(s, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+		case unicode.IsSpace(r):
+			n = strings.IndexFunc(s, func(r rune) bool { return !unicode.IsSpace(r) })
+		default:
+			n = size
+		}
+		if n < 0 {
+			n = len(s)
+		}
+
+		out = append(out, s[:n])
+		s = s[n:]
+	}
+
+	return out
+}
+
+func writeAll(b *strings.Builder, s []string) {
+	for _, e := range s {
+		b.WriteString(e)
+	}
+}
@@ -0,0 +1,196 @@
+package mediawiki
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/clockworksoul/mediawiki/internal/wikitest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerge3(t *testing.T) {
+	cc := []struct {
+		Name               string
+		Base, Ours, Theirs string
+		Merged             string
+		Conflicts          int
+	}{
+		{
+			Name:   "disjoint lines",
+			Base:   "a\nb\nc\nd\ne\n",
+			Ours:   "A\nb\nc\nd\ne\n",
+			Theirs: "a\nb\nc\nd\nE\n",
+			Merged: "A\nb\nc\nd\nE\n",
+		},
+		{
+			Name:   "insertions",
+			Base:   "a\nb\n",
+			Ours:   "x\na\nb\n",
+			Theirs: "a\nb\ny\n",
+			Merged: "x\na\nb\ny\n",
+		},
+		{
+			Name:   "same change",
+			Base:   "a\nb\n",
+			Ours:   "a\nB\n",
+			Theirs: "a\nB\n",
+			Merged: "a\nB\n",
+		},
+		{
+			Name:   "deletion",
+			Base:   "a\nb\nc\nd\n",
+			Ours:   "a\nc\nd\n",
+			Theirs: "a\nb\nc\nD\n",
+			Merged: "a\nc\nD\n",
+		},
+		{
+			Name:   "same line, different words",
+			Base:   "The quick brown fox.\n",
+			Ours:   "The slow brown fox.\n",
+			Theirs: "The quick brown dog.\n",
+			Merged: "The slow brown dog.\n",
+		},
+		{
+			Name:      "conflict",
+			Base:      "a\nstatus=open\nc\n",
+			Ours:      "a\nstatus=done\nc\n",
+			Theirs:    "a\nstatus=closed\nc\n",
+			Merged:    "a\n<<<<<<< ours\nstatus=done\n=======\nstatus=closed\n>>>>>>> theirs\nc\n",
+			Conflicts: 1,
+		},
+		{
+			Name:   "no final newline",
+			Base:   "a\nb",
+			Ours:   "a\nb\nc",
+			Theirs: "z\na\nb",
+			Merged: "z\na\nb\nc",
+		},
+		{
+			Name:   "empty base",
+			Base:   "",
+			Ours:   "a\n",
+			Theirs: "",
+			Merged: "a\n",
+		},
+	}
+
+	for _, c := range cc {
+		t.Run(c.Name, func(t *testing.T) {
+			merged, hunks := Merge3(c.Base, c.Ours, c.Theirs)
+			assert.Equal(t, c.Merged, merged)
+			assert.Len(t, hunks, c.Conflicts)
+		})
+	}
+
+	_, hunks := Merge3("a\nb\nc\n", "a\nx\nc\n", "a\ny\nc\n")
+	require.Len(t, hunks, 1)
+	assert.Equal(t, ConflictHunk{Line: 2, Base: "b\n", Ours: "x\n", Theirs: "y\n"}, hunks[0])
+}
+
+func TestMyers(t *testing.T) {
+	a := splitTokens("a b c a b b a")
+	b := splitTokens("c b a b a c")
+
+	pairs := myers(a, b)
+	for _, p := range pairs {
+		assert.Equal(t, a[p[0]], b[p[1]])
+	}
+	for i := 1; i < len(pairs); i++ {
+		assert.Less(t, pairs[i-1][0], pairs[i][0])
+		assert.Less(t, pairs[i-1][1], pairs[i][1])
+	}
+}
+
+// newConflictServer returns a wiki where every edit based on a
+// revision other than the latest one fails with an edit conflict.
+func newConflictServer(t *testing.T, revs []string) (*wikitest.Wiki, *[]string) {
+	t.Helper()
+
+	s := wikitest.New(t, map[string]http.HandlerFunc{
+		"query+revisions": func(w http.ResponseWriter, r *http.Request) {
+			id := len(revs)
+			if s := r.Form.Get("revids"); s != "" {
+				id, _ = strconv.Atoi(s)
+			}
+			json.NewEncoder(w).Encode(map[string]any{
+				"curtimestamp": "2024-02-03T04:05:06Z",
+				"query": map[string]any{"pages": []any{map[string]any{
+					"ns": 0, "title": "Foo", "pageid": 7,
+					"revisions": []any{map[string]any{
+						"revid":     id,
+						"timestamp": "2024-01-02T03:04:05Z",
+						"slots":     map[string]any{"main": map[string]any{"content": revs[id-1]}},
+					}},
+				}}},
+			})
+		},
+
+		"edit": func(w http.ResponseWriter, r *http.Request) {
+			if r.Form.Get("baserevid") != strconv.Itoa(len(revs)) {
+				w.Write([]byte(`{"error":{"code":"editconflict","info":"Edit conflict."}}`))
+				return
+			}
+			revs = append(revs, r.Form.Get("text"))
+			w.Write([]byte(`{"edit":{"result":"Success","title":"Foo"}}`))
+		},
+	})
+
+	return s, &revs
+}
+
+func TestEditResolveConflicts(t *testing.T) {
+	s, revs := newConflictServer(t, []string{"a\nb\nc\n", "a\nb\nC\n"})
+
+	c, err := New(s.URL, agent)
+	require.NoError(t, err)
+
+	r, err := c.Edit().Title("Foo").Text("A\nb\nc\n").BaseRevId(1).ResolveConflicts(true).Do(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, Success, r.Edit.Result)
+	assert.Equal(t, "A\nb\nC\n", (*revs)[2])
+
+	// Without resolution the conflict is returned as is.
+	_, err = c.Edit().Title("Foo").Text("x").BaseRevId(1).Do(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "editconflict")
+}
+
+func TestEditResolveConflictsFails(t *testing.T) {
+	s, revs := newConflictServer(t, []string{"a\nb\nc\n", "a\nB\nc\n"})
+
+	c, err := New(s.URL, agent)
+	require.NoError(t, err)
+
+	_, err = c.Edit().Title("Foo").Text("a\nX\nc\n").BaseRevId(1).ResolveConflicts(true).Do(context.Background())
+	require.Error(t, err)
+
+	var ce *EditConflictError
+	require.True(t, errors.As(err, &ce))
+	assert.Equal(t, 1, ce.BaseRevID)
+	assert.Equal(t, 2, ce.CurrentRevID)
+	assert.Equal(t, "a\nb\nc\n", ce.Base)
+	assert.Equal(t, "a\nX\nc\n", ce.Ours)
+	assert.Equal(t, "a\nB\nc\n", ce.Theirs)
+	require.Len(t, ce.Hunks, 1)
+	assert.Equal(t, 2, ce.Hunks[0].Line)
+
+	assert.Len(t, *revs, 2)
+}
+
+func TestEditResolveConflictsNewSection(t *testing.T) {
+	s, revs := newConflictServer(t, []string{"a\n", "a\nb\n"})
+
+	c, err := New(s.URL, agent)
+	require.NoError(t, err)
+
+	// The text of a new section isn't merged with the page, only sent
+	// again based on the current revision.
+	_, err = c.Edit().Title("Foo").Section("new").SectionTitle("New").Text("c\n").BaseRevId(1).ResolveConflicts(true).Do(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "c\n", (*revs)[2])
+}
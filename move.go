@@ -25,7 +25,7 @@ type MoveResponseMove struct {
 	From            string `json:"from"`
 	To              string `json:"to"`
 	Reason          string `json:"reason"`
-	RedirectCreated any    `json:"redirectcreated,omitempty"`
+	RedirectCreated bool   `json:"redirectcreated,omitempty"`
 	Talkfrom        string `json:"talkfrom,omitempty"`
 	Talkto          string `json:"talkto,omitempty"`
 }
@@ -33,8 +33,9 @@ type MoveResponseMove struct {
 type MoveOption func(map[string]string)
 
 type MoveClient struct {
-	o []MoveOption
-	c *Client
+	o      []MoveOption
+	c      *Client
+	dryRun *bool
 }
 
 func (c *Client) Move() *MoveClient {
@@ -112,9 +113,9 @@ func (w *MoveClient) Noredirect(b bool) *MoveClient {
 // Unconditionally add or remove the page from the current user's watchlist, use preferences (ignored for bot users) or do not change watch.
 // One of the following values: nochange, preferences, unwatch, watch
 // Default: preferences
-func (w *MoveClient) Watchlist(s string) *MoveClient {
+func (w *MoveClient) Watchlist(s Watchlist) *MoveClient {
 	w.o = append(w.o, func(m map[string]string) {
-		m["watchlist"] = s
+		m["watchlist"] = string(s)
 	})
 	return w
 }
@@ -155,7 +156,33 @@ func (w *MoveClient) Token(s string) *MoveClient {
 	return w
 }
 
+// DryRun
+// Record the request in the client's Plan instead of sending it.
+// Overrides Client.DryRun for this call.
+func (w *MoveClient) DryRun(b bool) *MoveClient {
+	w.dryRun = &b
+	return w
+}
+
+// Validate checks the parameters of the request without sending it,
+// and returns all problems found. Do calls it before sending the request.
+func (w *MoveClient) Validate() error {
+	parameters := Values{}
+	for _, o := range w.o {
+		o(parameters)
+	}
+
+	return checkParams(parameters,
+		exactlyOne("from", "fromid"),
+		required("to"),
+		oneOf("watchlist", watchlistValues...))
+}
+
 func (w *MoveClient) Do(ctx context.Context) (MoveResponse, error) {
+	if err := w.Validate(); err != nil {
+		return MoveResponse{}, err
+	}
+
 	if err := w.c.checkKeepAlive(ctx); err != nil {
 		return MoveResponse{}, err
 	}
@@ -175,6 +202,14 @@ func (w *MoveClient) Do(ctx context.Context) (MoveResponse, error) {
 		o(parameters)
 	}
 
+	if w.c.isDryRun(w.dryRun) {
+		w.c.plan(ctx, parameters, nil)
+
+		r := MoveResponse{Move: &MoveResponseMove{From: parameters["from"], To: parameters["to"], Reason: parameters["reason"]}}
+		simulate(&r.CoreResponse, r)
+		return r, nil
+	}
+
 	// Make the request.
 	r := MoveResponse{}
 	j, err := w.c.PostInto(ctx, parameters, &r)
@@ -0,0 +1,19 @@
+module github.com/clockworksoul/mediawiki/mwotel
+
+go 1.22
+
+require (
+	github.com/clockworksoul/mediawiki v0.0.0
+	github.com/stretchr/testify v1.9.0
+	go.opentelemetry.io/otel v1.32.0
+	go.opentelemetry.io/otel/trace v1.32.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/text v0.4.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/clockworksoul/mediawiki => ../
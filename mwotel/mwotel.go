@@ -0,0 +1,64 @@
+// Package mwotel creates OpenTelemetry spans for the API calls made by
+// a mediawiki.Client.
+//
+//	client.Tracer = mwotel.New(otel.GetTracerProvider())
+package mwotel
+
+import (
+	"context"
+
+	"github.com/clockworksoul/mediawiki"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName identifies this package to the tracer provider.
+const InstrumentationName = "github.com/clockworksoul/mediawiki"
+
+// Tracer implements mediawiki.Tracer using an OpenTelemetry tracer.
+type Tracer struct {
+	t trace.Tracer
+}
+
+var _ mediawiki.Tracer = (*Tracer)(nil)
+
+// New returns a Tracer that creates spans using tp.
+func New(tp trace.TracerProvider) *Tracer {
+	return &Tracer{t: tp.Tracer(InstrumentationName)}
+}
+
+func (t *Tracer) Start(ctx context.Context, name string, attrs map[string]string) (context.Context, mediawiki.Span) {
+	ctx, s := t.t.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(toAttributes(attrs)...))
+
+	return ctx, span{s}
+}
+
+type span struct {
+	s trace.Span
+}
+
+func (s span) SetAttributes(attrs map[string]string) {
+	s.s.SetAttributes(toAttributes(attrs)...)
+}
+
+func (s span) End(err error) {
+	if err != nil {
+		s.s.RecordError(err)
+		s.s.SetStatus(codes.Error, err.Error())
+	}
+
+	s.s.End()
+}
+
+func toAttributes(m map[string]string) []attribute.KeyValue {
+	kv := make([]attribute.KeyValue, 0, len(m))
+
+	for k, v := range m {
+		kv = append(kv, attribute.String(k, v))
+	}
+
+	return kv
+}
@@ -0,0 +1,90 @@
+package mwotel
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// provider records the spans started by its tracers.
+type provider struct {
+	embedded.TracerProvider
+
+	name  string
+	spans []*recordedSpan
+}
+
+func (p *provider) Tracer(name string, _ ...trace.TracerOption) trace.Tracer {
+	p.name = name
+	return tracer{p: p}
+}
+
+type tracer struct {
+	embedded.Tracer
+
+	p *provider
+}
+
+func (t tracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	cfg := trace.NewSpanStartConfig(opts...)
+	s := &recordedSpan{name: name, kind: cfg.SpanKind(), attrs: cfg.Attributes()}
+	t.p.spans = append(t.p.spans, s)
+
+	return trace.ContextWithSpan(ctx, s), s
+}
+
+type recordedSpan struct {
+	noop.Span
+
+	name   string
+	kind   trace.SpanKind
+	attrs  []attribute.KeyValue
+	errs   []error
+	status codes.Code
+	ended  bool
+}
+
+func (s *recordedSpan) SetAttributes(kv ...attribute.KeyValue)        { s.attrs = append(s.attrs, kv...) }
+func (s *recordedSpan) RecordError(err error, _ ...trace.EventOption) { s.errs = append(s.errs, err) }
+func (s *recordedSpan) SetStatus(code codes.Code, _ string)           { s.status = code }
+func (s *recordedSpan) End(...trace.SpanEndOption)                    { s.ended = true }
+
+func TestTracer(t *testing.T) {
+	p := &provider{}
+	tr := New(p)
+	assert.Equal(t, InstrumentationName, p.name)
+
+	ctx, s := tr.Start(context.Background(), "edit", map[string]string{"mediawiki.action": "edit"})
+	s.SetAttributes(map[string]string{"http.status_code": "200"})
+	s.End(nil)
+
+	require.Len(t, p.spans, 1)
+	got := p.spans[0]
+	assert.Same(t, got, trace.SpanFromContext(ctx))
+	assert.Equal(t, "edit", got.name)
+	assert.Equal(t, trace.SpanKindClient, got.kind)
+	assert.ElementsMatch(t, []attribute.KeyValue{
+		attribute.String("mediawiki.action", "edit"),
+		attribute.String("http.status_code", "200"),
+	}, got.attrs)
+	assert.True(t, got.ended)
+	assert.Empty(t, got.errs)
+	assert.Equal(t, codes.Unset, got.status)
+
+	err := errors.New("badtoken: Invalid CSRF token.")
+	_, s = tr.Start(context.Background(), "edit", nil)
+	s.End(err)
+
+	got = p.spans[1]
+	assert.Equal(t, []error{err}, got.errs)
+	assert.Equal(t, codes.Error, got.status)
+	assert.True(t, got.ended)
+}
@@ -0,0 +1,97 @@
+// Package mwprom exposes the metrics of a mediawiki.Client to Prometheus.
+//
+//	m, err := mwprom.New(prometheus.DefaultRegisterer)
+//	if err != nil {
+//		return err
+//	}
+//	client.Metrics = m
+package mwprom
+
+import (
+	"time"
+
+	"github.com/clockworksoul/mediawiki"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Namespace is the Prometheus namespace of all metrics.
+const Namespace = "mediawiki"
+
+// Metrics implements mediawiki.Metrics using Prometheus collectors.
+type Metrics struct {
+	requests       *prometheus.CounterVec
+	latency        *prometheus.HistogramVec
+	retries        *prometheus.CounterVec
+	maxlagWaits    prometheus.Histogram
+	tokenRefreshes *prometheus.CounterVec
+	relogins       prometheus.Counter
+}
+
+var _ mediawiki.Metrics = (*Metrics)(nil)
+
+// New creates the collectors and registers them with reg.
+func New(reg prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "requests_total",
+			Help:      "API calls by action and outcome.",
+		}, []string{"action", "outcome"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "request_duration_seconds",
+			Help:      "API call latency, including retries, by action.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"action"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "retries_total",
+			Help:      "Retried API calls by action.",
+		}, []string{"action"}),
+		maxlagWaits: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "maxlag_wait_seconds",
+			Help:      "Time spent waiting because of replication lag.",
+			Buckets:   []float64{1, 2, 5, 10, 30, 60},
+		}),
+		tokenRefreshes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "token_refreshes_total",
+			Help:      "Tokens fetched from the server by token type.",
+		}, []string{"token"}),
+		relogins: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "relogins_total",
+			Help:      "Logins performed because the session expired.",
+		}),
+	}
+
+	for _, c := range []prometheus.Collector{m.requests, m.latency, m.retries, m.maxlagWaits, m.tokenRefreshes, m.relogins} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+func (m *Metrics) RequestDone(action, outcome string, d time.Duration) {
+	m.requests.WithLabelValues(action, outcome).Inc()
+	m.latency.WithLabelValues(action).Observe(d.Seconds())
+}
+
+func (m *Metrics) Retry(action string) {
+	m.retries.WithLabelValues(action).Inc()
+}
+
+func (m *Metrics) MaxlagWait(d time.Duration) {
+	m.maxlagWaits.Observe(d.Seconds())
+}
+
+func (m *Metrics) TokenRefresh(token mediawiki.Token) {
+	m.tokenRefreshes.WithLabelValues(string(token)).Inc()
+}
+
+func (m *Metrics) Relogin() {
+	m.relogins.Inc()
+}
@@ -0,0 +1,37 @@
+package mwprom
+
+import (
+	"testing"
+	"time"
+
+	"github.com/clockworksoul/mediawiki"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	m, err := New(reg)
+	require.NoError(t, err)
+
+	m.RequestDone("edit", mediawiki.OutcomeSuccess, time.Second)
+	m.RequestDone("edit", mediawiki.OutcomeAPIError, time.Second)
+	m.RequestDone("query", mediawiki.OutcomeSuccess, time.Second)
+	m.Retry("edit")
+	m.MaxlagWait(5 * time.Second)
+	m.TokenRefresh(mediawiki.CSRFToken)
+	m.Relogin()
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.requests.WithLabelValues("edit", mediawiki.OutcomeSuccess)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.requests.WithLabelValues("edit", mediawiki.OutcomeAPIError)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.retries.WithLabelValues("edit")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.tokenRefreshes.WithLabelValues("csrf")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.relogins))
+	assert.Equal(t, 2, testutil.CollectAndCount(m.latency))
+
+	_, err = New(reg)
+	assert.Error(t, err, "registering twice should fail")
+}
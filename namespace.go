@@ -1,5 +1,14 @@
 package mediawiki
 
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
 type Namespace int64
 
 const (
@@ -27,6 +36,9 @@ const (
 	NamespaceCategoryTalk  Namespace = 15
 )
 
+// namespaceNamesMutex guards namespaceNames, which NewNamespace changes.
+var namespaceNamesMutex sync.RWMutex
+
 var namespaceNames = map[Namespace]string{
 	NamespaceAll:           "*",
 	NamespaceMedia:         "Media",
@@ -54,14 +66,26 @@ var namespaceNames = map[Namespace]string{
 // internal lookup table, so that future calls to String() will return the
 // assigned name. Note that this could be used to (accidentally or otherwise)
 // redefine the standard namespace names.
+//
+// Deprecated: The lookup table is shared by all clients. Use
+// Client.Namespaces, which loads the namespaces of the client's wiki,
+// and Namespaces.Add for namespaces the wiki doesn't report.
 func NewNamespace(name string, code int) Namespace {
 	ns := Namespace(code)
+
+	namespaceNamesMutex.Lock()
 	namespaceNames[ns] = name
+	namespaceNamesMutex.Unlock()
+
 	return ns
 }
 
 func (n Namespace) String() string {
-	if name, ok := namespaceNames[n]; ok {
+	namespaceNamesMutex.RLock()
+	name, ok := namespaceNames[n]
+	namespaceNamesMutex.RUnlock()
+
+	if ok {
 		return name
 	} else if n%2 == 0 {
 		return "Custom"
@@ -69,3 +93,239 @@ func (n Namespace) String() string {
 		return "Custom_talk"
 	}
 }
+
+// standardNamespaceNames are the names of the standard namespaces,
+// before any calls to NewNamespace.
+var standardNamespaceNames = maps.Clone(namespaceNames)
+
+// defaultSubpages are the namespaces with subpages enabled by default.
+var defaultSubpages = map[Namespace]bool{
+	NamespaceTalk:          true,
+	NamespaceUser:          true,
+	NamespaceUserTalk:      true,
+	NamespaceProject:       true,
+	NamespaceProjectTalk:   true,
+	NamespaceFileTalk:      true,
+	NamespaceMediaWiki:     true,
+	NamespaceMediaWikiTalk: true,
+	NamespaceTemplate:      true,
+	NamespaceTemplateTalk:  true,
+	NamespaceHelp:          true,
+	NamespaceHelpTalk:      true,
+	NamespaceCategoryTalk:  true,
+}
+
+// NamespaceInfo describes a namespace of a wiki.
+type NamespaceInfo struct {
+	ID Namespace
+
+	// Name is the local name, such as "Benutzer" on a German wiki, and
+	// Canonical the English name. Both use spaces and are empty for the
+	// main namespace.
+	Name      string
+	Canonical string
+	Aliases   []string
+
+	// Case is "first-letter" if the first letter of titles in the
+	// namespace is always capitalized, or "case-sensitive".
+	Case string
+
+	Content  bool
+	Subpages bool
+}
+
+// Namespaces is a registry of the namespaces of a wiki. Use
+// Client.Namespaces to get the registry of the client's wiki, loaded
+// from siteinfo. It is safe for concurrent use.
+type Namespaces struct {
+	mu     sync.RWMutex
+	byID   map[Namespace]NamespaceInfo
+	byName map[string]Namespace
+}
+
+// NewNamespaces returns a registry of the namespaces in a siteinfo
+// response with siprop=namespaces|namespacealiases. If q has no
+// namespaces, the registry holds the standard namespaces with their
+// English names and default settings.
+func NewNamespaces(q SiteinfoResponseQuery) *Namespaces {
+	n := &Namespaces{byID: map[Namespace]NamespaceInfo{}, byName: map[string]Namespace{}}
+
+	if len(q.Namespaces) == 0 {
+		for ns, name := range standardNamespaceNames {
+			if ns == NamespaceAll {
+				continue
+			}
+			if ns == NamespaceMain {
+				name = ""
+			}
+			name = strings.ReplaceAll(name, "_", " ")
+			n.Add(NamespaceInfo{
+				ID:        ns,
+				Name:      name,
+				Canonical: name,
+				Case:      "first-letter",
+				Content:   ns == NamespaceMain,
+				Subpages:  defaultSubpages[ns],
+			})
+		}
+	}
+
+	for _, s := range q.Namespaces {
+		n.Add(NamespaceInfo{
+			ID:        Namespace(s.ID),
+			Name:      s.Name,
+			Canonical: s.Canonical,
+			Case:      s.Case,
+			Content:   s.Content,
+			Subpages:  s.Subpages,
+		})
+	}
+
+	for _, a := range q.NamespacesAliases {
+		n.mu.Lock()
+		if info, ok := n.byID[Namespace(a.ID)]; ok {
+			info.Aliases = append(info.Aliases, a.Alias)
+			n.byID[info.ID] = info
+		}
+		n.byName[namespaceKey(a.Alias)] = Namespace(a.ID)
+		n.mu.Unlock()
+	}
+
+	return n
+}
+
+// namespaceKey normalizes a namespace name for case-insensitive lookup.
+func namespaceKey(name string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(name), "_", " "))
+}
+
+// Add registers a namespace, replacing any namespace with the same ID.
+// Use it for namespaces the wiki doesn't report.
+func (n *Namespaces) Add(info NamespaceInfo) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	info.Name = strings.ReplaceAll(info.Name, "_", " ")
+	info.Canonical = strings.ReplaceAll(info.Canonical, "_", " ")
+
+	n.byID[info.ID] = info
+	for _, name := range append([]string{info.Name, info.Canonical}, info.Aliases...) {
+		if name != "" {
+			n.byName[namespaceKey(name)] = info.ID
+		}
+	}
+}
+
+// Get returns the namespace with the given ID.
+func (n *Namespaces) Get(id Namespace) (NamespaceInfo, bool) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	info, ok := n.byID[id]
+	return info, ok
+}
+
+// Lookup returns the namespace with the given local or canonical name
+// or alias. Case, and underscores versus spaces, are ignored.
+func (n *Namespaces) Lookup(name string) (NamespaceInfo, bool) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	id, ok := n.byName[namespaceKey(name)]
+	if !ok {
+		return NamespaceInfo{}, false
+	}
+	info, ok := n.byID[id]
+	return info, ok
+}
+
+// Name returns the local name of the namespace, or its canonical name
+// if it has no local name. It is empty for the main namespace and for
+// namespaces that aren't registered.
+func (n *Namespaces) Name(id Namespace) string {
+	info, _ := n.Get(id)
+	return or(info.Name, info.Canonical)
+}
+
+// All returns all registered namespaces, ordered by ID.
+func (n *Namespaces) All() []NamespaceInfo {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	out := make([]NamespaceInfo, 0, len(n.byID))
+	for _, info := range n.byID {
+		out = append(out, info)
+	}
+	slices.SortFunc(out, func(a, b NamespaceInfo) int { return cmp.Compare(a.ID, b.ID) })
+	return out
+}
+
+// ContentNamespaces returns the IDs of the content namespaces, ordered
+// by ID.
+func (n *Namespaces) ContentNamespaces() []Namespace {
+	var out []Namespace
+	for _, info := range n.All() {
+		if info.Content {
+			out = append(out, info.ID)
+		}
+	}
+	return out
+}
+
+// IsContent reports whether id is a content namespace.
+func (n *Namespaces) IsContent(id Namespace) bool {
+	info, _ := n.Get(id)
+	return info.Content
+}
+
+// HasSubpages reports whether subpages are enabled in the namespace.
+func (n *Namespaces) HasSubpages(id Namespace) bool {
+	info, _ := n.Get(id)
+	return info.Subpages
+}
+
+// Talk returns the talk namespace of id, which is id itself for talk
+// namespaces, and false if there is none: virtual namespaces such as
+// Special have no talk namespace.
+func (n *Namespaces) Talk(id Namespace) (Namespace, bool) {
+	if id < NamespaceMain {
+		return 0, false
+	}
+	_, ok := n.Get(id | 1)
+	return id | 1, ok
+}
+
+// Subject returns the subject namespace of id, which is id itself for
+// subject namespaces.
+func (n *Namespaces) Subject(id Namespace) Namespace {
+	if id < NamespaceMain {
+		return id
+	}
+	return id &^ 1
+}
+
+// IsTalk reports whether id is a talk namespace.
+func (n *Namespaces) IsTalk(id Namespace) bool {
+	return id > NamespaceMain && id%2 == 1
+}
+
+// Namespaces returns the namespace registry of the wiki, taken from
+// Site the first time it is called. Namespaces added to it are kept
+// when the site metadata expires from SiteCache, until InvalidateSite
+// is called.
+func (c *Client) Namespaces(ctx context.Context) (*Namespaces, error) {
+	c.namespacesMutex.Lock()
+	defer c.namespacesMutex.Unlock()
+
+	if c.namespaces != nil {
+		return c.namespaces, nil
+	}
+
+	s, err := c.Site(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.namespaces = s.Namespaces
+	return c.namespaces, nil
+}
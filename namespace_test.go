@@ -0,0 +1,133 @@
+package mediawiki
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespaces(t *testing.T) {
+	var q SiteinfoResponseQuery
+	require.NoError(t, json.Unmarshal([]byte(germanSiteinfo), &q))
+	n := NewNamespaces(q)
+
+	info, ok := n.Get(NamespaceUser)
+	require.True(t, ok)
+	assert.Equal(t, "Benutzer", info.Name)
+	assert.Equal(t, "User", info.Canonical)
+	assert.Equal(t, []string{"Benutzerin"}, info.Aliases)
+	assert.True(t, info.Subpages)
+	assert.False(t, info.Content)
+
+	for name, id := range map[string]Namespace{
+		"Benutzer":            NamespaceUser,
+		"user":                NamespaceUser,
+		"BENUTZERIN":          NamespaceUser,
+		"Benutzer_Diskussion": NamespaceUserTalk,
+		"user talk":           NamespaceUserTalk,
+		"wp":                  NamespaceProject,
+	} {
+		info, ok := n.Lookup(name)
+		require.True(t, ok, name)
+		assert.Equal(t, id, info.ID, name)
+	}
+	_, ok = n.Lookup("Kategorie")
+	assert.False(t, ok)
+
+	assert.Equal(t, "Wikipedia", n.Name(NamespaceProject))
+	assert.Equal(t, "", n.Name(NamespaceMain))
+	assert.Equal(t, []Namespace{NamespaceMain}, n.ContentNamespaces())
+	assert.True(t, n.IsContent(NamespaceMain))
+	assert.False(t, n.HasSubpages(NamespaceFile))
+
+	talk, ok := n.Talk(NamespaceUser)
+	assert.True(t, ok)
+	assert.Equal(t, NamespaceUserTalk, talk)
+	_, ok = n.Talk(NamespaceFile)
+	assert.False(t, ok, "File talk isn't registered")
+	_, ok = n.Talk(NamespaceSpecial)
+	assert.False(t, ok)
+	assert.Equal(t, NamespaceUser, n.Subject(NamespaceUserTalk))
+	assert.True(t, n.IsTalk(NamespaceTalk))
+	assert.False(t, n.IsTalk(NamespaceMain))
+
+	n.Add(NamespaceInfo{ID: 828, Name: "Modul", Canonical: "Module", Case: "first-letter", Subpages: true})
+	info, ok = n.Lookup("module")
+	require.True(t, ok)
+	assert.Equal(t, Namespace(828), info.ID)
+	assert.Equal(t, "Custom", Namespace(828).String(), "registries don't touch the global names")
+
+	all := n.All()
+	assert.Equal(t, NamespaceMedia, all[0].ID)
+	assert.Equal(t, Namespace(828), all[len(all)-1].ID)
+}
+
+func TestNamespacesDefaults(t *testing.T) {
+	n := NewNamespaces(SiteinfoResponseQuery{})
+
+	info, ok := n.Lookup("user_talk")
+	require.True(t, ok)
+	assert.Equal(t, NamespaceUserTalk, info.ID)
+	assert.Equal(t, "User talk", info.Name)
+	assert.True(t, info.Subpages)
+
+	_, ok = n.Get(NamespaceAll)
+	assert.False(t, ok)
+}
+
+func TestNewNamespaceConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ns := NewNamespace(fmt.Sprintf("Concurrent %d", i), 4000+i)
+			_ = Namespace(4000 + (i+1)%10).String()
+			assert.Equal(t, fmt.Sprintf("Concurrent %d", i), ns.String())
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestClientNamespaces(t *testing.T) {
+	newWiki := func(siteinfo string) (*Client, *int) {
+		calls := 0
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.ParseForm()
+			assert.Equal(t, "siteinfo", r.Form.Get("meta"))
+			assert.Contains(t, r.Form.Get("siprop"), "namespaces|namespacealiases")
+			calls++
+			w.Write([]byte(`{"query":` + siteinfo + `}`))
+		}))
+		t.Cleanup(s.Close)
+
+		c, err := New(s.URL, agent)
+		require.NoError(t, err)
+		return c, &calls
+	}
+
+	ctx := context.Background()
+	de, calls := newWiki(germanSiteinfo)
+	en, _ := newWiki(`{"namespaces":{"0":{"id":0,"*":""},"100":{"id":100,"canonical":"Portal","*":"Portal"}}}`)
+
+	n, err := de.Namespaces(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "Benutzer", n.Name(NamespaceUser))
+
+	_, err = de.Namespaces(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, *calls, "the registry is loaded once")
+
+	m, err := en.Namespaces(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "Portal", m.Name(100))
+	assert.Equal(t, "", m.Name(NamespaceUser))
+	assert.Equal(t, "Portal", n.Name(100))
+}
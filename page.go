@@ -0,0 +1,662 @@
+package mediawiki
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/clockworksoul/mediawiki/wikitext"
+)
+
+// Page is a page on the wiki. It is created with Client.Page, which
+// loads the page info: its namespace, latest revision, protection and
+// categories. The content of the latest revision is loaded lazily, the
+// first time it is needed, or explicitly with Load.
+//
+// Edits made through a Page are saved with the revision they are based
+// on, so that a concurrent edit by someone else causes an edit conflict
+// instead of being overwritten. After each successful change the page
+// is reloaded.
+type Page struct {
+	QueryResponseQueryPage
+
+	c *Client
+
+	// Exists is false if the page doesn't exist yet. Saving it creates
+	// it, unless it has been created in the meantime.
+	Exists bool
+
+	Redirect   bool
+	Protection []PageProtection
+
+	// Categories are the titles of the categories the page is in,
+	// including the namespace prefix.
+	Categories []string
+
+	// RevID and Timestamp identify the loaded revision, and Text is its
+	// content. They are only set once the content has been loaded.
+	RevID     int
+	Timestamp time.Time
+	Text      string
+
+	loaded bool
+
+	// started is the server time when the page was loaded.
+	started time.Time
+}
+
+// PageProtection is a protection of a page: the action it restricts,
+// the user group required to perform it and when it expires.
+type PageProtection struct {
+	Type   ProtectionType  `json:"type"`
+	Level  ProtectionLevel `json:"level"`
+	Expiry Expiry          `json:"expiry,omitempty"`
+}
+
+// PageRevision is a revision of a page, as returned by PageHistory.
+type PageRevision struct {
+	RevID     int
+	ParentID  int
+	Timestamp time.Time
+	User      string
+	Comment   string
+	Minor     bool
+	Size      int
+}
+
+// PageSection is a section of a page. Section 0 is the text before the
+// first heading. Start and End are byte offsets into Page.Text; the
+// range includes the heading line and any subsections, like the
+// sections MediaWiki edits with EditClient.Section.
+type PageSection struct {
+	Index  int
+	Level  int
+	Title  string
+	Anchor string
+	Start  int
+	End    int
+
+	// bodyStart is the offset after the heading line.
+	bodyStart int
+}
+
+type pageInfoResponse struct {
+	QueryResponse
+	Curtimestamp *time.Time        `json:"curtimestamp,omitempty"`
+	Continue     map[string]string `json:"continue,omitempty"`
+	Query        *struct {
+		Pages []pageInfo `json:"pages"`
+	} `json:"query,omitempty"`
+}
+
+type pageInfo struct {
+	QueryResponseQueryPage
+	Redirect      bool             `json:"redirect,omitempty"`
+	Invalid       bool             `json:"invalid,omitempty"`
+	Invalidreason string           `json:"invalidreason,omitempty"`
+	Protection    []PageProtection `json:"protection,omitempty"`
+	Categories    []struct {
+		Title string `json:"title"`
+	} `json:"categories,omitempty"`
+}
+
+// Page loads the info of the page with the given title. Its content is
+// loaded when first needed.
+func (c *Client) Page(ctx context.Context, title string) (*Page, error) {
+	p := &Page{c: c}
+	p.Title = title
+	if err := p.info(ctx); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Reload reloads the page info, and the content if it has been loaded,
+// discarding any local changes to Text.
+func (p *Page) Reload(ctx context.Context) error {
+	if err := p.info(ctx); err != nil {
+		return err
+	}
+	if p.loaded {
+		return p.content(ctx)
+	}
+	return nil
+}
+
+// Load loads the content of the latest revision of the page, if it
+// hasn't been loaded yet.
+func (p *Page) Load(ctx context.Context) error {
+	if p.loaded {
+		return nil
+	}
+	return p.content(ctx)
+}
+
+// Content returns the content of the page, loading it if needed. It is
+// empty if the page doesn't exist.
+func (p *Page) Content(ctx context.Context) (string, error) {
+	if err := p.Load(ctx); err != nil {
+		return "", err
+	}
+	return p.Text, nil
+}
+
+// info loads the page info, protection and categories.
+func (p *Page) info(ctx context.Context) error {
+	if err := p.c.checkKeepAlive(ctx); err != nil {
+		return err
+	}
+
+	parameters := Values{
+		"action":       "query",
+		"titles":       p.Title,
+		"prop":         "info|categories",
+		"inprop":       "protection",
+		"cllimit":      "max",
+		"curtimestamp": "true",
+	}
+
+	var info *pageInfo
+	var categories []string
+	var started *time.Time
+
+	for {
+		r := pageInfoResponse{}
+		if _, err := p.c.GetInto(ctx, parameters, &r); err != nil {
+			return fmt.Errorf("failed to get: %w", err)
+		}
+		if e := r.Error; e != nil {
+			return fmt.Errorf("%s: %s", e.Code, e.Info)
+		}
+		if r.Query == nil || len(r.Query.Pages) == 0 {
+			return fmt.Errorf("unexpected error in query")
+		}
+
+		if info == nil {
+			info, started = &r.Query.Pages[0], r.Curtimestamp
+		}
+		for _, c := range r.Query.Pages[0].Categories {
+			categories = append(categories, c.Title)
+		}
+
+		if r.Continue == nil {
+			break
+		}
+		for k, v := range r.Continue {
+			parameters[k] = v
+		}
+	}
+
+	if info.Invalid {
+		return fmt.Errorf("%s: %s", p.Title, info.Invalidreason)
+	}
+
+	p.QueryResponseQueryPage = info.QueryResponseQueryPage
+	p.Exists = !info.Missing
+	p.Redirect = info.Redirect
+	p.Protection = info.Protection
+	p.Categories = categories
+
+	p.started = time.Now().UTC()
+	if started != nil {
+		p.started = *started
+	}
+
+	return nil
+}
+
+// content loads the latest revision of the page.
+func (p *Page) content(ctx context.Context) error {
+	r, err := p.c.Revisions().
+		Titles(p.Title).
+		Prop("ids", "timestamp", "content").
+		Curtimestamp(true).
+		Do(ctx)
+	if err != nil {
+		return err
+	}
+	if r.Query == nil || len(r.Query.Pages) == 0 {
+		return fmt.Errorf("unexpected error in query")
+	}
+
+	rp := r.Query.Pages[0]
+
+	p.Title = rp.Title
+	p.PageId = rp.Pageid
+	p.Exists = !rp.Missing && len(rp.Revisions) > 0
+	p.RevID, p.Timestamp, p.Text = 0, time.Time{}, ""
+	p.loaded = true
+
+	p.started = time.Now().UTC()
+	if r.Curtimestamp != nil {
+		p.started = *r.Curtimestamp
+	}
+
+	if p.Exists {
+		rev := rp.Revisions[0]
+		p.RevID = rev.Revid
+		p.Lastrevid = rev.Revid
+		p.Text = rev.Slots["main"].Content
+		if rev.Timestamp != nil {
+			p.Timestamp = *rev.Timestamp
+		}
+	}
+
+	return nil
+}
+
+// Talk returns the talk page of the page. On a talk page it returns the
+// page itself, reloaded. The title is resolved with the namespaces of
+// the wiki.
+func (p *Page) Talk(ctx context.Context) (*Page, error) {
+	t, err := p.parseTitle(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	talk, ok := t.TalkPage()
+	if !ok {
+		return nil, fmt.Errorf("%s has no talk page", p.Title)
+	}
+
+	return p.c.Page(ctx, talk.PrefixedText())
+}
+
+// Subject returns the subject page of a talk page. On a subject page it
+// returns the page itself, reloaded. The title is resolved with the
+// namespaces of the wiki.
+func (p *Page) Subject(ctx context.Context) (*Page, error) {
+	t, err := p.parseTitle(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.c.Page(ctx, t.SubjectPage().PrefixedText())
+}
+
+// parseTitle parses the title of the page with the rules of the wiki.
+func (p *Page) parseTitle(ctx context.Context) (Title, error) {
+	s, err := p.c.Site(ctx)
+	if err != nil {
+		return Title{}, err
+	}
+
+	return s.ParseTitle(p.Title)
+}
+
+// Sections returns the sections of the page, parsed locally from its
+// content. Headings produced by templates are not included.
+func (p *Page) Sections(ctx context.Context) ([]PageSection, error) {
+	if err := p.Load(ctx); err != nil {
+		return nil, err
+	}
+	return p.sections(), nil
+}
+
+func (p *Page) sections() []PageSection {
+	doc := wikitext.Parse(p.Text)
+
+	var out []PageSection
+	anchors := map[string]int{}
+
+	for _, s := range doc.Sections() {
+		ps := PageSection{Index: s.Index, Level: s.Level, Start: s.Start, End: s.End, bodyStart: s.Start}
+
+		if h := s.Heading; h != nil {
+			ps.Title = h.Title.Plain()
+			ps.Anchor = anchor(ps.Title, anchors)
+
+			ps.bodyStart += len(h.String())
+			if ps.bodyStart < len(p.Text) && p.Text[ps.bodyStart] == '\n' {
+				ps.bodyStart++
+			}
+		}
+
+		out = append(out, ps)
+	}
+
+	return out
+}
+
+// Section returns the first section whose title or anchor is heading.
+func (p *Page) Section(ctx context.Context, heading string) (PageSection, error) {
+	ss, err := p.Sections(ctx)
+	if err != nil {
+		return PageSection{}, err
+	}
+
+	heading = strings.TrimSpace(heading)
+	for _, s := range ss {
+		if s.Index > 0 && (s.Title == heading || s.Anchor == strings.ReplaceAll(heading, " ", "_")) {
+			return s, nil
+		}
+	}
+	return PageSection{}, fmt.Errorf("%s: no section %q", p.Title, heading)
+}
+
+// SectionText fetches the text of the section with the given index in
+// the latest revision from the wiki. Unlike the local Sections, the
+// index counts headings produced by templates the way MediaWiki does.
+func (p *Page) SectionText(ctx context.Context, index int) (string, error) {
+	r, err := p.c.Revisions().
+		Revids(p.revID()).
+		Prop("content").
+		Section(strconv.Itoa(index)).
+		Do(ctx)
+	if err != nil {
+		return "", err
+	}
+	if r.Query == nil || len(r.Query.Pages) == 0 || len(r.Query.Pages[0].Revisions) == 0 {
+		return "", fmt.Errorf("unexpected error in query")
+	}
+
+	return r.Query.Pages[0].Revisions[0].Slots["main"].Content, nil
+}
+
+// Save replaces the content of the page with text, creating the page if
+// it doesn't exist.
+func (p *Page) Save(ctx context.Context, text, summary string) (EditResponse, error) {
+	return p.save(ctx, text, summary)
+}
+
+// Append adds text to the end of the page, creating the page if it
+// doesn't exist. Appending never conflicts with other edits.
+func (p *Page) Append(ctx context.Context, text, summary string) (EditResponse, error) {
+	r, err := p.c.Edit().
+		Title(p.Title).
+		AppendText(text).
+		Summary(summary).
+		Do(ctx)
+	if err != nil || r.Simulated {
+		return r, err
+	}
+
+	return r, p.Reload(ctx)
+}
+
+// ReplaceSection replaces the content of the section with the given
+// heading, including its subsections, with text. The heading itself is
+// kept.
+func (p *Page) ReplaceSection(ctx context.Context, heading, text, summary string) (EditResponse, error) {
+	s, err := p.Section(ctx, heading)
+	if err != nil {
+		return EditResponse{}, err
+	}
+
+	return p.save(ctx, splice(p.Text, s.bodyStart, s.End, text), summary)
+}
+
+// AppendToSection adds text to the end of the section with the given
+// heading, after any subsections.
+func (p *Page) AppendToSection(ctx context.Context, heading, text, summary string) (EditResponse, error) {
+	s, err := p.Section(ctx, heading)
+	if err != nil {
+		return EditResponse{}, err
+	}
+
+	return p.save(ctx, splice(p.Text, s.End, s.End, text), summary)
+}
+
+// PrependToSection adds text to the start of the section with the given
+// heading, just below the heading.
+func (p *Page) PrependToSection(ctx context.Context, heading, text, summary string) (EditResponse, error) {
+	s, err := p.Section(ctx, heading)
+	if err != nil {
+		return EditResponse{}, err
+	}
+
+	return p.save(ctx, splice(p.Text, s.bodyStart, s.bodyStart, text), summary)
+}
+
+// InsertSectionAfter creates a section with the given title and text
+// after the section with the given heading and its subsections, at the
+// same level.
+func (p *Page) InsertSectionAfter(ctx context.Context, heading, title, text, summary string) (EditResponse, error) {
+	s, err := p.Section(ctx, heading)
+	if err != nil {
+		return EditResponse{}, err
+	}
+
+	return p.save(ctx, splice(p.Text, s.End, s.End, headingLine(s.Level, title)+text), summary)
+}
+
+// NewSection adds a level 2 section with the given title and text to
+// the end of the page, using section=new.
+func (p *Page) NewSection(ctx context.Context, title, text, summary string) (EditResponse, error) {
+	e := p.c.Edit().Section("new").SectionTitle(title).Text(text)
+	return p.do(ctx, e, summary)
+}
+
+// Move moves the page to a new title. The Page follows it to the new
+// title.
+func (p *Page) Move(ctx context.Context, to, reason string, opts ...MoveOption) (MoveResponse, error) {
+	m := p.c.Move().From(p.Title).To(to).Reason(reason)
+	m.o = append(m.o, opts...)
+
+	r, err := m.Do(ctx)
+	if err != nil || r.Simulated {
+		return r, err
+	}
+	if r.Move != nil && r.Move.To != "" {
+		p.Title = r.Move.To
+	}
+
+	return r, p.Reload(ctx)
+}
+
+// Delete deletes the page.
+func (p *Page) Delete(ctx context.Context, reason string, opts ...DeleteOption) (DeleteResponse, error) {
+	d := p.c.Delete().Title(p.Title).Reason(reason)
+	d.o = append(d.o, opts...)
+
+	r, err := d.Do(ctx)
+	if err != nil || r.Simulated {
+		return r, err
+	}
+
+	return r, p.Reload(ctx)
+}
+
+// Protect replaces the protections of the page. Actions not listed have
+// their restrictions removed. An empty Expiry means infinite.
+func (p *Page) Protect(ctx context.Context, protections []PageProtection, reason string, opts ...ProtectOption) (ProtectResponse, error) {
+	var levels []Protection
+	var expiries []Expiry
+	for _, pr := range protections {
+		levels = append(levels, Protection{pr.Type, pr.Level})
+		expiries = append(expiries, or(pr.Expiry, ExpiryInfinite))
+	}
+
+	w := p.c.Protect().
+		Title(p.Title).
+		Protections(levels...).
+		Reason(reason)
+	if len(expiries) > 0 {
+		w.Expiry(expiries...)
+	}
+	w.o = append(w.o, opts...)
+
+	r, err := w.Do(ctx)
+	if err != nil || r.Simulated {
+		return r, err
+	}
+
+	return r, p.Reload(ctx)
+}
+
+// History returns an iterator over the revisions of the page, newest
+// first.
+func (p *Page) History() *PageHistory {
+	return &PageHistory{p: p}
+}
+
+// PageHistory iterates over the revisions of a page, fetching them in
+// batches as needed:
+//
+//	h := p.History()
+//	for h.Next(ctx) {
+//		r := h.Revision()
+//		...
+//	}
+//	if err := h.Err(); err != nil {
+//		...
+//	}
+type PageHistory struct {
+	p    *Page
+	cur  PageRevision
+	revs []PageRevision
+	cont string
+	done bool
+	err  error
+}
+
+// Next advances to the next revision, and reports whether there is one.
+func (h *PageHistory) Next(ctx context.Context) bool {
+	if h.err != nil {
+		return false
+	}
+
+	for len(h.revs) == 0 {
+		if h.done {
+			return false
+		}
+		if h.err = h.fetch(ctx); h.err != nil {
+			return false
+		}
+	}
+
+	h.cur, h.revs = h.revs[0], h.revs[1:]
+	return true
+}
+
+// Revision returns the current revision.
+func (h *PageHistory) Revision() PageRevision {
+	return h.cur
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (h *PageHistory) Err() error {
+	return h.err
+}
+
+func (h *PageHistory) fetch(ctx context.Context) error {
+	w := h.p.c.Revisions().
+		Titles(h.p.Title).
+		Prop("ids", "timestamp", "user", "comment", "flags", "size").
+		Limit(historyBatch)
+	if h.cont != "" {
+		w.Continue(h.cont)
+	}
+
+	r, err := w.Do(ctx)
+	if err != nil {
+		return err
+	}
+
+	h.done = r.Continue == nil || r.Continue.Rvcontinue == ""
+	if !h.done {
+		h.cont = r.Continue.Rvcontinue
+	}
+
+	if r.Query == nil || len(r.Query.Pages) == 0 {
+		return nil
+	}
+	for _, rev := range r.Query.Pages[0].Revisions {
+		pr := PageRevision{
+			RevID:    rev.Revid,
+			ParentID: rev.Parentid,
+			User:     rev.User,
+			Comment:  rev.Comment,
+			Minor:    rev.Minor,
+			Size:     rev.Size,
+		}
+		if rev.Timestamp != nil {
+			pr.Timestamp = *rev.Timestamp
+		}
+		h.revs = append(h.revs, pr)
+	}
+
+	return nil
+}
+
+// historyBatch is the number of revisions History fetches at a time.
+const historyBatch = 50
+
+// save replaces the text of the page.
+func (p *Page) save(ctx context.Context, text, summary string) (EditResponse, error) {
+	e := p.c.Edit().Text(text)
+	r, err := p.do(ctx, e, summary)
+	if err == nil && r.Simulated && p.loaded {
+		p.Text = text
+	}
+	return r, err
+}
+
+// do makes the edit with conflict detection and reloads the page.
+func (p *Page) do(ctx context.Context, e *EditClient, summary string) (EditResponse, error) {
+	e.Title(p.Title).
+		Summary(summary).
+		StartTimestamp(p.started.Format(time.RFC3339))
+
+	if p.Exists {
+		e.BaseRevId(p.revID()).NoCreate(true)
+		if p.loaded {
+			e.BaseTimestamp(p.Timestamp.Format(time.RFC3339))
+		}
+	} else {
+		e.CreateOnly(true)
+	}
+
+	r, err := e.Do(ctx)
+	if err != nil || r.Simulated {
+		return r, err
+	}
+
+	return r, p.Reload(ctx)
+}
+
+// revID returns the revision edits are based on: the loaded revision,
+// or the latest one if the content hasn't been loaded.
+func (p *Page) revID() int {
+	if p.loaded {
+		return p.RevID
+	}
+	return p.Lastrevid
+}
+
+// splice replaces text[start:end] with s, adding newlines where needed
+// to keep s on lines of its own.
+func splice(text string, start, end int, s string) string {
+	before, after := text[:start], text[end:]
+
+	if before != "" && !strings.HasSuffix(before, "\n") {
+		s = "\n" + s
+	}
+	if after != "" && !strings.HasSuffix(s, "\n") {
+		s += "\n"
+	}
+
+	return before + s + after
+}
+
+func headingLine(level int, title string) string {
+	eq := strings.Repeat("=", max(level, 1))
+	return eq + " " + title + " " + eq + "\n"
+}
+
+// anchor returns the anchor MediaWiki gives a heading with the given
+// plain text. seen counts the anchors used so far, since repeated
+// headings get numbered anchors.
+func anchor(title string, seen map[string]int) string {
+	a := strings.ReplaceAll(title, " ", "_")
+
+	key := strings.ToLower(a)
+	seen[key]++
+	if n := seen[key]; n > 1 {
+		a += "_" + strconv.Itoa(n)
+	}
+
+	return a
+}
@@ -0,0 +1,424 @@
+package mediawiki
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/clockworksoul/mediawiki/internal/wikitest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeWiki is a minimal wiki holding revisions of a single page.
+type fakeWiki struct {
+	*wikitest.Wiki
+
+	title      string
+	revs       []string
+	edits      []Values
+	categories []string
+	protection []PageProtection
+}
+
+func newFakeWiki(t *testing.T, title string, revs ...string) (*fakeWiki, *Client) {
+	t.Helper()
+
+	f := &fakeWiki{title: title, revs: revs}
+	f.Wiki = wikitest.New(t, map[string]http.HandlerFunc{
+		"query+info":      f.info,
+		"query+siteinfo":  f.siteinfo,
+		"query+revisions": f.revisions,
+		"edit":            f.edit,
+		"move":            f.move,
+		"delete":          f.delete,
+		"protect":         f.protect,
+	})
+
+	c, err := New(f.URL, agent)
+	require.NoError(t, err)
+
+	return f, c
+}
+
+// revisions answers a revision history query if it has a limit, and
+// else returns the latest revision or the one of revids.
+func (f *fakeWiki) revisions(w http.ResponseWriter, r *http.Request) {
+	v := r.Form
+	if v.Get("rvlimit") != "" {
+		f.history(w, v)
+		return
+	}
+
+	page := map[string]any{"ns": 0, "title": f.title}
+
+	id := len(f.revs)
+	if t := v.Get("titles"); t != "" && t != f.title {
+		id = 0
+	}
+	if s := v.Get("revids"); s != "" {
+		id, _ = strconv.Atoi(s)
+	}
+
+	if id == 0 {
+		page["missing"] = true
+	} else {
+		text := f.revs[id-1]
+		if s := v.Get("rvsection"); s != "" {
+			p := &Page{Text: text}
+			ss := p.sections()
+			n, _ := strconv.Atoi(s)
+			text = text[ss[n].Start:ss[n].End]
+		}
+
+		page["pageid"] = 7
+		page["revisions"] = []map[string]any{{
+			"revid":     id,
+			"timestamp": "2024-01-02T03:04:05Z",
+			"slots":     map[string]any{"main": map[string]any{"content": text}},
+		}}
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"curtimestamp": "2024-02-03T04:05:06Z",
+		"query":        map[string]any{"pages": []any{page}},
+	})
+}
+
+func (f *fakeWiki) edit(w http.ResponseWriter, r *http.Request) {
+	v := r.Form
+
+	e := Values{}
+	for k := range v {
+		e[k] = v.Get(k)
+	}
+	f.edits = append(f.edits, e)
+
+	text := v.Get("text")
+	if s := v.Get("appendtext"); s != "" && len(f.revs) > 0 {
+		text = f.revs[len(f.revs)-1] + s
+	} else if s != "" {
+		text = s
+	}
+	if v.Get("section") == "new" {
+		text = "== " + v.Get("sectiontitle") + " ==\n" + text
+		if n := len(f.revs); n > 0 {
+			text = f.revs[n-1] + "\n\n" + text
+		}
+	}
+	f.revs = append(f.revs, text)
+
+	w.Write([]byte(`{"edit":{"result":"Success","title":"` + f.title + `"}}`))
+}
+
+func (f *fakeWiki) move(w http.ResponseWriter, r *http.Request) {
+	v := r.Form
+	f.title = v.Get("to")
+	json.NewEncoder(w).Encode(map[string]any{"move": map[string]any{"from": v.Get("from"), "to": f.title}})
+}
+
+func (f *fakeWiki) delete(w http.ResponseWriter, r *http.Request) {
+	f.revs = nil
+	json.NewEncoder(w).Encode(map[string]any{"delete": map[string]any{"title": f.title}})
+}
+
+func (f *fakeWiki) protect(w http.ResponseWriter, r *http.Request) {
+	v := r.Form
+	f.protection = nil
+	expiries := strings.Split(v.Get("expiry"), "|")
+	for i, p := range strings.Split(v.Get("protections"), "|") {
+		typ, level, _ := strings.Cut(p, "=")
+		f.protection = append(f.protection, PageProtection{Type: ProtectionType(typ), Level: ProtectionLevel(level), Expiry: Expiry(expiries[i])})
+	}
+	json.NewEncoder(w).Encode(map[string]any{"protect": map[string]any{"title": f.title}})
+}
+
+// siteinfo answers a siteinfo query with the namespaces of a German wiki.
+func (f *fakeWiki) siteinfo(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(`{"query":` + germanSiteinfo + `}`))
+}
+
+// info answers a prop=info|categories query, returning one category
+// per batch.
+func (f *fakeWiki) info(w http.ResponseWriter, r *http.Request) {
+	v := r.Form
+	title := v.Get("titles")
+	page := map[string]any{"ns": 0, "title": title}
+	if strings.HasPrefix(title, "Diskussion:") {
+		page["ns"] = 1
+	}
+
+	resp := map[string]any{"curtimestamp": "2024-02-03T04:05:06Z"}
+
+	if title != f.title || len(f.revs) == 0 {
+		page["missing"] = true
+	} else {
+		page["pageid"] = 7
+		page["lastrevid"] = len(f.revs)
+		page["length"] = len(f.revs[len(f.revs)-1])
+		page["protection"] = f.protection
+
+		i, _ := strconv.Atoi(v.Get("clcontinue"))
+		if i < len(f.categories) {
+			page["categories"] = []any{map[string]any{"ns": 14, "title": f.categories[i]}}
+		}
+		if i+1 < len(f.categories) {
+			resp["continue"] = map[string]any{"clcontinue": strconv.Itoa(i + 1), "continue": "||"}
+		}
+	}
+
+	resp["query"] = map[string]any{"pages": []any{page}}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// history answers a revision history query, newest first, two
+// revisions per batch.
+func (f *fakeWiki) history(w http.ResponseWriter, v url.Values) {
+	id := len(f.revs)
+	if s := v.Get("rvcontinue"); s != "" {
+		id, _ = strconv.Atoi(s)
+	}
+
+	var revs []any
+	for ; id > 0 && len(revs) < 2; id-- {
+		revs = append(revs, map[string]any{
+			"revid":     id,
+			"parentid":  id - 1,
+			"user":      "User" + strconv.Itoa(id),
+			"comment":   "rev " + strconv.Itoa(id),
+			"minor":     id%2 == 0,
+			"size":      len(f.revs[id-1]),
+			"timestamp": "2024-01-02T03:04:05Z",
+		})
+	}
+
+	resp := map[string]any{
+		"query": map[string]any{"pages": []any{map[string]any{"ns": 0, "title": f.title, "pageid": 7, "revisions": revs}}},
+	}
+	if id > 0 {
+		resp["continue"] = map[string]any{"rvcontinue": strconv.Itoa(id), "continue": "||"}
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+const sectioned = `Lead.
+
+== History ==
+Old.
+
+=== Early [[years|days]] ===
+Very old.
+
+== History ==
+Again.
+`
+
+func TestPageSections(t *testing.T) {
+	_, c := newFakeWiki(t, "Foo", sectioned)
+
+	ctx := context.Background()
+
+	p, err := c.Page(ctx, "Foo")
+	require.NoError(t, err)
+	assert.True(t, p.Exists)
+	assert.Equal(t, 1, p.Lastrevid)
+
+	ss, err := p.Sections(ctx)
+	require.NoError(t, err)
+	require.Len(t, ss, 4)
+	assert.Equal(t, 1, p.RevID, "content is loaded when needed")
+
+	assert.Equal(t, 0, ss[0].Index)
+	assert.Equal(t, "Lead.\n\n", p.Text[ss[0].Start:ss[0].End])
+
+	assert.Equal(t, "History", ss[1].Title)
+	assert.Equal(t, "History", ss[1].Anchor)
+	assert.Equal(t, 2, ss[1].Level)
+	assert.Equal(t, "== History ==\nOld.\n\n=== Early [[years|days]] ===\nVery old.\n\n", p.Text[ss[1].Start:ss[1].End])
+
+	assert.Equal(t, "Early days", ss[2].Title)
+	assert.Equal(t, "Early_days", ss[2].Anchor)
+	assert.Equal(t, 3, ss[2].Level)
+
+	assert.Equal(t, "History_2", ss[3].Anchor)
+
+	s, err := p.Section(ctx, "Early_days")
+	require.NoError(t, err)
+	assert.Equal(t, 2, s.Index)
+
+	_, err = p.Section(ctx, "Missing")
+	assert.Error(t, err)
+
+	text, err := p.SectionText(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "=== Early [[years|days]] ===\nVery old.\n\n", text)
+}
+
+func TestPageEditSections(t *testing.T) {
+	f, c := newFakeWiki(t, "Foo", sectioned)
+	ctx := context.Background()
+
+	p, err := c.Page(ctx, "Foo")
+	require.NoError(t, err)
+
+	_, err = p.ReplaceSection(ctx, "Early days", "New.", "replace")
+	require.NoError(t, err)
+	assert.Equal(t, 2, p.RevID, "page is reloaded after saving")
+	assert.Contains(t, p.Text, "=== Early [[years|days]] ===\nNew.\n== History ==\nAgain.")
+
+	e := f.edits[0]
+	assert.Equal(t, "1", e["baserevid"])
+	assert.Equal(t, "2024-01-02T03:04:05Z", e["basetimestamp"])
+	assert.Equal(t, "2024-02-03T04:05:06Z", e["starttimestamp"])
+	assert.Equal(t, "replace", e["summary"])
+
+	_, err = p.PrependToSection(ctx, "History", "First.", "prepend")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(p.Text, "Lead.\n\n== History ==\nFirst.\nOld.\n"))
+
+	_, err = p.AppendToSection(ctx, "History_2", "Last.", "append")
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(p.Text, "== History ==\nAgain.\nLast."))
+
+	_, err = p.InsertSectionAfter(ctx, "History", "Between", "Middle.\n", "insert")
+	require.NoError(t, err)
+	assert.Contains(t, p.Text, "New.\n== Between ==\nMiddle.\n== History ==\nAgain.")
+
+	_, err = p.NewSection(ctx, "Talk", "Hello.", "new")
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(p.Text, "== Talk ==\nHello."))
+	assert.Equal(t, "new", f.edits[4]["section"])
+	assert.Equal(t, 6, p.RevID)
+}
+
+func TestPageCreate(t *testing.T) {
+	f, c := newFakeWiki(t, "Foo")
+	ctx := context.Background()
+
+	p, err := c.Page(ctx, "Foo")
+	require.NoError(t, err)
+	assert.False(t, p.Exists)
+
+	_, err = p.AppendToSection(ctx, "x", "y", "z")
+	assert.Error(t, err)
+
+	_, err = p.NewSection(ctx, "Intro", "Hello.", "create")
+	require.NoError(t, err)
+	assert.True(t, p.Exists)
+	assert.Equal(t, "== Intro ==\nHello.", p.Text)
+
+	e := f.edits[0]
+	assert.Equal(t, "true", e["createonly"])
+	assert.Empty(t, e["baserevid"])
+	assert.Empty(t, e["nocreate"])
+}
+
+func TestPageInfo(t *testing.T) {
+	f, c := newFakeWiki(t, "Foo", "a")
+	f.categories = []string{"Category:A", "Category:B", "Category:C"}
+	f.protection = []PageProtection{{Type: "edit", Level: "sysop", Expiry: "infinity"}}
+	ctx := context.Background()
+
+	p, err := c.Page(ctx, "Foo")
+	require.NoError(t, err)
+	assert.True(t, p.Exists)
+	assert.Equal(t, 7, p.PageId)
+	assert.Equal(t, 1, p.Lastrevid)
+	assert.Equal(t, f.categories, p.Categories)
+	assert.Equal(t, f.protection, p.Protection)
+	assert.Empty(t, p.Text, "content is loaded lazily")
+
+	text, err := p.Content(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "a", text)
+
+	talk, err := p.Talk(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "Diskussion:Foo", talk.Title)
+	assert.Equal(t, NamespaceTalk, talk.Namespace)
+	assert.False(t, talk.Exists)
+
+	subject, err := talk.Subject(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "Foo", subject.Title)
+	assert.True(t, subject.Exists)
+}
+
+func TestPageSave(t *testing.T) {
+	f, c := newFakeWiki(t, "Foo", "a", "b")
+	ctx := context.Background()
+
+	p, err := c.Page(ctx, "Foo")
+	require.NoError(t, err)
+
+	_, err = p.Save(ctx, "c", "save")
+	require.NoError(t, err)
+	assert.Equal(t, "2", f.edits[0]["baserevid"], "unloaded pages are based on the latest revision")
+	assert.Empty(t, f.edits[0]["basetimestamp"])
+	assert.Equal(t, "2024-02-03T04:05:06Z", f.edits[0]["starttimestamp"])
+	assert.Equal(t, 3, p.Lastrevid)
+
+	_, err = p.Append(ctx, "d", "append")
+	require.NoError(t, err)
+	assert.Equal(t, "cd", f.revs[3])
+	assert.Empty(t, f.edits[1]["baserevid"])
+
+	require.NoError(t, p.Load(ctx))
+	assert.Equal(t, "cd", p.Text)
+	assert.Equal(t, 4, p.RevID)
+}
+
+func TestPageMoveDeleteProtect(t *testing.T) {
+	f, c := newFakeWiki(t, "Foo", "a")
+	ctx := context.Background()
+
+	p, err := c.Page(ctx, "Foo")
+	require.NoError(t, err)
+
+	_, err = p.Protect(ctx, []PageProtection{{Type: "edit", Level: "sysop"}, {Type: "move", Level: "sysop", Expiry: "1 week"}}, "vandalism")
+	require.NoError(t, err)
+	assert.Equal(t, []PageProtection{{Type: "edit", Level: "sysop", Expiry: "infinite"}, {Type: "move", Level: "sysop", Expiry: "1 week"}}, p.Protection)
+
+	_, err = p.Move(ctx, "Bar", "rename")
+	require.NoError(t, err)
+	assert.Equal(t, "Bar", p.Title)
+	assert.True(t, p.Exists)
+
+	_, err = p.Delete(ctx, "cleanup")
+	require.NoError(t, err)
+	assert.False(t, p.Exists)
+
+	var actions []string
+	for _, r := range f.Requests() {
+		if a := r.Get("action"); a != "query" {
+			actions = append(actions, a+":"+r.Get("title")+r.Get("from"))
+		}
+	}
+	assert.Equal(t, []string{"protect:Foo", "move:Foo", "delete:Bar"}, actions)
+}
+
+func TestPageHistory(t *testing.T) {
+	_, c := newFakeWiki(t, "Foo", "a", "bb", "ccc", "dddd", "eeeee")
+	ctx := context.Background()
+
+	p, err := c.Page(ctx, "Foo")
+	require.NoError(t, err)
+
+	var ids []int
+	h := p.History()
+	for h.Next(ctx) {
+		r := h.Revision()
+		ids = append(ids, r.RevID)
+		assert.Equal(t, r.RevID, r.Size)
+		assert.Equal(t, r.RevID-1, r.ParentID)
+		assert.Equal(t, "rev "+strconv.Itoa(r.RevID), r.Comment)
+		assert.Equal(t, r.RevID%2 == 0, r.Minor)
+	}
+	require.NoError(t, h.Err())
+	assert.Equal(t, []int{5, 4, 3, 2, 1}, ids)
+	assert.False(t, h.Next(ctx))
+}
@@ -0,0 +1,231 @@
+package mediawiki
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Obtain information about API modules.
+// https://www.mediawiki.org/wiki/Special:MyLanguage/API:Parameter_information
+//
+// Flags:
+// * This module requires read rights.
+
+// Paraminfo
+
+type ParaminfoResponse struct {
+	CoreResponse
+	Paraminfo *ParaminfoResponseParaminfo `json:"paraminfo,omitempty"`
+}
+
+type ParaminfoResponseParaminfo struct {
+	Modules []ParaminfoModule `json:"modules"`
+}
+
+type ParaminfoModule struct {
+	Name         string `json:"name"`
+	Classname    string `json:"classname,omitempty"`
+	Path         string `json:"path"`
+	Group        string `json:"group,omitempty"`
+	Prefix       string `json:"prefix"`
+	Source       string `json:"source,omitempty"`
+	Generator    bool   `json:"generator,omitempty"`
+	Mustbeposted bool   `json:"mustbeposted,omitempty"`
+	Readrights   bool   `json:"readrights,omitempty"`
+	Writerights  bool   `json:"writerights,omitempty"`
+	Deprecated   bool   `json:"deprecated,omitempty"`
+	Internal     bool   `json:"internal,omitempty"`
+
+	// Description and Helpurls are only returned with a Helpformat
+	// other than none.
+	Description ParaminfoHelp `json:"description,omitempty"`
+	Helpurls    []string      `json:"helpurls,omitempty"`
+
+	Parameters          []ParaminfoParameter `json:"parameters"`
+	Templatedparameters []ParaminfoParameter `json:"templatedparameters,omitempty"`
+
+	// Dynamicparameters is set if the module accepts parameters that
+	// aren't listed, such as the fields of action=clientlogin.
+	Dynamicparameters any `json:"dynamicparameters,omitempty"`
+}
+
+type ParaminfoParameter struct {
+	Index int    `json:"index,omitempty"`
+	Name  string `json:"name"`
+
+	// Type is the name of the type, such as "string" or "timestamp",
+	// or the list of allowed values. See Values.
+	Type any `json:"type"`
+
+	Default    any  `json:"default,omitempty"`
+	Multi      bool `json:"multi,omitempty"`
+	Required   bool `json:"required,omitempty"`
+	Deprecated bool `json:"deprecated,omitempty"`
+	Sensitive  bool `json:"sensitive,omitempty"`
+
+	Lowlimit  int `json:"lowlimit,omitempty"`
+	Highlimit int `json:"highlimit,omitempty"`
+	Limit     int `json:"limit,omitempty"`
+	Min       any `json:"min,omitempty"`
+	Max       any `json:"max,omitempty"`
+
+	// Templatevars maps the variables in the name of a templated
+	// parameter, such as {slot}, to the parameters holding their values.
+	Templatevars map[string]string `json:"templatevars,omitempty"`
+
+	// Submodules maps the values of a parameter selecting submodules,
+	// such as list, to the paths of the submodules.
+	Submodules           map[string]string `json:"submodules,omitempty"`
+	Submoduleparamprefix string            `json:"submoduleparamprefix,omitempty"`
+
+	// Tokentype is the type of token a token parameter takes.
+	Tokentype string `json:"tokentype,omitempty"`
+
+	Description ParaminfoHelp `json:"description,omitempty"`
+}
+
+// ParaminfoHelp is a help text of a module or parameter. It holds the
+// text with the html and wikitext help formats, and the message keys,
+// one per line, with the raw help format.
+type ParaminfoHelp string
+
+func (h *ParaminfoHelp) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*h = ParaminfoHelp(s)
+		return nil
+	}
+
+	var msgs []struct {
+		Key string `json:"key"`
+	}
+	if err := json.Unmarshal(b, &msgs); err != nil {
+		return err
+	}
+
+	keys := make([]string, len(msgs))
+	for i, m := range msgs {
+		keys[i] = m.Key
+	}
+	*h = ParaminfoHelp(strings.Join(keys, "\n"))
+	return nil
+}
+
+// Values returns the allowed values of an enumerated parameter, or nil
+// if the parameter isn't enumerated.
+func (p ParaminfoParameter) Values() []string {
+	vs, ok := p.Type.([]any)
+	if !ok {
+		return nil
+	}
+
+	out := make([]string, 0, len(vs))
+	for _, v := range vs {
+		out = append(out, fmt.Sprint(v))
+	}
+	return out
+}
+
+// Parameter returns the parameter of the module with the given name,
+// without the module prefix. Templated parameters such as
+// "{slot}-content" match any value of their variables.
+func (m *ParaminfoModule) Parameter(name string) (ParaminfoParameter, bool) {
+	for _, p := range m.Parameters {
+		if p.Name == name {
+			return p, true
+		}
+	}
+
+	for _, p := range m.Templatedparameters {
+		re := regexp.QuoteMeta(p.Name)
+		for v := range p.Templatevars {
+			re = strings.ReplaceAll(re, regexp.QuoteMeta("{"+v+"}"), ".+")
+		}
+		if ok, _ := regexp.MatchString("^"+re+"$", name); ok {
+			return p, true
+		}
+	}
+
+	return ParaminfoParameter{}, false
+}
+
+type ParaminfoClient struct {
+	o []QueryOption
+	c *Client
+}
+
+func (c *Client) Paraminfo() *ParaminfoClient {
+	return &ParaminfoClient{c: c}
+}
+
+// Modules
+// List of module names (values of the action and format parameters, or main). Can specify submodules with a +, or all submodules with +*, or all submodules recursively with +**.
+// Separate values with | or alternative.
+// Maximum number of values is 50 (500 for clients allowed higher limits).
+func (w *ParaminfoClient) Modules(s ...string) *ParaminfoClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["modules"] = strings.Join(s, "|")
+	})
+	return w
+}
+
+// Helpformat
+// Format of help strings.
+// One of the following values: html, none, raw, wikitext
+// Default: none
+func (w *ParaminfoClient) Helpformat(s string) *ParaminfoClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["helpformat"] = s
+	})
+	return w
+}
+
+// Validate checks that modules are given and that the help format is
+// known.
+func (w *ParaminfoClient) Validate() error {
+	parameters := Values{}
+	for _, o := range w.o {
+		o(parameters)
+	}
+
+	return checkParams(parameters,
+		required("modules"),
+		oneOf("helpformat", "html", "none", "raw", "wikitext"))
+}
+
+func (w *ParaminfoClient) Do(ctx context.Context) (ParaminfoResponse, error) {
+	if err := w.Validate(); err != nil {
+		return ParaminfoResponse{}, err
+	}
+
+	if err := w.c.checkKeepAlive(ctx); err != nil {
+		return ParaminfoResponse{}, err
+	}
+
+	// Specify parameters to send.
+	parameters := Values{
+		"action":     "paraminfo",
+		"helpformat": "none",
+	}
+
+	for _, o := range w.o {
+		o(parameters)
+	}
+
+	// Make the request.
+	r := ParaminfoResponse{}
+	j, err := w.c.GetInto(ctx, parameters, &r)
+	r.RawJSON = j
+	if err != nil {
+		return r, fmt.Errorf("failed to get: %w", err)
+	}
+
+	if e := r.Error; e != nil {
+		return r, fmt.Errorf("%s: %s", e.Code, e.Info)
+	}
+
+	return r, nil
+}
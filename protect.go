@@ -18,21 +18,27 @@ import (
 
 type ProtectResponse struct {
 	CoreResponse
-	Protect *struct {
-		Title       string `json:"title,omitempty"`
-		Reason      string `json:"reason,omitempty"`
-		Protections []struct {
-			Edit   string `json:"edit,omitempty"`
-			Expiry string `json:"expiry,omitempty"`
-		} `json:"protections,omitempty"`
-	} `json:"protect,omitempty"`
+	Protect *ProtectResponseProtect `json:"protect,omitempty"`
+}
+
+type ProtectResponseProtect struct {
+	Title       string                             `json:"title,omitempty"`
+	Reason      string                             `json:"reason,omitempty"`
+	Protections []ProtectResponseProtectProtection `json:"protections,omitempty"`
+	Cascade     bool                               `json:"cascade,omitempty"`
+}
+
+type ProtectResponseProtectProtection struct {
+	Edit   string `json:"edit,omitempty"`
+	Expiry string `json:"expiry,omitempty"`
 }
 
 type ProtectOption func(map[string]string)
 
 type ProtectClient struct {
-	o []ProtectOption
-	c *Client
+	o      []ProtectOption
+	c      *Client
+	dryRun *bool
 }
 
 func (c *Client) Protect() *ProtectClient {
@@ -58,25 +64,31 @@ func (w *ProtectClient) Pageid(i int) *ProtectClient {
 }
 
 // Protections
-// List of protection levels, formatted action=level (e.g. edit=sysop). A level of all means everyone is allowed to take the action, i.e. no restriction.
+// List of protection levels. A level of ProtectionAll means everyone is allowed to take the action, i.e. no restriction.
 // Note: Any actions not listed will have restrictions removed.
-// Separate values with | or alternative.
 // Maximum number of values is 50 (500 for clients allowed higher limits).
-func (w *ProtectClient) Protections(s string) *ProtectClient {
+func (w *ProtectClient) Protections(p ...Protection) *ProtectClient {
 	w.o = append(w.o, func(m map[string]string) {
-		m["protections"] = s
+		s := make([]string, len(p))
+		for i := range p {
+			s[i] = p[i].String()
+		}
+		m["protections"] = strings.Join(s, "|")
 	})
 	return w
 }
 
 // Expiry
-// Expiry timestamps. If only one timestamp is set, it'll be used for all protections. Use infinite, indefinite, infinity, or never, for a never-expiring protection.
-// Separate values with | or alternative.
+// Expiry of each protection. If only one expiry is set, it'll be used for all protections.
 // Maximum number of values is 50 (500 for clients allowed higher limits).
-// Default: infinite
-func (w *ProtectClient) Expiry(s string) *ProtectClient {
+// Default: ExpiryInfinite
+func (w *ProtectClient) Expiry(e ...Expiry) *ProtectClient {
 	w.o = append(w.o, func(m map[string]string) {
-		m["expiry"] = s
+		s := make([]string, len(e))
+		for i := range e {
+			s[i] = string(e[i])
+		}
+		m["expiry"] = strings.Join(s, "|")
 	})
 	return w
 }
@@ -122,9 +134,9 @@ func (w *ProtectClient) Watch(b bool) *ProtectClient {
 // Unconditionally add or remove the page from the current user's watchlist, use preferences (ignored for bot users) or do not change watch.
 // One of the following values: nochange, preferences, unwatch, watch
 // Default: preferences
-func (w *ProtectClient) Watchlist(s string) *ProtectClient {
+func (w *ProtectClient) Watchlist(s Watchlist) *ProtectClient {
 	w.o = append(w.o, func(m map[string]string) {
-		m["watchlist"] = s
+		m["watchlist"] = string(s)
 	})
 	return w
 }
@@ -138,7 +150,34 @@ func (w *ProtectClient) Watchlistexpiry(s string) *ProtectClient {
 	return w
 }
 
+// DryRun
+// Record the request in the client's Plan instead of sending it.
+// Overrides Client.DryRun for this call.
+func (w *ProtectClient) DryRun(b bool) *ProtectClient {
+	w.dryRun = &b
+	return w
+}
+
+// Validate checks the parameters of the request without sending it,
+// and returns all problems found. Do calls it before sending the request.
+func (w *ProtectClient) Validate() error {
+	parameters := Values{}
+	for _, o := range w.o {
+		o(parameters)
+	}
+
+	return checkParams(parameters,
+		exactlyOne("title", "pageid"),
+		required("protections"),
+		protectionExpiries,
+		oneOf("watchlist", watchlistValues...))
+}
+
 func (w *ProtectClient) Do(ctx context.Context) (ProtectResponse, error) {
+	if err := w.Validate(); err != nil {
+		return ProtectResponse{}, err
+	}
+
 	if err := w.c.checkKeepAlive(ctx); err != nil {
 		return ProtectResponse{}, err
 	}
@@ -158,6 +197,14 @@ func (w *ProtectClient) Do(ctx context.Context) (ProtectResponse, error) {
 		o(parameters)
 	}
 
+	if w.c.isDryRun(w.dryRun) {
+		w.c.plan(ctx, parameters, nil)
+
+		r := ProtectResponse{Protect: &ProtectResponseProtect{Title: parameters["title"], Reason: parameters["reason"]}}
+		simulate(&r.CoreResponse, r)
+		return r, nil
+	}
+
 	// Make the request.
 	r := ProtectResponse{}
 	j, err := w.c.PostInto(ctx, parameters, &r)
@@ -174,3 +221,17 @@ func (w *ProtectClient) Do(ctx context.Context) (ProtectResponse, error) {
 
 	return r, nil
 }
+
+// protectionExpiries requires either a single expiry, or one for each
+// protection.
+func protectionExpiries(v Values) error {
+	if v["expiry"] == "" {
+		return nil
+	}
+
+	n, p := len(strings.Split(v["expiry"], "|")), len(strings.Split(v["protections"], "|"))
+	if n != 1 && n != p {
+		return fmt.Errorf("the parameter expiry must have 1 or %d values, not %d", p, n)
+	}
+	return nil
+}
@@ -29,7 +29,7 @@ func TestProtectStandard(t *testing.T) {
 	require.NotNil(t, r.Edit)
 	assert.Equal(t, Success, r.Edit.Result)
 
-	rp, err := c.Protect().Title(name).Protections("edit=sysop").Reason("This is a test.").Do(ctx)
+	rp, err := c.Protect().Title(name).Protections(Protection{ProtectionEdit, ProtectionSysop}).Reason("This is a test.").Do(ctx)
 	require.NoError(t, err)
 	assert.Nil(t, rp.Error)
 
@@ -44,7 +44,7 @@ func TestProtectError(t *testing.T) {
 	_, err = c.BotLogin(ctx, username, password)
 	require.NoError(t, err)
 
-	rp, err := c.Protect().Title("No such page").Protections("edit=sysop").Reason("This is a test.").Do(ctx)
+	rp, err := c.Protect().Title("No such page").Protections(Protection{ProtectionEdit, ProtectionSysop}).Reason("This is a test.").Do(ctx)
 	require.Error(t, err)
 	assert.NotNil(t, rp.Error)
 	assert.Nil(t, rp.Protect)
@@ -9,7 +9,7 @@ import (
 
 type QueryResponse struct {
 	CoreResponse
-	BatchComplete any `json:"batchcomplete"`
+	BatchComplete bool `json:"batchcomplete"`
 }
 
 type QueryResponseNormalized struct {
@@ -25,19 +25,19 @@ type QueryResponseQuery struct {
 }
 
 type QueryResponseQueryPage struct {
-	PageId               int                              `json:"pageid,omitempty"`
-	Namespace            Namespace                        `json:"ns"`
-	Title                string                           `json:"title"`
-	Revisions            []QueryResponseQueryPageRevision `json:"revisions,omitempty"`
-	Missing              any                              `json:"missing,omitempty"`
-	CategoryInfo         map[string]int                   `json:"categoryinfo,omitempty"`
-	Contentmodel         string                           `json:"contentmodel,omitempty"`
-	Pagelanguage         string                           `json:"pagelanguage,omitempty"`
-	Pagelanguagehtmlcode string                           `json:"pagelanguagehtmlcode,omitempty"`
-	Pagelanguagedir      string                           `json:"pagelanguagedir,omitempty"`
-	Touched              *time.Time                       `json:"touched,omitempty"`
-	Lastrevid            int                              `json:"lastrevid,omitempty"`
-	Length               int                              `json:"length,omitempty"`
+	PageId               int                                    `json:"pageid,omitempty"`
+	Namespace            Namespace                              `json:"ns"`
+	Title                string                                 `json:"title"`
+	Revisions            []QueryResponseQueryPageRevision       `json:"revisions,omitempty"`
+	Missing              bool                                   `json:"missing,omitempty"`
+	CategoryInfo         *CategoryInfoResponsePagesCategoryInfo `json:"categoryinfo,omitempty"`
+	Contentmodel         string                                 `json:"contentmodel,omitempty"`
+	Pagelanguage         string                                 `json:"pagelanguage,omitempty"`
+	Pagelanguagehtmlcode string                                 `json:"pagelanguagehtmlcode,omitempty"`
+	Pagelanguagedir      string                                 `json:"pagelanguagedir,omitempty"`
+	Touched              *time.Time                             `json:"touched,omitempty"`
+	Lastrevid            int                                    `json:"lastrevid,omitempty"`
+	Length               int                                    `json:"length,omitempty"`
 }
 
 type QueryResponseQueryPageRevision struct {
@@ -55,13 +55,34 @@ type QueryResponseQueryPageRevisionSlot struct {
 	ContentFormat string `json:"contentformat"`
 }
 
+// The title helpers below split titles with simple string rules that
+// only work for English wikis with the default configuration. Pass a
+// Site to parse the title with the rules of the wiki instead.
+
+// title parses the page title with the rules of the site, if given.
+func (p QueryResponseQueryPage) title(site []*Site) (Title, bool) {
+	if len(site) == 0 || site[0] == nil {
+		return Title{}, false
+	}
+	t, err := site[0].ParseTitle(p.Title)
+	return t, err == nil
+}
+
 // Namespace and full page title (including all subpage levels).
-func (p QueryResponseQueryPage) FullPageName() string {
+func (p QueryResponseQueryPage) FullPageName(site ...*Site) string {
+	if t, ok := p.title(site); ok {
+		return t.PrefixedText()
+	}
+
 	return strings.ReplaceAll(p.Title, "_", " ")
 }
 
 // Full page title (including all subpage levels) without the namespace.
-func (p QueryResponseQueryPage) PageName() string {
+func (p QueryResponseQueryPage) PageName(site ...*Site) string {
+	if t, ok := p.title(site); ok {
+		return t.Text()
+	}
+
 	title := strings.ReplaceAll(p.Title, "_", " ")
 
 	if p.Namespace == NamespaceMain {
@@ -76,7 +97,11 @@ func (p QueryResponseQueryPage) PageName() string {
 }
 
 // Page title of the page in the immediately superior subpage level without the namespace. Would return Title/Foo on page Help:Title/Foo/Bar.
-func (p QueryResponseQueryPage) BasePageName() string {
+func (p QueryResponseQueryPage) BasePageName(site ...*Site) string {
+	if t, ok := p.title(site); ok {
+		return t.BaseText()
+	}
+
 	name := p.PageName()
 	split := strings.Split(name, "/")
 
@@ -88,21 +113,33 @@ func (p QueryResponseQueryPage) BasePageName() string {
 }
 
 // Name of the root of the current page. Would return Title on page Help:Title/Foo/Bar.
-func (p QueryResponseQueryPage) RootPageName() string {
+func (p QueryResponseQueryPage) RootPageName(site ...*Site) string {
+	if t, ok := p.title(site); ok {
+		return t.RootText()
+	}
+
 	name := p.PageName()
 	split := strings.Split(name, "/")
 	return split[0]
 }
 
 // The subpage title. Would return Bar on page Help:Title/Foo/Bar. If no subpage exists the value of PageName() is returned.
-func (p QueryResponseQueryPage) SubPageName() string {
+func (p QueryResponseQueryPage) SubPageName(site ...*Site) string {
+	if t, ok := p.title(site); ok {
+		return t.SubpageText()
+	}
+
 	name := p.PageName()
 	split := strings.Split(name, "/")
 	return split[len(split)-1]
 }
 
 // Full page name of the associated subject (e.g. article or file). Useful on talk pages.
-func (p QueryResponseQueryPage) ArticlePageName() string {
+func (p QueryResponseQueryPage) ArticlePageName(site ...*Site) string {
+	if t, ok := p.title(site); ok {
+		return t.SubjectPage().PrefixedText()
+	}
+
 	title := strings.ReplaceAll(p.Title, "_", " ")
 
 	switch {
@@ -116,7 +153,14 @@ func (p QueryResponseQueryPage) ArticlePageName() string {
 }
 
 // Full page name of the associated talk page.
-func (p QueryResponseQueryPage) TalkPageName() string {
+func (p QueryResponseQueryPage) TalkPageName(site ...*Site) string {
+	if t, ok := p.title(site); ok {
+		if talk, ok := t.TalkPage(); ok {
+			return talk.PrefixedText()
+		}
+		return ""
+	}
+
 	title := strings.ReplaceAll(p.Title, "_", " ")
 
 	switch {
@@ -3,31 +3,59 @@ package mediawiki
 import (
 	"encoding/json"
 	"io"
+	"reflect"
 	"time"
 )
 
 type CoreResponse struct {
-	RawJSON     string                   `json:"-"`
-	ClientLogin *ResponseClientLogin     `json:"clientlogin,omitempty"`
-	Error       *ResponseError           `json:"error,omitempty"`
-	Warnings    map[string]ResponseError `json:"warnings,omitempty"`
+	RawJSON     string               `json:"-"`
+	Simulated   bool                 `json:"-"`
+	ClientLogin *ResponseClientLogin `json:"clientlogin,omitempty"`
+	Error       *ResponseError       `json:"error,omitempty"`
+
+	// Errors holds the errors of a response in the errorformat=plaintext
+	// format. The first one is also made available as Error.
+	Errors []ResponseError `json:"errors,omitempty"`
+
+	// Warnings holds the warnings of the response, in both the plaintext
+	// and the legacy format.
+	Warnings ResponseWarnings `json:"warnings,omitempty"`
+}
+
+// core returns r. It lets decodeInto find the CoreResponse embedded in
+// any response type.
+func (r *CoreResponse) core() *CoreResponse {
+	return r
+}
+
+// normalize sets Error from Errors, and Error.Info from the plaintext
+// or legacy message, so that callers only need to look at Error.
+func (r *CoreResponse) normalize() {
+	if r.Error == nil && len(r.Errors) > 0 {
+		e := r.Errors[0]
+		r.Error = &e
+	}
+	if e := r.Error; e != nil && e.Info == "" {
+		e.Info = or(e.Text, e.Star)
+	}
 }
 
 type Response struct {
 	CoreResponse
 	RawJSON       string               `json:"-"`
-	BatchComplete any                  `json:"batchcomplete,omitempty"`
+	BatchComplete bool                 `json:"batchcomplete,omitempty"`
 	BotLogin      *ResponseBotLogin    `json:"login,omitempty"`
 	ClientLogin   *ResponseClientLogin `json:"clientlogin,omitempty"`
 	Edit          *ResponseEdit        `json:"edit,omitempty"`
 	Query         *ResponseQuery       `json:"query,omitempty"`
-	Warnings      *ResponseWarnings    `json:"warnings,omitempty"`
 }
 
 type ResponseError struct {
 	Code        string `json:"code,omitempty"`
 	Docref      string `json:"docref,omitempty"`
 	Info        string `json:"info,omitempty"`
+	Text        string `json:"text,omitempty"`
+	Module      string `json:"module,omitempty"`
 	Stasherrors []struct {
 		Message string   `json:"message,omitempty"`
 		Params  []string `json:"params,omitempty"`
@@ -42,10 +70,6 @@ type ResponseQuery struct {
 	Tokens map[string]string        `json:"tokens"`
 }
 
-type ResponseWarnings struct {
-	Tokens map[string]string `json:"tokens"`
-}
-
 type ResponseClientLogin struct {
 	Status      string `json:"status"`
 	Message     string `json:"message"`
@@ -58,6 +82,12 @@ const (
 	Error   Result = "Error"
 	Success Result = "Success"
 	Warning Result = "Warning"
+
+	// Continue, Poll and Failure are results of chunked and
+	// asynchronous uploads.
+	Continue Result = "Continue"
+	Poll     Result = "Poll"
+	Failure  Result = "Failure"
 )
 
 type ResponseBotLogin struct {
@@ -75,7 +105,7 @@ type ResponseEdit struct {
 	OldRevId     int       `json:"oldrevid,omitempty"`
 	NewRevId     int       `json:"newrevid,omitempty"`
 	NewTimestamp time.Time `json:"newtimestamp,omitempty"`
-	Watched      string    `json:"watched"`
+	Watched      bool      `json:"watched"`
 }
 
 func ParseResponseReader(in io.Reader, v any) error {
@@ -87,6 +117,22 @@ func ParseResponseReader(in io.Reader, v any) error {
 	return ParseResponse(b, v)
 }
 
+// ParseResponse decodes the response body b into v. Bodies in the
+// formatversion=1 format are upgraded to the formatversion=2 shapes
+// of the response types first.
 func ParseResponse(b []byte, v any) error {
-	return json.Unmarshal(b, v)
+	err := json.Unmarshal(b, v)
+	if !needsUpgrade(b, err) {
+		return err
+	}
+
+	u, uerr := upgradeFormat(b, reflect.TypeOf(v))
+	if uerr != nil {
+		return or(err, uerr)
+	}
+
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv.Elem().SetZero()
+	}
+	return json.Unmarshal(u, v)
 }
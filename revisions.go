@@ -21,7 +21,14 @@ import (
 
 type RevisionsResponse struct {
 	QueryResponse
-	Query *RevisionsResponseQuery `json:"query,omitempty"`
+	Curtimestamp *time.Time                 `json:"curtimestamp,omitempty"`
+	Continue     *RevisionsResponseContinue `json:"continue,omitempty"`
+	Query        *RevisionsResponseQuery    `json:"query,omitempty"`
+}
+
+type RevisionsResponseContinue struct {
+	Rvcontinue string `json:"rvcontinue"`
+	Continue   string `json:"continue"`
 }
 
 type RevisionsResponseQuery struct {
@@ -38,25 +45,33 @@ type RevisionsResponseNormalized struct {
 type RevisionsResponsePage struct {
 	Namespace Namespace                   `json:"ns"`
 	Title     string                      `json:"title,omitempty"`
-	Missing   any                         `json:"missing,omitempty"`
+	Missing   bool                        `json:"missing,omitempty"`
 	Pageid    int                         `json:"pageid,omitempty"`
 	Revisions []RevisionsResponseRevision `json:"revisions,omitempty"`
 }
 
 type RevisionsResponseRevision struct {
-	Revid         int                              `json:"revid,omitempty"`
-	Parentid      int                              `json:"parentid"`
-	Minor         bool                             `json:"minor"`
-	User          string                           `json:"user,omitempty"`
-	Userid        int                              `json:"userid,omitempty"`
-	Timestamp     *time.Time                       `json:"timestamp,omitempty"`
-	Size          int                              `json:"size,omitempty"`
-	Sha1          string                           `json:"sha1,omitempty"`
-	Roles         []string                         `json:"roles,omitempty"`
-	Slots         map[string]RevisionsResponseSlot `json:"slots,omitempty"`
-	Comment       string                           `json:"comment"`
-	Parsedcomment string                           `json:"parsedcomment,omitempty"`
-	Tags          []string                         `json:"tags,omitempty"`
+	Revid     int                              `json:"revid,omitempty"`
+	Parentid  int                              `json:"parentid"`
+	Minor     bool                             `json:"minor"`
+	User      string                           `json:"user,omitempty"`
+	Userid    int                              `json:"userid,omitempty"`
+	Timestamp *time.Time                       `json:"timestamp,omitempty"`
+	Size      int                              `json:"size,omitempty"`
+	Sha1      string                           `json:"sha1,omitempty"`
+	Roles     []string                         `json:"roles,omitempty"`
+	Slots     map[string]RevisionsResponseSlot `json:"slots,omitempty"`
+
+	// Content, Contentmodel and Contentformat are only returned by
+	// wikis without multi-content revisions (before MediaWiki 1.32).
+	// Do moves them into the main slot.
+	Content       string `json:"content,omitempty"`
+	Contentmodel  string `json:"contentmodel,omitempty"`
+	Contentformat string `json:"contentformat,omitempty"`
+
+	Comment       string   `json:"comment"`
+	Parsedcomment string   `json:"parsedcomment,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
 }
 
 type RevisionsResponseSlot struct {
@@ -85,6 +100,32 @@ func (w *RevisionsClient) Titles(s ...string) *RevisionsClient {
 	return w
 }
 
+// revids
+// A list of revision IDs to work on.
+func (w *RevisionsClient) Revids(ids ...int) *RevisionsClient {
+	w.o = append(w.o, func(m map[string]string) {
+		s := make([]string, len(ids))
+		for i, id := range ids {
+			s[i] = strconv.Itoa(id)
+		}
+		m["revids"] = strings.Join(s, "|")
+	})
+	return w
+}
+
+// pageids
+// A list of page IDs to work on.
+func (w *RevisionsClient) Pageids(ids ...int) *RevisionsClient {
+	w.o = append(w.o, func(m map[string]string) {
+		s := make([]string, len(ids))
+		for i, id := range ids {
+			s[i] = strconv.Itoa(id)
+		}
+		m["pageids"] = strings.Join(s, "|")
+	})
+	return w
+}
+
 // prop
 func (w *RevisionsClient) Prop(s ...string) *RevisionsClient {
 	w.o = append(w.o, func(m map[string]string) {
@@ -229,9 +270,9 @@ func (w *RevisionsClient) End(t time.Time) *RevisionsClient {
 }
 
 // dir
-func (w *RevisionsClient) Dir(s string) *RevisionsClient {
+func (w *RevisionsClient) Dir(s Direction) *RevisionsClient {
 	w.o = append(w.o, func(m map[string]string) {
-		m["rvdir"] = s
+		m["rvdir"] = string(s)
 	})
 	return w
 }
@@ -265,6 +306,16 @@ func (w *RevisionsClient) Tag(s string) *RevisionsClient {
 	return w
 }
 
+// curtimestamp
+// Include the current timestamp in the result. Use it as the
+// StartTimestamp of an edit based on the returned content.
+func (w *RevisionsClient) Curtimestamp(b bool) *RevisionsClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["curtimestamp"] = strconv.FormatBool(b)
+	})
+	return w
+}
+
 // continue
 // When more results are available, use this to continue.
 func (w *RevisionsClient) Continue(s string) *RevisionsClient {
@@ -274,23 +325,50 @@ func (w *RevisionsClient) Continue(s string) *RevisionsClient {
 	return w
 }
 
+// Validate checks the parameters of the request without sending it,
+// and returns all problems found. Do calls it before sending the request.
+func (w *RevisionsClient) Validate() error {
+	parameters := Values{}
+	for _, o := range w.o {
+		o(parameters)
+	}
+
+	return checkParams(parameters,
+		atMostOne("titles", "pageids", "revids"),
+		oneOf("rvdir", string(DirNewer), string(DirOlder)))
+}
+
 func (w *RevisionsClient) Do(ctx context.Context) (RevisionsResponse, error) {
+	if err := w.Validate(); err != nil {
+		return RevisionsResponse{}, err
+	}
+
 	if err := w.c.checkKeepAlive(ctx); err != nil {
 		return RevisionsResponse{}, err
 	}
 
 	// Specify parameters to send.
 	parameters := Values{
-		"action":        "query",
-		"prop":          "revisions",
-		"formatversion": "2",
-		"rvslots":       "main",
+		"action":  "query",
+		"prop":    "revisions",
+		"rvslots": "main",
 	}
 
 	for _, o := range w.o {
 		o(parameters)
 	}
 
+	// Wikis before 1.32 have no slots.
+	if w.c.CheckCapabilities {
+		ok, err := w.c.Supports(ctx, "query+revisions", "slots")
+		if err != nil {
+			return RevisionsResponse{}, err
+		}
+		if !ok {
+			delete(parameters, "rvslots")
+		}
+	}
+
 	// Make the request.
 	r := RevisionsResponse{}
 	j, err := w.c.GetInto(ctx, parameters, &r)
@@ -303,5 +381,19 @@ func (w *RevisionsClient) Do(ctx context.Context) (RevisionsResponse, error) {
 		return r, fmt.Errorf("%s: %s", e.Code, e.Info)
 	}
 
+	if r.Query != nil {
+		for i := range r.Query.Pages {
+			for j, rev := range r.Query.Pages[i].Revisions {
+				if rev.Slots == nil && (rev.Content != "" || rev.Contentmodel != "") {
+					r.Query.Pages[i].Revisions[j].Slots = map[string]RevisionsResponseSlot{"main": {
+						Content:       rev.Content,
+						Contentmodel:  rev.Contentmodel,
+						Contentformat: rev.Contentformat,
+					}}
+				}
+			}
+		}
+	}
+
 	return r, nil
 }
@@ -0,0 +1,180 @@
+// Code generated by import; DO NOT EDIT.
+
+package mediawiki
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Undo the last edit to the page.
+// If the last user who edited the page made multiple edits in a row, they will all be rolled back.
+// https://www.mediawiki.org/wiki/Special:MyLanguage/API:Rollback
+//
+// Flags:
+// * This module requires write rights.
+// * This module only accepts POST requests.
+
+// Rollback
+
+type RollbackResponse struct {
+	CoreResponse
+	Rollback *RollbackRollbackResponse `json:"rollback,omitempty"`
+}
+
+type RollbackRollbackResponse struct {
+	Title     string `json:"title"`
+	Pageid    int    `json:"pageid"`
+	Summary   string `json:"summary"`
+	Revid     int    `json:"revid"`
+	Oldrevid  int    `json:"old_revid"`
+	Lastrevid int    `json:"last_revid"`
+}
+
+type RollbackOption func(map[string]string)
+
+type RollbackClient struct {
+	o []RollbackOption
+	c *Client
+}
+
+func (c *Client) Rollback() *RollbackClient {
+	return &RollbackClient{c: c}
+}
+
+// Title
+// Title of the page to roll back. Cannot be used together with pageid.
+func (w *RollbackClient) Title(s string) *RollbackClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["title"] = s
+	})
+	return w
+}
+
+// Pageid
+// Page ID of the page to roll back. Cannot be used together with title.
+// Type: integer
+func (w *RollbackClient) Pageid(i int) *RollbackClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["pageid"] = strconv.FormatInt(int64(i), 10)
+	})
+	return w
+}
+
+// Tags
+// Tags to apply to the rollback.
+// Values (separate with | or alternative): mw-rollback
+// Maximum number of values is 50 (500 for clients allowed higher limits).
+func (w *RollbackClient) Tags(s ...string) *RollbackClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["tags"] = strings.Join(s, "|")
+	})
+	return w
+}
+
+// User
+// Name of the user whose edits are to be rolled back.
+// This parameter is required.
+// Type: user
+func (w *RollbackClient) User(s string) *RollbackClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["user"] = s
+	})
+	return w
+}
+
+// Summary
+// Custom edit summary. If empty, default summary will be used.
+func (w *RollbackClient) Summary(s string) *RollbackClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["summary"] = s
+	})
+	return w
+}
+
+// Markbot
+// Mark the reverted edits and the revert as bot edits.
+// Type: boolean
+func (w *RollbackClient) Markbot(b bool) *RollbackClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["markbot"] = strconv.FormatBool(b)
+	})
+	return w
+}
+
+// Watchlist
+// Unconditionally add or remove the page from the current user's watchlist, use preferences or do not change watch.
+// One of the following values: nochange, preferences, unwatch, watch
+// If Watchlist isn't called, the wiki uses WatchlistPreferences.
+func (w *RollbackClient) Watchlist(s Watchlist) *RollbackClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["watchlist"] = string(s)
+	})
+	return w
+}
+
+// Watchlistexpiry
+// Watchlist expiry timestamp. Omit this parameter entirely to leave the current expiry unchanged.
+// Type: expiry
+func (w *RollbackClient) Watchlistexpiry(s string) *RollbackClient {
+	w.o = append(w.o, func(m map[string]string) {
+		m["watchlistexpiry"] = s
+	})
+	return w
+}
+
+// Validate checks the parameters of the request without sending it,
+// and returns all problems found. Do calls it before sending the request.
+func (w *RollbackClient) Validate() error {
+	parameters := Values{}
+	for _, o := range w.o {
+		o(parameters)
+	}
+
+	return checkParams(parameters,
+		required("user"),
+		oneOf("watchlist", "nochange", "preferences", "unwatch", "watch"))
+}
+
+func (w *RollbackClient) Do(ctx context.Context) (RollbackResponse, error) {
+	if err := w.Validate(); err != nil {
+		return RollbackResponse{}, err
+	}
+
+	if err := w.c.checkKeepAlive(ctx); err != nil {
+		return RollbackResponse{}, err
+	}
+
+	token, err := w.c.GetToken(ctx, RollbackToken)
+	if err != nil {
+		return RollbackResponse{}, err
+	}
+
+	// Specify parameters to send.
+	parameters := Values{
+		"action": "rollback",
+		"token":  token,
+	}
+
+	for _, o := range w.o {
+		o(parameters)
+	}
+
+	// Make the request.
+	r := RollbackResponse{}
+	j, err := w.c.PostInto(ctx, parameters, &r)
+	r.RawJSON = j
+	if err != nil {
+		return r, fmt.Errorf("failed to post: %w", err)
+	}
+
+	if e := r.Error; e != nil {
+		return r, fmt.Errorf("%s: %s", e.Code, e.Info)
+	} else if r.Rollback == nil {
+		return r, fmt.Errorf("unexpected error in rollback")
+	}
+
+	return r, nil
+}
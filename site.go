@@ -0,0 +1,302 @@
+package mediawiki
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSiteTTL is how long Client.Site uses cached site metadata if
+// Client.SiteTTL is not set.
+const DefaultSiteTTL = time.Hour
+
+// siteProps are the siteinfo properties loaded by Client.Site.
+var siteProps = []string{
+	SiteinfoPropGeneral,
+	SiteinfoPropNamespaces,
+	SiteinfoPropNamespacealiases,
+	SiteinfoPropInterwikimap,
+	SiteinfoPropMagicwords,
+	SiteinfoPropExtensions,
+	SiteinfoPropRestrictions,
+	SiteinfoPropFileextensions,
+}
+
+// Site is the metadata of a wiki: its general settings, namespaces,
+// interwiki prefixes, magic words, extensions, restriction types and
+// allowed file extensions. It holds the rules used to parse titles.
+// Use Client.Site to get the cached metadata of the client's wiki.
+type Site struct {
+	// Info is the siteinfo response the site was built from.
+	Info SiteinfoResponseQuery
+
+	// Fetched is when the site metadata was loaded from the wiki.
+	Fetched time.Time
+
+	Namespaces *Namespaces
+
+	interwiki map[string]SiteinfoInterwikiMap
+
+	// firstLetter is the wiki-wide case rule, used for namespaces that
+	// don't specify their own.
+	firstLetter bool
+
+	illegal *regexp.Regexp
+}
+
+// defaultLegalTitleChars is the default value of $wgLegalTitleChars.
+const defaultLegalTitleChars = ` %!"$&'()*,\-.\/0-9:;=?@A-Z\\^_` + "`" + `a-z~\x80-\xFF+`
+
+// NewSite returns the site described by a siteinfo response. To parse
+// titles, q needs at least siprop=general|namespaces|namespacealiases|interwikimap.
+// Anything missing from q falls back to the defaults of an English
+// wiki: the standard namespaces, no interwiki prefixes, first-letter
+// capitalization and the default legal title characters.
+func NewSite(q SiteinfoResponseQuery) (*Site, error) {
+	s := &Site{
+		Info:        q,
+		Namespaces:  NewNamespaces(q),
+		interwiki:   map[string]SiteinfoInterwikiMap{},
+		firstLetter: true,
+	}
+
+	legal := defaultLegalTitleChars
+	if g := q.General; g != nil {
+		s.firstLetter = g.Case != "case-sensitive"
+		if g.Legaltitlechars != "" {
+			legal = g.Legaltitlechars
+		}
+	}
+
+	// MediaWiki matches the legal characters against bytes, so \x80-\xFF
+	// stands for any non-ASCII character.
+	legal = strings.ReplaceAll(legal, `\x80-\xFF`, `\x{80}-\x{10FFFF}`)
+	re, err := regexp.Compile(`[^` + legal + `]|%[0-9A-Fa-f]{2}|&[A-Za-z0-9\x{80}-\x{10FFFF}]+;`)
+	if err != nil {
+		return nil, fmt.Errorf("invalid legal title characters: %w", err)
+	}
+	s.illegal = re
+
+	for _, iw := range q.InterwikiMap {
+		s.interwiki[strings.ToLower(iw.Prefix)] = iw
+	}
+
+	return s, nil
+}
+
+// firstLetterCase reports whether the first letter of titles in the
+// namespace is capitalized.
+func (s *Site) firstLetterCase(ns Namespace) bool {
+	info, _ := s.Namespaces.Get(ns)
+	switch info.Case {
+	case "first-letter":
+		return true
+	case "case-sensitive":
+		return false
+	}
+	return s.firstLetter
+}
+
+// SiteCache stores site metadata for Client.Site, keyed by API URL.
+// Clients may share a cache. Implementations must be safe for concurrent
+// use.
+type SiteCache interface {
+	Get(key string) (*Site, bool)
+	Set(key string, site *Site)
+	Delete(key string)
+}
+
+// MemorySiteCache is a SiteCache that keeps sites in memory. It is the
+// default cache of a Client.
+type MemorySiteCache struct {
+	mu    sync.Mutex
+	sites map[string]*Site
+}
+
+// NewMemorySiteCache returns an empty MemorySiteCache.
+func NewMemorySiteCache() *MemorySiteCache {
+	return &MemorySiteCache{sites: map[string]*Site{}}
+}
+
+func (m *MemorySiteCache) Get(key string) (*Site, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.sites[key]
+	return s, ok
+}
+
+func (m *MemorySiteCache) Set(key string, site *Site) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sites[key] = site
+}
+
+func (m *MemorySiteCache) Delete(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.sites, key)
+}
+
+// Site returns the metadata of the wiki. It is loaded from siteinfo the
+// first time it is needed and cached in SiteCache for SiteTTL.
+func (c *Client) Site(ctx context.Context) (*Site, error) {
+	c.siteMutex.Lock()
+	defer c.siteMutex.Unlock()
+
+	key := c.apiURL.String()
+	ttl := c.SiteTTL
+	if ttl == 0 {
+		ttl = DefaultSiteTTL
+	}
+
+	if s, ok := c.SiteCache.Get(key); ok && time.Since(s.Fetched) < ttl {
+		return s, nil
+	}
+
+	r, err := c.Siteinfo().Prop(siteProps...).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if r.Query == nil {
+		return nil, fmt.Errorf("unexpected error in query")
+	}
+
+	s, err := NewSite(*r.Query)
+	if err != nil {
+		return nil, err
+	}
+	s.Fetched = time.Now()
+
+	c.SiteCache.Set(key, s)
+	return s, nil
+}
+
+// InvalidateSite removes the wiki's metadata from SiteCache, so that the
+// next call to Site loads it again. The namespace registry returned by
+// Namespaces and the cached module information used for capability
+// checks are discarded as well.
+func (c *Client) InvalidateSite() {
+	c.SiteCache.Delete(c.apiURL.String())
+
+	c.namespacesMutex.Lock()
+	c.namespaces = nil
+	c.namespacesMutex.Unlock()
+
+	c.caps.Lock()
+	c.caps.modules = nil
+	c.caps.Unlock()
+}
+
+// HasExtension reports whether the extension with the given name is
+// installed, ignoring case.
+func (s *Site) HasExtension(name string) bool {
+	for _, e := range s.Info.Extensions {
+		if strings.EqualFold(e.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Version returns the MediaWiki version, parsed from the generator in
+// the general site info.
+func (s *Site) Version() (Version, error) {
+	if s.Info.General == nil {
+		return Version{}, fmt.Errorf("no general site info")
+	}
+	return ParseVersion(s.Info.General.Generator)
+}
+
+// AllowedFileExtension reports whether files with the extension may be
+// uploaded. The leading dot is optional and case is ignored.
+func (s *Site) AllowedFileExtension(ext string) bool {
+	ext = strings.TrimPrefix(ext, ".")
+	for _, e := range s.Info.Fileextensions {
+		if strings.EqualFold(e.Ext, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// ProtectionLevels returns the protection levels of the wiki, such as
+// "autoconfirmed" and "sysop". The empty level means no protection.
+func (s *Site) ProtectionLevels() []string {
+	if s.Info.Restrictions == nil {
+		return nil
+	}
+	return s.Info.Restrictions.Levels
+}
+
+// ProtectionTypes returns the actions that can be protected, such as
+// "edit" and "move".
+func (s *Site) ProtectionTypes() []string {
+	if s.Info.Restrictions == nil {
+		return nil
+	}
+	return s.Info.Restrictions.Types
+}
+
+// MagicWord returns the aliases of the magic word with the given
+// canonical name, such as "redirect".
+func (s *Site) MagicWord(name string) []string {
+	i := slices.IndexFunc(s.Info.MagicWords, func(m SiteinfoMagicWords) bool { return m.Name == name })
+	if i < 0 {
+		return nil
+	}
+	return s.Info.MagicWords[i].Alises
+}
+
+// Version is a MediaWiki version such as 1.41.0-wmf.5. Suffix is
+// everything after the patch number.
+type Version struct {
+	Major, Minor, Patch int
+	Suffix              string
+}
+
+var versionRegexp = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?(\S*)`)
+
+// ParseVersion parses the first version number in s, such as the
+// generator "MediaWiki 1.41.0-wmf.5".
+func ParseVersion(s string) (Version, error) {
+	m := versionRegexp.FindStringSubmatch(s)
+	if m == nil {
+		return Version{}, fmt.Errorf("no version in %q", s)
+	}
+
+	var v Version
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	v.Patch, _ = strconv.Atoi(m[3])
+	v.Suffix = m[4]
+
+	return v, nil
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d%s", v.Major, v.Minor, v.Patch, v.Suffix)
+}
+
+// Compare returns -1, 0 or 1 depending on whether v is older than, the
+// same as or newer than o. Suffixes are ignored.
+func (v Version) Compare(o Version) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d != 0 {
+			return max(-1, min(d, 1))
+		}
+	}
+	return 0
+}
+
+// AtLeast reports whether v is major.minor or newer.
+func (v Version) AtLeast(major, minor int) bool {
+	return v.Compare(Version{Major: major, Minor: minor}) >= 0
+}
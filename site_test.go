@@ -0,0 +1,129 @@
+package mediawiki
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clockworksoul/mediawiki/internal/wikitest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const siteSiteinfo = `{"query":{
+	"general": {"generator": "MediaWiki 1.41.0-wmf.5", "case": "first-letter"},
+	"namespaces": {"0": {"id": 0, "*": ""}, "828": {"id": 828, "canonical": "Module", "*": "Module"}},
+	"magicwords": [{"name": "redirect", "aliases": ["#REDIRECT", "#WEITERLEITUNG"]}],
+	"extensions": [{"type": "parserhook", "name": "Scribunto"}],
+	"restrictions": {"types": ["edit", "move"], "levels": ["", "autoconfirmed", "sysop"]},
+	"fileextensions": [{"ext": "png"}, {"ext": "jpg"}]
+}}`
+
+// newSiteServer returns a wiki answering siteinfo queries with
+// siteSiteinfo, and the number of queries.
+func newSiteServer(t *testing.T) (*wikitest.Wiki, *int) {
+	t.Helper()
+
+	calls := 0
+	s := wikitest.New(t, map[string]http.HandlerFunc{
+		"query+siteinfo": func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, strings.Join(siteProps, "|"), r.Form.Get("siprop"))
+			calls++
+			w.Write([]byte(siteSiteinfo))
+		},
+	})
+
+	return s, &calls
+}
+
+func TestClientSite(t *testing.T) {
+	s, calls := newSiteServer(t)
+	ctx := context.Background()
+
+	c, err := New(s.URL, agent)
+	require.NoError(t, err)
+
+	site, err := c.Site(ctx)
+	require.NoError(t, err)
+	assert.True(t, site.HasExtension("scribunto"))
+	assert.False(t, site.HasExtension("Cite"))
+	assert.True(t, site.AllowedFileExtension(".PNG"))
+	assert.False(t, site.AllowedFileExtension("exe"))
+	assert.Equal(t, []string{"", "autoconfirmed", "sysop"}, site.ProtectionLevels())
+	assert.Equal(t, []string{"edit", "move"}, site.ProtectionTypes())
+	assert.Equal(t, []string{"#REDIRECT", "#WEITERLEITUNG"}, site.MagicWord("redirect"))
+	assert.Equal(t, "Module", site.Namespaces.Name(828))
+
+	v, err := site.Version()
+	require.NoError(t, err)
+	assert.Equal(t, Version{Major: 1, Minor: 41, Suffix: "-wmf.5"}, v)
+
+	_, err = c.Site(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, *calls, "site is cached")
+
+	n, err := c.Namespaces(ctx)
+	require.NoError(t, err)
+	assert.Same(t, site.Namespaces, n)
+	assert.Equal(t, 1, *calls)
+
+	c.InvalidateSite()
+	site, err = c.Site(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, *calls, "site is reloaded after invalidation")
+
+	n, err = c.Namespaces(ctx)
+	require.NoError(t, err)
+	assert.Same(t, site.Namespaces, n, "namespaces are reloaded after invalidation")
+
+	c.SiteTTL = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	_, err = c.Site(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, *calls, "site is reloaded after the TTL")
+}
+
+func TestClientSiteSharedCache(t *testing.T) {
+	s, calls := newSiteServer(t)
+	cache := NewMemorySiteCache()
+
+	for i := 0; i < 3; i++ {
+		c, err := New(s.URL, agent)
+		require.NoError(t, err)
+		c.SiteCache = cache
+
+		_, err = c.Site(context.Background())
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, *calls)
+}
+
+func TestParseVersion(t *testing.T) {
+	cc := []struct {
+		In       string
+		Expected Version
+	}{
+		{"MediaWiki 1.35.13", Version{Major: 1, Minor: 35, Patch: 13}},
+		{"MediaWiki 1.41.0-wmf.5", Version{Major: 1, Minor: 41, Suffix: "-wmf.5"}},
+		{"MediaWiki 1.42alpha", Version{Major: 1, Minor: 42, Suffix: "alpha"}},
+	}
+
+	for _, c := range cc {
+		v, err := ParseVersion(c.In)
+		require.NoError(t, err)
+		assert.Equal(t, c.Expected, v)
+	}
+
+	_, err := ParseVersion("MediaWiki")
+	assert.Error(t, err)
+
+	v := Version{Major: 1, Minor: 39, Patch: 2}
+	assert.True(t, v.AtLeast(1, 35))
+	assert.True(t, v.AtLeast(1, 39))
+	assert.False(t, v.AtLeast(1, 40))
+	assert.Equal(t, -1, v.Compare(Version{Major: 1, Minor: 39, Patch: 3}))
+	assert.Equal(t, 0, v.Compare(Version{Major: 1, Minor: 39, Patch: 2, Suffix: "-rc"}))
+	assert.Equal(t, "1.39.2", v.String())
+}
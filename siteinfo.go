@@ -49,7 +49,7 @@ type SiteinfoResponse struct {
 type SiteinfoResponseQuery struct {
 	General           *SiteinfoGeneral                        `json:"general,omitempty"`
 	Namespaces        map[string]SiteinfoNamespace            `json:"namespaces,omitempty"`
-	NamespacesAliases []SiteinfoNamespace                     `json:"namespacealiases,omitempty"`
+	NamespacesAliases []SiteinfoNamespaceAlias                `json:"namespacealiases,omitempty"`
 	SpecialPageAlises []SiteinfoSpecialPageAlises             `json:"specialpagealiases,omitempty"`
 	MagicWords        []SiteinfoMagicWords                    `json:"magicwords,omitempty"`
 	InterwikiMap      []SiteinfoInterwikiMap                  `json:"interwikimap,omitempty"`
@@ -94,23 +94,23 @@ type SiteinfoGeneral struct {
 	Phpsapi                         string                                          `json:"phpsapi,omitempty"`
 	Dbtype                          string                                          `json:"dbtype,omitempty"`
 	Dbversion                       string                                          `json:"dbversion,omitempty"`
-	Langconversion                  string                                          `json:"langconversion"`
-	Linkconversion                  string                                          `json:"linkconversion"`
-	Titleconversion                 string                                          `json:"titleconversion"`
+	Langconversion                  bool                                            `json:"langconversion"`
+	Linkconversion                  bool                                            `json:"linkconversion"`
+	Titleconversion                 bool                                            `json:"titleconversion"`
 	Linkprefixcharset               string                                          `json:"linkprefixcharset"`
 	Linkprefix                      string                                          `json:"linkprefix"`
 	Linktrail                       string                                          `json:"linktrail,omitempty"`
 	Legaltitlechars                 string                                          `json:"legaltitlechars,omitempty"`
 	Invalidusernamechars            string                                          `json:"invalidusernamechars,omitempty"`
-	Fixarabicunicode                string                                          `json:"fixarabicunicode"`
-	Fixmalayalamunicode             string                                          `json:"fixmalayalamunicode"`
+	Fixarabicunicode                bool                                            `json:"fixarabicunicode"`
+	Fixmalayalamunicode             bool                                            `json:"fixmalayalamunicode"`
 	GitHash                         string                                          `json:"git-hash,omitempty"`
 	GitBranch                       string                                          `json:"git-branch,omitempty"`
 	Case                            string                                          `json:"case,omitempty"`
 	Lang                            string                                          `json:"lang,omitempty"`
 	Fallback                        []interface{}                                   `json:"fallback"`
 	Fallback8BitEncoding            string                                          `json:"fallback8bitEncoding,omitempty"`
-	Writeapi                        string                                          `json:"writeapi"`
+	Writeapi                        bool                                            `json:"writeapi"`
 	Maxarticlesize                  int                                             `json:"maxarticlesize,omitempty"`
 	Timezone                        string                                          `json:"timezone,omitempty"`
 	Timeoffset                      int                                             `json:"timeoffset"`
@@ -122,8 +122,8 @@ type SiteinfoGeneral struct {
 	Servername                      string                                          `json:"servername,omitempty"`
 	Wikiid                          string                                          `json:"wikiid,omitempty"`
 	Time                            *time.Time                                      `json:"time,omitempty"`
-	Misermode                       string                                          `json:"misermode,omitempty"`
-	Uploadsenabled                  string                                          `json:"uploadsenabled"`
+	Misermode                       bool                                            `json:"misermode,omitempty"`
+	Uploadsenabled                  bool                                            `json:"uploadsenabled"`
 	Maxuploadsize                   int64                                           `json:"maxuploadsize,omitempty"`
 	Minuploadchunksize              int                                             `json:"minuploadchunksize,omitempty"`
 	Galleryoptions                  *SiteinfoGeneralGalleryoptions                  `json:"galleryoptions,omitempty"`
@@ -132,17 +132,17 @@ type SiteinfoGeneral struct {
 	Favicon                         string                                          `json:"favicon,omitempty"`
 	Centralidlookupprovider         string                                          `json:"centralidlookupprovider,omitempty"`
 	Allcentralidlookupproviders     []string                                        `json:"allcentralidlookupproviders,omitempty"`
-	Interwikimagic                  string                                          `json:"interwikimagic"`
-	Magiclinks                      []string                                        `json:"magiclinks"`
+	Interwikimagic                  bool                                            `json:"interwikimagic"`
+	Magiclinks                      map[string]bool                                 `json:"magiclinks"`
 	Categorycollation               string                                          `json:"categorycollation,omitempty"`
-	Nofollowlinks                   string                                          `json:"nofollowlinks,omitempty"`
+	Nofollowlinks                   bool                                            `json:"nofollowlinks,omitempty"`
 	Nofollownsexceptions            []interface{}                                   `json:"nofollownsexceptions,omitempty"`
 	Nofollowdomainexceptions        []string                                        `json:"nofollowdomainexceptions,omitempty"`
 	WmfConfig                       *SiteinfoGeneralWmfConfig                       `json:"wmf-config,omitempty"`
 	Extensiondistributor            *SiteinfoGeneralExtensiondistributor            `json:"extensiondistributor,omitempty"`
 	Mobileserver                    string                                          `json:"mobileserver,omitempty"`
 	ReadinglistsConfig              *SiteinfoGeneralReadinglistsConfig              `json:"readinglists-config,omitempty"`
-	Citeresponsivereferences        string                                          `json:"citeresponsivereferences,omitempty"`
+	Citeresponsivereferences        bool                                            `json:"citeresponsivereferences,omitempty"`
 	Linter                          *SiteinfoGeneralLinter                          `json:"linter,omitempty"`
 	PageviewserviceSupportedMetrics *SiteinfoGeneralPageviewserviceSupportedMetrics `json:"pageviewservice-supported-metrics,omitempty"`
 }
@@ -150,11 +150,17 @@ type SiteinfoGeneral struct {
 type SiteinfoNamespace struct {
 	ID                  int    `json:"id"`
 	Case                string `json:"case,omitempty"`
-	Subpages            any    `json:"subpages,omitempty"`
+	Name                string `json:"name"`
+	Subpages            bool   `json:"subpages,omitempty"`
 	Canonical           string `json:"canonical,omitempty"`
-	Content             any    `json:"content,omitempty"`
+	Content             bool   `json:"content,omitempty"`
+	Nonincludable       bool   `json:"nonincludable,omitempty"`
 	NamespaceProtection string `json:"namespaceprotection,omitempty"`
-	Comment             string `json:"*"`
+}
+
+type SiteinfoNamespaceAlias struct {
+	ID    int    `json:"id"`
+	Alias string `json:"alias"`
 }
 
 type SiteinfoSpecialPageAlises struct {
@@ -165,12 +171,12 @@ type SiteinfoSpecialPageAlises struct {
 type SiteinfoMagicWords struct {
 	Name          string   `json:"name,omitempty"`
 	Alises        []string `json:"aliases,omitempty"`
-	CaseSensitive any      `json:"case-sensitive,omitempty"`
+	CaseSensitive bool     `json:"case-sensitive,omitempty"`
 }
 
 type SiteinfoInterwikiMap struct {
 	Prefix string `json:"prefix,omitempty"`
-	Local  any    `json:"local,omitempty"`
+	Local  bool   `json:"local,omitempty"`
 	URL    string `json:"url,omitempty"`
 	API    string `json:"api,omitempty"`
 }
@@ -194,9 +200,9 @@ type SiteinfoShowhooks struct {
 
 type SiteinfoSkin struct {
 	Code     string `json:"code,omitempty"`
-	Default  any    `json:"default,omitempty"`
-	Unusable any    `json:"unusable,omitempty"`
-	Comment  string `json:"*,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Default  bool   `json:"default,omitempty"`
+	Unusable bool   `json:"unusable,omitempty"`
 }
 
 type SiteinfoFallbacks struct {
@@ -204,9 +210,9 @@ type SiteinfoFallbacks struct {
 }
 
 type SiteinfoLanguages struct {
-	Code    string `json:"code,omitempty"`
-	Bcp47   string `json:"bcp47,omitempty"`
-	Comment string `json:"*,omitempty"`
+	Code  string `json:"code,omitempty"`
+	Bcp47 string `json:"bcp47,omitempty"`
+	Name  string `json:"name,omitempty"`
 }
 
 type SiteinfoRestrictions struct {
@@ -257,10 +263,10 @@ type SiteinfoGeneralGalleryoptions struct {
 	ImagesPerRow   int    `json:"imagesPerRow"`
 	ImageWidth     int    `json:"imageWidth"`
 	ImageHeight    int    `json:"imageHeight"`
-	CaptionLength  string `json:"captionLength"`
-	ShowBytes      string `json:"showBytes"`
+	CaptionLength  bool   `json:"captionLength"`
+	ShowBytes      bool   `json:"showBytes"`
 	Mode           string `json:"mode"`
-	ShowDimensions string `json:"showDimensions"`
+	ShowDimensions bool   `json:"showDimensions"`
 }
 
 type SiteinfoGeneralImagelimits struct {
@@ -374,7 +380,23 @@ func (w *SiteinfoClient) Inlanguagecode(s string) *SiteinfoClient {
 	return w
 }
 
+// Validate checks the interwiki filter, the only parameter with a
+// fixed set of values.
+func (w *SiteinfoClient) Validate() error {
+	parameters := Values{}
+	for _, o := range w.o {
+		o(parameters)
+	}
+
+	return checkParams(parameters,
+		oneOf("sifilteriw", "local", "!local"))
+}
+
 func (w *SiteinfoClient) Do(ctx context.Context) (SiteinfoResponse, error) {
+	if err := w.Validate(); err != nil {
+		return SiteinfoResponse{}, err
+	}
+
 	if err := w.c.checkKeepAlive(ctx); err != nil {
 		return SiteinfoResponse{}, err
 	}
@@ -0,0 +1,409 @@
+package mediawiki
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/clockworksoul/mediawiki/wikitext"
+)
+
+// TemplateEditResult is the outcome of a TemplateEdit on one page.
+type TemplateEditResult struct {
+	Title string
+
+	// Invocations is the number of invocations of the template found on
+	// the page.
+	Invocations int
+
+	// Changed reports whether the page text was changed and saved.
+	Changed bool
+
+	// Edit is the response to the edit, if one was made.
+	Edit *EditResponse
+
+	// Err is the error fetching, editing or saving this page, if any.
+	Err error
+}
+
+// TemplateEditClient changes the parameters of every invocation of a
+// template on a set of pages: either the pages given with Titles, or
+// every page that transcludes the template.
+//
+// Invocations through redirects to the template are included. Each page
+// is fetched with Revisions and saved with Edit, passing the base
+// revision and start timestamp so that a concurrent edit causes an edit
+// conflict instead of being overwritten. Pages where nothing changes
+// are not saved.
+//
+//	r, err := c.TemplateEdit("Task").
+//		Transclusions(NamespaceMain).
+//		Set("status", "done").
+//		Summary("Mark tasks done").
+//		Do(ctx)
+type TemplateEditClient struct {
+	c        *Client
+	template string
+	titles   []string
+	all      bool
+	ns       []Namespace
+	fns      []func(*wikitext.Template) error
+	edit     []EditOption
+	dryRun   *bool
+	resolve  bool
+}
+
+// TemplateEdit returns a client that edits invocations of the named
+// template. The "Template:" prefix is optional.
+func (c *Client) TemplateEdit(template string) *TemplateEditClient {
+	return &TemplateEditClient{c: c, template: template}
+}
+
+// Titles
+// Edit these pages.
+func (w *TemplateEditClient) Titles(s ...string) *TemplateEditClient {
+	w.titles = append(w.titles, s...)
+	return w
+}
+
+// Transclusions
+// Edit every page that transcludes the template, optionally only in the
+// given namespaces.
+func (w *TemplateEditClient) Transclusions(ns ...Namespace) *TemplateEditClient {
+	w.all = true
+	w.ns = ns
+	return w
+}
+
+// Set
+// Set a parameter, adding it if it isn't present.
+func (w *TemplateEditClient) Set(name, value string) *TemplateEditClient {
+	return w.Func(func(t *wikitext.Template) error {
+		t.Set(name, value)
+		return nil
+	})
+}
+
+// Rename
+// Rename a named parameter, if present.
+func (w *TemplateEditClient) Rename(old, name string) *TemplateEditClient {
+	return w.Func(func(t *wikitext.Template) error {
+		t.Rename(old, name)
+		return nil
+	})
+}
+
+// Remove
+// Remove a parameter, if present.
+func (w *TemplateEditClient) Remove(name string) *TemplateEditClient {
+	return w.Func(func(t *wikitext.Template) error {
+		t.Remove(name)
+		return nil
+	})
+}
+
+// Func
+// Call fn for every invocation of the template. Functions are called in
+// the order they were added. If fn returns an error, the page isn't
+// saved.
+func (w *TemplateEditClient) Func(fn func(*wikitext.Template) error) *TemplateEditClient {
+	w.fns = append(w.fns, fn)
+	return w
+}
+
+// Summary
+// Edit summary.
+func (w *TemplateEditClient) Summary(s string) *TemplateEditClient {
+	w.edit = append(w.edit, func(m map[string]string) {
+		m["summary"] = s
+	})
+	return w
+}
+
+// Minor
+// Mark the edits as minor edits.
+func (w *TemplateEditClient) Minor(b bool) *TemplateEditClient {
+	w.edit = append(w.edit, func(m map[string]string) {
+		if b {
+			m["minor"] = "true"
+		}
+	})
+	return w
+}
+
+// Bot
+// Mark the edits as bot edits.
+func (w *TemplateEditClient) Bot(b bool) *TemplateEditClient {
+	w.edit = append(w.edit, func(m map[string]string) {
+		if b {
+			m["bot"] = "true"
+		}
+	})
+	return w
+}
+
+// DryRun
+// Record the edits in the client's Plan instead of sending them.
+// Overrides Client.DryRun for this call.
+func (w *TemplateEditClient) DryRun(b bool) *TemplateEditClient {
+	w.dryRun = &b
+	return w
+}
+
+// ResolveConflicts
+// Merge edits made to a page while it was being changed instead of
+// failing with an edit conflict. See EditClient.ResolveConflicts.
+func (w *TemplateEditClient) ResolveConflicts(b bool) *TemplateEditClient {
+	w.resolve = b
+	return w
+}
+
+// Validate checks that a template, the pages to edit and at least one
+// change are given, and returns all problems found. Do calls it before
+// fetching any page.
+func (w *TemplateEditClient) Validate() error {
+	var errs []error
+
+	if strings.TrimSpace(w.template) == "" {
+		errs = append(errs, errors.New("the template name is required"))
+	}
+	if len(w.titles) == 0 && !w.all {
+		errs = append(errs, errors.New("pages to edit are required: call Titles or Transclusions"))
+	}
+	if len(w.fns) == 0 {
+		errs = append(errs, errors.New("no changes to make: call Set, Rename, Remove or Func"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Do edits the pages and returns one result per page. Failures on
+// individual pages are reported in the results; the returned error is
+// only set if the request is invalid, as reported by Validate, or if the
+// pages to edit couldn't be determined.
+func (w *TemplateEditClient) Do(ctx context.Context) ([]TemplateEditResult, error) {
+	if err := w.Validate(); err != nil {
+		return nil, err
+	}
+
+	title := w.template
+	if !strings.Contains(title, ":") {
+		title = "Template:" + title
+	}
+
+	names, err := w.names(ctx, title)
+	if err != nil {
+		return nil, err
+	}
+
+	titles := w.titles
+	if w.all {
+		t, err := w.transclusions(ctx, title)
+		if err != nil {
+			return nil, err
+		}
+		titles = append(titles, t...)
+	}
+
+	var results []TemplateEditResult
+	for len(titles) > 0 {
+		n := min(len(titles), 50)
+		batch := titles[:n]
+		titles = titles[n:]
+
+		r, err := w.c.Revisions().
+			Titles(batch...).
+			Prop("ids", "timestamp", "content").
+			Curtimestamp(true).
+			Do(ctx)
+		if err != nil {
+			for _, t := range batch {
+				results = append(results, TemplateEditResult{Title: t, Err: err})
+			}
+			continue
+		}
+
+		start := time.Now().UTC()
+		if r.Curtimestamp != nil {
+			start = *r.Curtimestamp
+		}
+
+		for _, p := range r.Query.Pages {
+			results = append(results, w.editPage(ctx, p, names, start))
+		}
+	}
+
+	return results, nil
+}
+
+func (w *TemplateEditClient) editPage(ctx context.Context, p RevisionsResponsePage, names *templateNames, start time.Time) TemplateEditResult {
+	res := TemplateEditResult{Title: p.Title}
+
+	if p.Missing || len(p.Revisions) == 0 {
+		res.Err = fmt.Errorf("%s: page does not exist", p.Title)
+		return res
+	}
+
+	rev := p.Revisions[0]
+	text := rev.Slots["main"].Content
+
+	doc := wikitext.Parse(text)
+	for _, t := range doc.Templates() {
+		if !names.match(t.Name()) {
+			continue
+		}
+
+		res.Invocations++
+		for _, fn := range w.fns {
+			if err := fn(t); err != nil {
+				res.Err = fmt.Errorf("%s: %w", p.Title, err)
+				return res
+			}
+		}
+	}
+
+	out := doc.String()
+	if out == text {
+		return res
+	}
+
+	e := w.c.Edit().
+		Title(p.Title).
+		Text(out).
+		BaseRevId(rev.Revid).
+		StartTimestamp(start.Format(time.RFC3339)).
+		NoCreate(true).
+		ResolveConflicts(w.resolve)
+	if rev.Timestamp != nil {
+		e.BaseTimestamp(rev.Timestamp.Format(time.RFC3339))
+	}
+	e.o = append(e.o, w.edit...)
+	if w.dryRun != nil {
+		e.DryRun(*w.dryRun)
+	}
+
+	r, err := e.Do(ctx)
+	res.Edit = &r
+	if err != nil {
+		res.Err = fmt.Errorf("%s: %w", p.Title, err)
+		return res
+	}
+
+	res.Changed = r.Edit == nil || !r.Edit.NoChange
+
+	return res
+}
+
+// names returns the names of the template and every redirect to it.
+func (w *TemplateEditClient) names(ctx context.Context, title string) (*templateNames, error) {
+	names := newTemplateNames()
+	names.add(title)
+
+	cont := ""
+	for {
+		lh := w.c.Linkshere().Titles(title).Show("redirect").Namespace(NamespaceTemplate).Limit(500)
+		if cont != "" {
+			lh.Continue(cont)
+		}
+
+		r, err := lh.Do(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get redirects to %s: %w", title, err)
+		}
+
+		for _, p := range r.Query.Pages {
+			for _, l := range p.Linkshere {
+				names.add(l.Title)
+			}
+		}
+
+		if r.Continue == nil || r.Continue.Lhcontinue == "" {
+			return names, nil
+		}
+		cont = r.Continue.Lhcontinue
+	}
+}
+
+// transclusions returns the titles of the pages that transclude title.
+func (w *TemplateEditClient) transclusions(ctx context.Context, title string) ([]string, error) {
+	var titles []string
+
+	cont := ""
+	for {
+		ti := w.c.Transcludedin().Titles(title).Prop("title").Limit(500)
+		if len(w.ns) > 0 {
+			ti.Namespace(w.ns...)
+		}
+		if cont != "" {
+			ti.Continue(cont)
+		}
+
+		r, err := ti.Do(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get transclusions of %s: %w", title, err)
+		}
+
+		for _, p := range r.Query.Pages {
+			for _, t := range p.Transcludedin {
+				titles = append(titles, t.Title)
+			}
+		}
+
+		if r.Continue == nil || r.Continue.Ticontinue == "" {
+			return titles, nil
+		}
+		cont = r.Continue.Ticontinue
+	}
+}
+
+// templateNames matches template invocations against a set of template
+// titles.
+type templateNames struct {
+	names    map[string]bool
+	prefixes map[string]bool
+}
+
+func newTemplateNames() *templateNames {
+	return &templateNames{
+		names:    map[string]bool{},
+		prefixes: map[string]bool{"template": true},
+	}
+}
+
+// add adds a template title. The namespace prefix of the title, which
+// may be a localized one, is recognized from then on.
+func (n *templateNames) add(title string) {
+	if i := strings.IndexByte(title, ':'); i >= 0 {
+		n.prefixes[normalizePrefix(title[:i])] = true
+	}
+	n.names[n.key(title)] = true
+}
+
+// match reports whether an invocation of name refers to one of the
+// templates.
+func (n *templateNames) match(name string) bool {
+	return n.names[n.key(name)]
+}
+
+// key normalizes a template name for comparison: a template namespace
+// prefix is removed, underscores become spaces and the first letter is
+// capitalized.
+func (n *templateNames) key(s string) string {
+	s = strings.TrimSpace(strings.ReplaceAll(s, "_", " "))
+	if i := strings.IndexByte(s, ':'); i >= 0 && n.prefixes[normalizePrefix(s[:i])] {
+		s = strings.TrimSpace(s[i+1:])
+	}
+	if s == "" {
+		return s
+	}
+
+	r := []rune(s)
+	r[0] = []rune(strings.ToUpper(string(r[0])))[0]
+	return string(r)
+}
+
+func normalizePrefix(s string) string {
+	return strings.ToLower(strings.TrimSpace(strings.ReplaceAll(s, "_", " ")))
+}
@@ -0,0 +1,157 @@
+package mediawiki
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/clockworksoul/mediawiki/internal/wikitest"
+	"github.com/clockworksoul/mediawiki/wikitext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTemplateEditServer returns a wiki with a {{Task}} template, a
+// {{Todo}} redirect to it, and the given pages. Edits are recorded in
+// the returned map.
+func newTemplateEditServer(t *testing.T, pages map[string]string) (*wikitest.Wiki, map[string]Values) {
+	t.Helper()
+
+	var mu sync.Mutex
+	edits := map[string]Values{}
+
+	s := wikitest.New(t, map[string]http.HandlerFunc{
+		"query+linkshere": func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "Template:Task", r.Form.Get("titles"))
+			assert.Equal(t, "redirect", r.Form.Get("lhshow"))
+			w.Write([]byte(`{"query":{"pages":{"1":{"pageid":1,"ns":10,"title":"Template:Task","linkshere":[{"pageid":2,"ns":10,"title":"Template:Todo","redirect":""}]}}}}`))
+		},
+
+		"query+transcludedin": func(w http.ResponseWriter, r *http.Request) {
+			if r.Form.Get("ticontinue") == "" {
+				w.Write([]byte(`{"continue":{"ticontinue":"0|5","continue":"||"},"query":{"pages":{"1":{"pageid":1,"ns":10,"title":"Template:Task","transcludedin":[{"pageid":3,"ns":0,"title":"A"}]}}}}`))
+				return
+			}
+			w.Write([]byte(`{"batchcomplete":"","query":{"pages":{"1":{"pageid":1,"ns":10,"title":"Template:Task","transcludedin":[{"pageid":4,"ns":0,"title":"B"},{"pageid":5,"ns":0,"title":"C"}]}}}}`))
+		},
+
+		"query+revisions": func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "true", r.Form.Get("curtimestamp"))
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			var ps []map[string]any
+			for _, title := range strings.Split(r.Form.Get("titles"), "|") {
+				text, ok := pages[title]
+				if !ok {
+					ps = append(ps, map[string]any{"ns": 0, "title": title, "missing": true})
+					continue
+				}
+				ps = append(ps, map[string]any{
+					"ns": 0, "title": title, "pageid": len(title),
+					"revisions": []map[string]any{{
+						"revid":     100 + len(title),
+						"timestamp": "2024-01-02T03:04:05Z",
+						"slots":     map[string]any{"main": map[string]any{"content": text}},
+					}},
+				})
+			}
+
+			json.NewEncoder(w).Encode(map[string]any{
+				"curtimestamp": "2024-02-03T04:05:06Z",
+				"query":        map[string]any{"pages": ps},
+			})
+		},
+
+		"edit": func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			v := Values{}
+			for k := range r.Form {
+				v[k] = r.Form.Get(k)
+			}
+			edits[r.Form.Get("title")] = v
+			w.Write([]byte(`{"edit":{"result":"Success","title":"` + r.Form.Get("title") + `"}}`))
+		},
+	})
+
+	return s, edits
+}
+
+func TestTemplateEditTransclusions(t *testing.T) {
+	s, edits := newTemplateEditServer(t, map[string]string{
+		"A": "{{Task\n| owner  = Ada\n| status = open\n}}\nText {{Other|status=open}}",
+		"B": "{{todo|status=open}} and {{template:task |status = open }}",
+		"C": "{{Task|status=done}}",
+	})
+
+	c, err := New(s.URL, agent)
+	require.NoError(t, err)
+
+	results, err := c.TemplateEdit("Task").
+		Transclusions(NamespaceMain).
+		Set("status", "done").
+		Summary("close tasks").
+		Bot(true).
+		Do(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	for _, r := range results {
+		assert.NoError(t, r.Err, r.Title)
+	}
+
+	assert.Equal(t, 1, results[0].Invocations)
+	assert.True(t, results[0].Changed)
+	assert.Equal(t, 2, results[1].Invocations)
+	assert.True(t, results[1].Changed)
+	assert.Equal(t, 1, results[2].Invocations)
+	assert.False(t, results[2].Changed)
+	assert.Nil(t, results[2].Edit)
+
+	require.Len(t, edits, 2)
+
+	a := edits["A"]
+	assert.Equal(t, "{{Task\n| owner  = Ada\n| status = done\n}}\nText {{Other|status=open}}", a["text"])
+	assert.Equal(t, "101", a["baserevid"])
+	assert.Equal(t, "2024-01-02T03:04:05Z", a["basetimestamp"])
+	assert.Equal(t, "2024-02-03T04:05:06Z", a["starttimestamp"])
+	assert.Equal(t, "true", a["nocreate"])
+	assert.Equal(t, "close tasks", a["summary"])
+	assert.Equal(t, "true", a["bot"])
+
+	assert.Equal(t, "{{todo|status=done}} and {{template:task |status = done }}", edits["B"]["text"])
+}
+
+func TestTemplateEditTitles(t *testing.T) {
+	s, edits := newTemplateEditServer(t, map[string]string{
+		"A": "{{Task|old=1|drop=2|keep=3}}",
+	})
+
+	c, err := New(s.URL, agent)
+	require.NoError(t, err)
+
+	results, err := c.TemplateEdit("Template:Task").
+		Titles("A", "Missing").
+		Rename("old", "new").
+		Remove("drop").
+		Func(func(tpl *wikitext.Template) error {
+			v, _ := tpl.Get("keep")
+			tpl.Set("keep", v+"0")
+			return nil
+		}).
+		Do(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, "{{Task|new=1|keep=30}}", edits["A"]["text"])
+
+	assert.Equal(t, "Missing", results[1].Title)
+	assert.Error(t, results[1].Err)
+}
@@ -0,0 +1,277 @@
+package mediawiki
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// InvalidTitleError is returned when a title can't be parsed. Code is
+// the MediaWiki message key for the problem, such as
+// "title-invalid-characters".
+type InvalidTitleError struct {
+	Title string
+	Code  string
+}
+
+func (e *InvalidTitleError) Error() string {
+	return fmt.Sprintf("invalid title %q: %s", e.Title, e.Code)
+}
+
+// Title is a parsed and normalized page title. A Title that wasn't
+// parsed by a Site, such as a literal, has the standard namespaces.
+type Title struct {
+	// Interwiki is the interwiki prefix, in lower case, if the title
+	// refers to a page on another wiki.
+	Interwiki string
+
+	Namespace Namespace
+
+	// DBKey is the title without namespace, with underscores instead of
+	// spaces.
+	DBKey string
+
+	// Fragment is the section anchor after '#', with spaces.
+	Fragment string
+
+	site *Site
+}
+
+var (
+	// titleSpace matches the runs of whitespace and underscores that
+	// MediaWiki collapses into a single underscore.
+	titleSpace = regexp.MustCompile(`[ _\x{A0}\x{1680}\x{180E}\x{2000}-\x{200A}\x{2028}\x{2029}\x{202F}\x{205F}\x{3000}]+`)
+
+	// titleMarks matches the directional marks MediaWiki strips.
+	titleMarks = regexp.MustCompile(`[\x{200E}\x{200F}\x{202A}-\x{202E}]`)
+
+	// titlePrefix splits a namespace or interwiki prefix from the rest.
+	titlePrefix = regexp.MustCompile(`^(.+?)_*:_*(.*)$`)
+
+	// titleRelative matches relative path components, which would break
+	// subpage links.
+	titleRelative = regexp.MustCompile(`^\.\.?(/|$)|/\.\.?(/|$)`)
+)
+
+// ParseTitle parses, validates and normalizes a title the way MediaWiki
+// does: HTML entities are decoded, whitespace is collapsed, namespace
+// and interwiki prefixes are recognized in any case and by any of their
+// names, the fragment is split off and the first letter is capitalized
+// where the namespace requires it.
+func (s *Site) ParseTitle(text string) (Title, error) {
+	invalid := func(code string) (Title, error) {
+		return Title{}, &InvalidTitleError{Title: text, Code: code}
+	}
+
+	dbkey := norm.NFC.String(html.UnescapeString(text))
+	dbkey = titleMarks.ReplaceAllString(dbkey, "")
+	dbkey = titleSpace.ReplaceAllString(dbkey, "_")
+	dbkey = strings.Trim(dbkey, "_")
+
+	if strings.ContainsRune(dbkey, utf8.RuneError) {
+		return invalid("title-invalid-utf8")
+	}
+	if dbkey == "" {
+		return invalid("title-invalid-empty")
+	}
+
+	t := Title{Namespace: NamespaceMain, site: s}
+
+	// A leading colon only forces the main namespace; the rest may still
+	// have a prefix.
+	if dbkey[0] == ':' {
+		dbkey = strings.TrimLeft(dbkey[1:], "_")
+	}
+
+	for {
+		m := titlePrefix.FindStringSubmatch(dbkey)
+		if m == nil {
+			break
+		}
+		prefix, rest := m[1], m[2]
+
+		if info, ok := s.Namespaces.Lookup(prefix); ok {
+			ns := info.ID
+			if ns == NamespaceTalk {
+				if mm := titlePrefix.FindStringSubmatch(rest); mm != nil {
+					if _, ok := s.interwiki[strings.ToLower(mm[1])]; ok {
+						return invalid("title-invalid-talk-namespace")
+					}
+				}
+			}
+			t.Namespace, dbkey = ns, rest
+			break
+		}
+
+		iw, ok := s.interwiki[strings.ToLower(prefix)]
+		if !ok || t.Interwiki != "" {
+			break
+		}
+
+		// A prefix pointing back at this wiki is redundant.
+		if iw.Local && rest != "" {
+			dbkey = rest
+			continue
+		}
+
+		t.Interwiki, dbkey = strings.ToLower(prefix), rest
+		if dbkey != "" && dbkey[0] == ':' {
+			dbkey = strings.TrimLeft(dbkey[1:], "_")
+		}
+		break
+	}
+
+	if i := strings.IndexByte(dbkey, '#'); i >= 0 {
+		t.Fragment = strings.ReplaceAll(dbkey[i+1:], "_", " ")
+		dbkey = strings.TrimRight(dbkey[:i], "_")
+	}
+
+	switch {
+	case s.illegal.MatchString(dbkey):
+		return invalid("title-invalid-characters")
+	case titleRelative.MatchString(dbkey):
+		return invalid("title-invalid-relative")
+	case strings.Contains(dbkey, "~~~"):
+		return invalid("title-invalid-magic-tilde")
+	case t.Namespace != NamespaceSpecial && len(dbkey) > 255,
+		t.Namespace == NamespaceSpecial && len(dbkey) > 512:
+		return invalid("title-invalid-too-long")
+	case dbkey == "" && t.Interwiki == "" && t.Namespace != NamespaceMain:
+		return invalid("title-invalid-empty")
+	case dbkey == "" && t.Interwiki == "" && t.Fragment == "":
+		return invalid("title-invalid-empty")
+	case dbkey != "" && dbkey[0] == ':':
+		return invalid("title-invalid-leading-colon")
+	}
+
+	if t.Interwiki == "" && s.firstLetterCase(t.Namespace) {
+		dbkey = upperFirst(dbkey)
+	}
+	t.DBKey = dbkey
+
+	return t, nil
+}
+
+// upperFirst capitalizes the first letter of s.
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+// standardNamespaces are the namespaces of a Title without a site, such
+// as a Title literal.
+var standardNamespaces = NewNamespaces(SiteinfoResponseQuery{})
+
+// namespaces returns the namespaces of the site of the title.
+func (t Title) namespaces() *Namespaces {
+	if t.site == nil {
+		return standardNamespaces
+	}
+	return t.site.Namespaces
+}
+
+// Text returns the title without namespace, with spaces.
+func (t Title) Text() string {
+	return strings.ReplaceAll(t.DBKey, "_", " ")
+}
+
+// PrefixedText returns the title with its namespace, with spaces.
+func (t Title) PrefixedText() string {
+	return strings.ReplaceAll(t.PrefixedDBKey(), "_", " ")
+}
+
+// PrefixedDBKey returns the title with its namespace and interwiki
+// prefix, with underscores.
+func (t Title) PrefixedDBKey() string {
+	s := t.DBKey
+	if name := t.namespaces().Name(t.Namespace); name != "" {
+		s = strings.ReplaceAll(name, " ", "_") + ":" + s
+	}
+	if t.Interwiki != "" {
+		s = t.Interwiki + ":" + s
+	}
+	return s
+}
+
+// String returns the full title, including interwiki prefix and
+// fragment, with spaces.
+func (t Title) String() string {
+	s := t.PrefixedText()
+	if t.Fragment != "" {
+		s += "#" + t.Fragment
+	}
+	return s
+}
+
+// IsTalk reports whether the title is in a talk namespace.
+func (t Title) IsTalk() bool {
+	return t.Namespace > NamespaceMain && t.Namespace%2 == 1
+}
+
+// TalkPage returns the talk page of the title, and false if the
+// namespace has no talk pages. The fragment is dropped.
+func (t Title) TalkPage() (Title, bool) {
+	if t.Namespace < NamespaceMain || t.Interwiki != "" {
+		return Title{}, false
+	}
+	ns, ok := t.namespaces().Talk(t.Namespace)
+	if !ok {
+		return Title{}, false
+	}
+	return Title{Namespace: ns, DBKey: t.DBKey, site: t.site}, true
+}
+
+// SubjectPage returns the subject page of the title: the title itself
+// unless it is a talk page. The fragment is dropped.
+func (t Title) SubjectPage() Title {
+	ns := t.Namespace
+	if t.IsTalk() {
+		ns--
+	}
+	return Title{Interwiki: t.Interwiki, Namespace: ns, DBKey: t.DBKey, site: t.site}
+}
+
+// BaseText returns the title of the parent page without namespace, with
+// spaces: "Title/Foo" for "Help:Title/Foo/Bar". If subpages aren't
+// enabled in the namespace, it is the same as Text.
+func (t Title) BaseText() string {
+	text := t.Text()
+	if !t.namespaces().HasSubpages(t.Namespace) {
+		return text
+	}
+	if i := strings.LastIndexByte(text, '/'); i > 0 {
+		return text[:i]
+	}
+	return text
+}
+
+// RootText returns the title of the root page without namespace, with
+// spaces: "Title" for "Help:Title/Foo/Bar".
+func (t Title) RootText() string {
+	text := t.Text()
+	if !t.namespaces().HasSubpages(t.Namespace) {
+		return text
+	}
+	if i := strings.IndexByte(text, '/'); i > 0 {
+		return text[:i]
+	}
+	return text
+}
+
+// SubpageText returns the last component of the title: "Bar" for
+// "Help:Title/Foo/Bar".
+func (t Title) SubpageText() string {
+	text := t.Text()
+	if !t.namespaces().HasSubpages(t.Namespace) {
+		return text
+	}
+	return text[strings.LastIndexByte(text, '/')+1:]
+}
@@ -0,0 +1,219 @@
+package mediawiki
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// germanSiteinfo is a trimmed formatversion=2 siteinfo response of a
+// German wiki with a case-sensitive custom namespace.
+const germanSiteinfo = `{
+	"general": {"case": "first-letter", "legaltitlechars": " %!\"$&'()*,\\-.\\/0-9:;=?@A-Z\\\\^_` + "`" + `a-z~\\x80-\\xFF+"},
+	"namespaces": {
+		"-2": {"id": -2, "case": "first-letter", "canonical": "Media", "name": "Medium"},
+		"-1": {"id": -1, "case": "first-letter", "canonical": "Special", "name": "Spezial"},
+		"0": {"id": 0, "case": "first-letter", "content": true, "name": ""},
+		"1": {"id": 1, "case": "first-letter", "subpages": true, "canonical": "Talk", "name": "Diskussion"},
+		"2": {"id": 2, "case": "first-letter", "subpages": true, "canonical": "User", "name": "Benutzer"},
+		"3": {"id": 3, "case": "first-letter", "subpages": true, "canonical": "User talk", "name": "Benutzer Diskussion"},
+		"4": {"id": 4, "case": "first-letter", "subpages": true, "canonical": "Project", "name": "Wikipedia"},
+		"6": {"id": 6, "case": "first-letter", "canonical": "File", "name": "Datei"},
+		"12": {"id": 12, "case": "first-letter", "subpages": true, "canonical": "Help", "name": "Hilfe"},
+		"100": {"id": 100, "case": "case-sensitive", "subpages": true, "canonical": "Portal", "name": "Portal"}
+	},
+	"namespacealiases": [
+		{"id": 4, "alias": "WP"},
+		{"id": 6, "alias": "Bild"},
+		{"id": 2, "alias": "Benutzerin"}
+	],
+	"interwikimap": [
+		{"prefix": "en", "url": "https://en.wikipedia.org/wiki/$1"},
+		{"prefix": "de", "local": true, "url": "https://de.wikipedia.org/wiki/$1"},
+		{"prefix": "commons", "url": "https://commons.wikimedia.org/wiki/$1"}
+	]
+}`
+
+func newGermanSite(t *testing.T) *Site {
+	t.Helper()
+
+	var q SiteinfoResponseQuery
+	require.NoError(t, json.Unmarshal([]byte(germanSiteinfo), &q))
+
+	s, err := NewSite(q)
+	require.NoError(t, err)
+	return s
+}
+
+func TestParseTitle(t *testing.T) {
+	s := newGermanSite(t)
+
+	cc := []struct {
+		In        string
+		Full      string
+		Namespace Namespace
+		Interwiki string
+		Fragment  string
+	}{
+		{In: "foo\u200e bar", Full: "Foo bar"},
+		{In: "  foo__ _bar  ", Full: "Foo bar"},
+		{In: "benutzer:alice", Full: "Benutzer:Alice", Namespace: NamespaceUser},
+		{In: "User:alice", Full: "Benutzer:Alice", Namespace: NamespaceUser},
+		{In: "Benutzerin:Alice", Full: "Benutzer:Alice", Namespace: NamespaceUser},
+		{In: "user_talk : alice", Full: "Benutzer Diskussion:Alice", Namespace: NamespaceUserTalk},
+		{In: "WP:Hilfe", Full: "Wikipedia:Hilfe", Namespace: NamespaceProject},
+		{In: "Bild:x.jpg", Full: "Datei:X.jpg", Namespace: NamespaceFile},
+		{In: "Portal:iPhone", Full: "Portal:iPhone", Namespace: 100},
+		{In: ":Kategorie:Foo", Full: "Kategorie:Foo"},
+		{In: ":Datei:Foo", Full: "Datei:Foo", Namespace: NamespaceFile},
+		{In: "Foo#Geschichte und_mehr", Full: "Foo#Geschichte und mehr", Fragment: "Geschichte und mehr"},
+		{In: "#Nur", Full: "#Nur", Fragment: "Nur"},
+		{In: "en:foo bar", Full: "en:foo bar", Interwiki: "en"},
+		{In: "EN:Talk:Foo", Full: "en:Talk:Foo", Interwiki: "en"},
+		{In: "de:foo", Full: "Foo"},
+		{In: "de:Hilfe:foo", Full: "Hilfe:Foo", Namespace: NamespaceHelp},
+		{In: "commons:", Full: "commons:", Interwiki: "commons"},
+		{In: "Tom &amp; Jerry", Full: "Tom & Jerry"},
+		{In: "ärger", Full: "Ärger"},
+	}
+
+	for _, c := range cc {
+		t.Run(c.In, func(t *testing.T) {
+			title, err := s.ParseTitle(c.In)
+			require.NoError(t, err)
+			assert.Equal(t, c.Full, title.String())
+			assert.Equal(t, c.Namespace, title.Namespace)
+			assert.Equal(t, c.Interwiki, title.Interwiki)
+			assert.Equal(t, c.Fragment, title.Fragment)
+		})
+	}
+}
+
+func TestParseTitleInvalid(t *testing.T) {
+	s := newGermanSite(t)
+
+	cc := map[string]string{
+		"":                        "title-invalid-empty",
+		"  _ ":                    "title-invalid-empty",
+		"Benutzer:":               "title-invalid-empty",
+		":":                       "title-invalid-empty",
+		"Foo[bar]":                "title-invalid-characters",
+		"Foo|bar":                 "title-invalid-characters",
+		"Foo<bar>":                "title-invalid-characters",
+		"Foo{{bar}}":              "title-invalid-characters",
+		"100%25":                  "title-invalid-characters",
+		"Foo &nbsp bar &foo; baz": "title-invalid-characters",
+		"..":                      "title-invalid-relative",
+		"./Foo":                   "title-invalid-relative",
+		"Foo/../Bar":              "title-invalid-relative",
+		"Foo/.":                   "title-invalid-relative",
+		"Sig ~~~":                 "title-invalid-magic-tilde",
+		"Benutzer::Alice":         "title-invalid-leading-colon",
+		"Diskussion:en:Foo":       "title-invalid-talk-namespace",
+		strings.Repeat("x", 256):  "title-invalid-too-long",
+	}
+
+	for in, code := range cc {
+		_, err := s.ParseTitle(in)
+
+		var te *InvalidTitleError
+		require.True(t, errors.As(err, &te), "%q", in)
+		assert.Equal(t, code, te.Code, "%q", in)
+	}
+
+	_, err := s.ParseTitle("Spezial:" + strings.Repeat("x", 300))
+	assert.NoError(t, err)
+}
+
+func TestTitleNavigation(t *testing.T) {
+	s := newGermanSite(t)
+
+	title, err := s.ParseTitle("Hilfe:Titel/Foo/Bar")
+	require.NoError(t, err)
+	assert.Equal(t, "Titel/Foo/Bar", title.Text())
+	assert.Equal(t, "Hilfe:Titel/Foo/Bar", title.PrefixedText())
+	assert.Equal(t, "Titel/Foo", title.BaseText())
+	assert.Equal(t, "Titel", title.RootText())
+	assert.Equal(t, "Bar", title.SubpageText())
+	assert.False(t, title.IsTalk())
+
+	// Subpages are disabled in the file namespace.
+	title, err = s.ParseTitle("Datei:A/B.jpg")
+	require.NoError(t, err)
+	assert.Equal(t, "A/B.jpg", title.BaseText())
+	assert.Equal(t, "A/B.jpg", title.SubpageText())
+
+	title, err = s.ParseTitle("Benutzer:Alice/Notizen#Liste")
+	require.NoError(t, err)
+	talk, ok := title.TalkPage()
+	require.True(t, ok)
+	assert.Equal(t, "Benutzer_Diskussion:Alice/Notizen", talk.PrefixedDBKey())
+	assert.True(t, talk.IsTalk())
+	assert.Equal(t, "Benutzer:Alice/Notizen", talk.SubjectPage().PrefixedText())
+
+	title, err = s.ParseTitle("Spezial:Suche")
+	require.NoError(t, err)
+	_, ok = title.TalkPage()
+	assert.False(t, ok)
+
+	// The file talk namespace isn't in this siteinfo.
+	title, err = s.ParseTitle("Datei:A.jpg")
+	require.NoError(t, err)
+	_, ok = title.TalkPage()
+	assert.False(t, ok)
+}
+
+func TestSiteDefaults(t *testing.T) {
+	s, err := NewSite(SiteinfoResponseQuery{})
+	require.NoError(t, err)
+
+	title, err := s.ParseTitle("user_talk:foo/bar")
+	require.NoError(t, err)
+	assert.Equal(t, "User talk:Foo/bar", title.PrefixedText())
+	assert.Equal(t, "Foo", title.BaseText())
+	assert.Equal(t, "User:Foo/bar", title.SubjectPage().PrefixedText())
+}
+
+func TestTitleLiteral(t *testing.T) {
+	// Titles that weren't parsed use the standard namespaces.
+	title := Title{Namespace: NamespaceUser, DBKey: "Foo/bar/baz"}
+	assert.Equal(t, "User:Foo/bar/baz", title.PrefixedDBKey())
+	assert.Equal(t, "Foo/bar", title.BaseText())
+	assert.Equal(t, "Foo", title.RootText())
+	assert.Equal(t, "baz", title.SubpageText())
+
+	talk, ok := title.TalkPage()
+	require.True(t, ok)
+	assert.Equal(t, "User talk:Foo/bar/baz", talk.String())
+
+	var zero Title
+	assert.Equal(t, "", zero.String())
+	assert.Equal(t, "", zero.SubpageText())
+	talk, ok = zero.TalkPage()
+	require.True(t, ok)
+	assert.Equal(t, NamespaceTalk, talk.Namespace)
+}
+
+func TestResponsePageWithSite(t *testing.T) {
+	s := newGermanSite(t)
+
+	p := QueryResponseQueryPage{Title: "Benutzer Diskussion:Alice/Archiv", Namespace: NamespaceUserTalk}
+	assert.Equal(t, "Benutzer Diskussion:Alice/Archiv", p.FullPageName(s))
+	assert.Equal(t, "Alice/Archiv", p.PageName(s))
+	assert.Equal(t, "Alice", p.BasePageName(s))
+	assert.Equal(t, "Alice", p.RootPageName(s))
+	assert.Equal(t, "Archiv", p.SubPageName(s))
+	assert.Equal(t, "Benutzer:Alice/Archiv", p.ArticlePageName(s))
+	assert.Equal(t, "Benutzer Diskussion:Alice/Archiv", p.TalkPageName(s))
+
+	// The string rules only know English namespace names.
+	assert.Equal(t, "Benutzer Diskussion:Alice/Archiv", p.ArticlePageName())
+
+	p = QueryResponseQueryPage{Title: "Foo", Namespace: NamespaceMain}
+	assert.Equal(t, "Diskussion:Foo", p.TalkPageName(s))
+	assert.Equal(t, "Talk:Foo", p.TalkPageName())
+}
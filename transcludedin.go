@@ -17,25 +17,25 @@ import (
 
 type TranscludedinResponse struct {
 	CoreResponse
-	BatchComplete any                    `json:"batchcomplete,omitempty"`
+	BatchComplete bool                   `json:"batchcomplete,omitempty"`
 	Continue      *TranscludedinContinue `json:"continue,omitempty"`
 	Query         *TranscludedinQuery    `json:"query,omitempty"`
 }
 
 type TranscludedinContinue struct {
-	Lhcontinue string `json:"lhcontinue"`
+	Ticontinue string `json:"ticontinue"`
 	Continue   string `json:"continue"`
 }
 
 type TranscludedinQuery struct {
-	Pages map[string]TranscludedinFromPage `json:"pages"`
+	Pages []TranscludedinFromPage `json:"pages"`
 }
 
 type TranscludedinFromPage struct {
 	Pageid        int                 `json:"pageid"`
 	Ns            Namespace           `json:"ns"`
 	Title         string              `json:"title"`
-	Missing       any                 `json:"missing,omitempty"`
+	Missing       bool                `json:"missing,omitempty"`
 	Transcludedin []TranscludedinPage `json:"transcludedin,omitempty"`
 }
 
@@ -43,7 +43,7 @@ type TranscludedinPage struct {
 	Pageid   int       `json:"pageid"`
 	Ns       Namespace `json:"ns"`
 	Title    string    `json:"title"`
-	Redirect any       `json:"redirect"`
+	Redirect bool      `json:"redirect"`
 }
 
 type TranscludedinOption func(map[string]string)
@@ -138,7 +138,23 @@ func (w *TranscludedinClient) Titles(s ...string) *TranscludedinClient {
 	return w
 }
 
+// Validate checks that the pages whose transclusions to list are
+// given.
+func (w *TranscludedinClient) Validate() error {
+	parameters := Values{}
+	for _, o := range w.o {
+		o(parameters)
+	}
+
+	return checkParams(parameters,
+		required("titles"))
+}
+
 func (w *TranscludedinClient) Do(ctx context.Context) (TranscludedinResponse, error) {
+	if err := w.Validate(); err != nil {
+		return TranscludedinResponse{}, err
+	}
+
 	if err := w.c.checkKeepAlive(ctx); err != nil {
 		return TranscludedinResponse{}, err
 	}
@@ -1,13 +1,13 @@
 package mediawiki
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"mime/multipart"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -40,6 +40,14 @@ type UploadUploadResponse struct {
 	Warnings   *UploadUploadWarnings  `json:"warnings,omitempty"`
 	FileKey    string                 `json:"filekey,omitempty"`
 	SessionKey string                 `json:"sessionkey,omitempty"`
+
+	// Offset is the number of bytes of a chunked upload received so
+	// far, if Result is Continue.
+	Offset int64 `json:"offset,omitempty"`
+
+	// Stage is the step an asynchronous upload is at, such as queued,
+	// assembling or publish, if Result is Poll.
+	Stage string `json:"stage,omitempty"`
 }
 
 type UploadUploadWarnings struct {
@@ -50,6 +58,82 @@ type UploadUploadWarnings struct {
 	Duplicate        []string                      `json:"duplicate,omitempty"`
 	DuplicateArchive string                        `json:"duplicate-archive,omitempty"`
 	BadFileName      string                        `json:"badfilename,omitempty"`
+
+	// Other holds the warnings without a field, such as
+	// exists-normalized or large-file, keyed by code.
+	Other map[string]json.RawMessage `json:"-"`
+}
+
+func (w *UploadUploadWarnings) UnmarshalJSON(b []byte) error {
+	type fields UploadUploadWarnings
+	if err := json.Unmarshal(b, (*fields)(w)); err != nil {
+		return err
+	}
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(b, &all); err != nil {
+		return err
+	}
+
+	w.Other = nil
+	for code, v := range all {
+		switch code {
+		case UploadWarningExists, UploadWarningNoChange, UploadWarningDuplicateVersion, UploadWarningWasDeleted,
+			UploadWarningDuplicate, UploadWarningDuplicateArchive, UploadWarningBadFileName:
+			continue
+		}
+		if w.Other == nil {
+			w.Other = map[string]json.RawMessage{}
+		}
+		w.Other[code] = v
+	}
+
+	return nil
+}
+
+// Codes of the upload warnings.
+const (
+	UploadWarningExists           = "exists"
+	UploadWarningNoChange         = "nochange"
+	UploadWarningDuplicateVersion = "duplicate-version"
+	UploadWarningWasDeleted       = "was-deleted"
+	UploadWarningDuplicate        = "duplicate"
+	UploadWarningDuplicateArchive = "duplicate-archive"
+	UploadWarningBadFileName      = "badfilename"
+)
+
+// Codes returns the codes of the warnings, in the order of the fields,
+// followed by the codes of Other in alphabetical order.
+func (w *UploadUploadWarnings) Codes() []string {
+	if w == nil {
+		return nil
+	}
+
+	var codes []string
+	for _, c := range []struct {
+		code string
+		set  bool
+	}{
+		{UploadWarningExists, w.Exists != ""},
+		{UploadWarningNoChange, w.NoChange != nil},
+		{UploadWarningDuplicateVersion, w.DuplicateVersion != ""},
+		{UploadWarningWasDeleted, w.WasDeleted != ""},
+		{UploadWarningDuplicate, len(w.Duplicate) > 0},
+		{UploadWarningDuplicateArchive, w.DuplicateArchive != ""},
+		{UploadWarningBadFileName, w.BadFileName != ""},
+	} {
+		if c.set {
+			codes = append(codes, c.code)
+		}
+	}
+
+	other := make([]string, 0, len(w.Other))
+	for code := range w.Other {
+		other = append(other, code)
+	}
+	slices.Sort(other)
+
+	return append(codes, other...)
 }
 
 type UploadUploadWarningsNoChange struct {
@@ -69,12 +153,12 @@ type UploadUploadImageinfo struct {
 		DateTime *struct {
 			Value  *time.Time `json:"value,omitempty"`
 			Source string     `json:"source,omitempty"`
-			Hidden any        `json:"hidden,omitempty"`
+			Hidden bool       `json:"hidden,omitempty"`
 		} `json:"DateTime,omitempty"`
 		ObjectName *struct {
 			Value  string `json:"value,omitempty"`
 			Source string `json:"source,omitempty"`
-			Hidden any    `json:"hidden,omitempty"`
+			Hidden bool   `json:"hidden,omitempty"`
 		} `json:"ObjectName,omitempty"`
 	} `json:"extmetadata,omitempty"`
 	Height    int    `json:"height,omitempty"`
@@ -98,9 +182,18 @@ type UploadUploadImageinfo struct {
 type UploadOption func(map[string]string)
 
 type UploadClient struct {
-	o []UploadOption
-	c *Client
-	f io.Reader
+	o      []UploadOption
+	c      *Client
+	f      io.Reader
+	chunk  io.Reader
+	dryRun *bool
+
+	progress func(sent, total int64)
+
+	// Used by UploadLarge
+	chunkSize    int64
+	chunkRetries *int
+	pollInterval time.Duration
 }
 
 func (c *Client) Upload() *UploadClient {
@@ -158,9 +251,9 @@ func (w *UploadClient) Watch(b bool) *UploadClient {
 // Unconditionally add or remove the page from the current user's watchlist, use preferences (ignored for bot users) or do not change watch.
 // One of the following values: nochange, preferences, watch
 // Default: preferences
-func (w *UploadClient) Watchlist(s string) *UploadClient {
+func (w *UploadClient) Watchlist(s Watchlist) *UploadClient {
 	w.o = append(w.o, func(m map[string]string) {
-		m["watchlist"] = s
+		m["watchlist"] = string(s)
 	})
 	return w
 }
@@ -250,10 +343,17 @@ func (w *UploadClient) Offset(i int) *UploadClient {
 // Chunk
 // Chunk contents.
 // Must be posted as a file upload using multipart/form-data.
-func (w *UploadClient) Chunk(s string) *UploadClient {
-	w.o = append(w.o, func(m map[string]string) {
-		m["chunk"] = s
-	})
+func (w *UploadClient) Chunk(r io.Reader) *UploadClient {
+	w.chunk = r
+	return w
+}
+
+// Progress
+// Called as the file or chunk is sent, with the number of bytes sent so far
+// and the size of the file, or -1 if it is unknown. It is called from
+// the goroutine writing the request.
+func (w *UploadClient) Progress(f func(sent, total int64)) *UploadClient {
+	w.progress = f
 	return w
 }
 
@@ -284,7 +384,45 @@ func (w *UploadClient) Token(s string) *UploadClient {
 	return w
 }
 
+// DryRun
+// Record the request in the client's Plan instead of sending it.
+// Overrides Client.DryRun for this call.
+func (w *UploadClient) DryRun(b bool) *UploadClient {
+	w.dryRun = &b
+	return w
+}
+
+// Validate checks the parameters of the upload without sending it, and
+// returns all problems found. Do calls it before sending the upload.
+func (w *UploadClient) Validate() error {
+	parameters := Values{}
+	for _, o := range w.o {
+		o(parameters)
+	}
+
+	rules := []paramRule{
+		required("filename"),
+		oneOf("watchlist", string(WatchlistNoChange), string(WatchlistPreferences), string(WatchlistWatch)),
+	}
+	switch {
+	case w.f != nil && w.chunk != nil:
+		rules = append(rules, func(Values) error {
+			return errors.New("the file and the chunk can't both be sent")
+		})
+	case w.chunk != nil:
+		rules = append(rules, required("filesize"), required("offset"))
+	case w.f == nil:
+		rules = append(rules, exactlyOne("url", "filekey", "sessionkey"))
+	}
+
+	return checkParams(parameters, rules...)
+}
+
 func (w *UploadClient) Do(ctx context.Context) (UploadResponse, error) {
+	if err := w.Validate(); err != nil {
+		return UploadResponse{}, err
+	}
+
 	if err := w.c.checkKeepAlive(ctx); err != nil {
 		return UploadResponse{}, err
 	}
@@ -294,9 +432,6 @@ func (w *UploadClient) Do(ctx context.Context) (UploadResponse, error) {
 		return UploadResponse{}, err
 	}
 
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-
 	// Specify parameters to send.
 	parameters := Values{
 		"action": "upload",
@@ -308,61 +443,53 @@ func (w *UploadClient) Do(ctx context.Context) (UploadResponse, error) {
 		o(parameters)
 	}
 
-	for k, v := range parameters {
-		writer.WriteField(k, v)
+	field, file := "file", w.f
+	if w.chunk != nil {
+		field, file = "chunk", w.chunk
 	}
 
-	if w.f != nil {
-		part, _ := writer.CreateFormFile("file", parameters["filename"])
-		io.Copy(part, w.f)
-	}
+	// The body is streamed as it is sent. It is built once call has
+	// added any parameters of its own.
+	body := newUploadBody(parameters, field, parameters["filename"], file, w.progress)
 
-	writer.Close()
+	if w.c.isDryRun(w.dryRun) {
+		w.c.plan(ctx, parameters, body)
 
-	req, err := http.NewRequestWithContext(ctx, "POST", w.c.apiURL.String(), body)
-	if err != nil {
-		return UploadResponse{}, err
+		r := UploadResponse{Upload: &UploadUploadResponse{Result: Success, Filename: parameters["filename"]}}
+		simulate(&r.CoreResponse, r)
+		return r, nil
 	}
 
-	req.Header.Add("User-Agent", w.c.UserAgent)
-	req.Header.Add("Content-Type", writer.FormDataContentType())
+	b, status, err := w.c.call(ctx, parameters, func(ctx context.Context) (*http.Request, error) {
+		req, err := body.request(ctx, w.c.apiURL.String())
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Add("User-Agent", w.c.UserAgent)
 
-	resp, err := w.c.Client.Do(req)
+		return req, nil
+	})
 	if err != nil {
 		return UploadResponse{}, err
 	}
 
-	if resp.StatusCode >= 400 {
-		return UploadResponse{}, fmt.Errorf(resp.Status)
-	}
-
-	b, _ := io.ReadAll(resp.Body)
-	buf := &bytes.Buffer{}
-	json.Indent(buf, b, "", "  ")
-	j := buf.String()
-
-	if w.c.Debug != nil {
-		fmt.Fprintln(w.c.Debug, j)
+	if status >= 400 {
+		return UploadResponse{}, fmt.Errorf("%d %s", status, http.StatusText(status))
 	}
 
 	r := UploadResponse{}
-	err = ParseResponseReader(buf, &r)
+	j, err := w.c.decodeInto(ctx, b, &r)
 	r.RawJSON = j
 	if err != nil {
-		return r, fmt.Errorf("error parsing response: %w", err)
-	}
-
-	if w.c.Debug != nil {
-		fmt.Fprintln(w.c.Debug, "-----")
-		b, _ = json.MarshalIndent(r, "", "  ")
-		fmt.Fprintln(w.c.Debug, string(b))
+		return r, err
 	}
 
 	if e := r.Error; e != nil {
 		return r, fmt.Errorf("%s: %s", e.Code, e.Info)
 	} else if r.Upload == nil {
 		return r, fmt.Errorf("unexpected error in upload")
-	} else if r.Upload.Result != Success && r.Upload.Result != Warning {
+	} else if r.Upload.Result != Success && r.Upload.Result != Warning && r.Upload.Result != Continue && r.Upload.Result != Poll {
 		return r, fmt.Errorf("upload failure")
 	}
 
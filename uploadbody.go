@@ -0,0 +1,197 @@
+package mediawiki
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+)
+
+// uploadBody is the multipart/form-data body of an upload. It is
+// streamed to the server through a pipe as the request is sent, so that
+// the file is never held in memory.
+type uploadBody struct {
+	params Values
+
+	// field is the name of the part holding the file, which is read from
+	// r. r may be nil.
+	field, filename string
+	r               io.Reader
+
+	// size is the number of bytes left in r, or -1 if it is unknown.
+	// start is the position of r if it is an io.Seeker, or -1.
+	size, start int64
+
+	progress func(sent, total int64)
+	boundary string
+	sent     bool
+}
+
+func newUploadBody(params Values, field, filename string, r io.Reader, progress func(sent, total int64)) *uploadBody {
+	b := &uploadBody{
+		params:   params,
+		field:    field,
+		filename: filename,
+		r:        r,
+		size:     -1,
+		start:    -1,
+		progress: progress,
+		boundary: multipart.NewWriter(io.Discard).Boundary(),
+	}
+
+	switch r := r.(type) {
+	case nil:
+		b.size = 0
+	case io.Seeker:
+		cur, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			break
+		}
+		end, err := r.Seek(0, io.SeekEnd)
+		if err != nil {
+			break
+		}
+		if _, err := r.Seek(cur, io.SeekStart); err == nil {
+			b.size, b.start = end-cur, cur
+		}
+	case interface{ Len() int }:
+		b.size = int64(r.Len())
+	}
+
+	return b
+}
+
+// request returns a request sending the body to url. The file is read
+// again from its start for every request, which requires it to be an
+// io.Seeker.
+func (b *uploadBody) request(ctx context.Context, url string) (*http.Request, error) {
+	if b.sent && b.r != nil {
+		s, ok := b.r.(io.Seeker)
+		if !ok || b.start < 0 {
+			return nil, errors.New("the upload can't be sent again: the file isn't an io.Seeker")
+		}
+		if _, err := s.Seek(b.start, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("error rewinding file: %w", err)
+		}
+	}
+	b.sent = true
+
+	pr, pw := io.Pipe()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, pr)
+	if err != nil {
+		pr.Close()
+		return nil, err
+	}
+	req.ContentLength = b.length(ctx)
+	req.Header.Set("Content-Type", b.contentType())
+
+	// The pipe is closed by the transport once the request is done, which
+	// stops the writer if the request failed early.
+	go func() {
+		pw.CloseWithError(b.write(ctx, pw, true))
+	}()
+
+	return req, nil
+}
+
+// length returns the length of the body, or -1 if the size of the file
+// is unknown.
+func (b *uploadBody) length(ctx context.Context) int64 {
+	if b.size < 0 {
+		return -1
+	}
+
+	c := &countingWriter{}
+	if err := b.write(ctx, c, false); err != nil {
+		return -1
+	}
+
+	return c.n + b.size
+}
+
+func (b *uploadBody) contentType() string {
+	return "multipart/form-data; boundary=" + b.boundary
+}
+
+// write writes the body to w. If file is false, the contents of the file
+// are left out, to measure the rest of the body.
+func (b *uploadBody) write(ctx context.Context, w io.Writer, file bool) error {
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(b.boundary); err != nil {
+		return err
+	}
+
+	// The token is sent last, as in EncodeMultipart.
+	for _, k := range b.params.sortKeys() {
+		if k == "token" {
+			continue
+		}
+		if err := mw.WriteField(k, b.params[k]); err != nil {
+			return err
+		}
+	}
+	if t, ok := b.params["token"]; ok {
+		if err := mw.WriteField("token", t); err != nil {
+			return err
+		}
+	}
+
+	if b.r != nil {
+		part, err := mw.CreateFormFile(b.field, b.filename)
+		if err != nil {
+			return err
+		}
+
+		if file {
+			r := &progressReader{ctx: ctx, r: b.r, total: b.size, progress: b.progress}
+			if _, err := io.Copy(part, r); err != nil {
+				return err
+			}
+		}
+	}
+
+	return mw.Close()
+}
+
+// progressReader reads the file of an upload, reporting its progress and
+// stopping once the context is cancelled.
+type progressReader struct {
+	ctx      context.Context
+	r        io.Reader
+	sent     int64
+	total    int64
+	progress func(sent, total int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	if err := p.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.sent += int64(n)
+		if p.progress != nil {
+			p.progress(p.sent, p.total)
+		}
+	}
+
+	if err != nil && err != io.EOF {
+		return n, fmt.Errorf("error reading file: %w", err)
+	}
+
+	return n, err
+}
+
+// countingWriter counts the bytes written to it.
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	c.n += int64(len(b))
+	return len(b), nil
+}
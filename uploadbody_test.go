@@ -0,0 +1,185 @@
+package mediawiki
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/clockworksoul/mediawiki/internal/wikitest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newUploadServer returns a client of a wiki that passes uploads to
+// handle.
+func newUploadServer(t *testing.T, handle http.HandlerFunc) *Client {
+	t.Helper()
+
+	s := wikitest.New(t, map[string]http.HandlerFunc{"upload": handle})
+
+	c, err := New(s.URL, agent)
+	require.NoError(t, err)
+
+	return c
+}
+
+// uploadedFile returns the contents of the file part of an upload.
+func uploadedFile(t *testing.T, r *http.Request) []byte {
+	t.Helper()
+
+	require.NoError(t, r.ParseMultipartForm(1<<20))
+	f, _, err := r.FormFile("file")
+	require.NoError(t, err)
+	defer f.Close()
+
+	b, err := io.ReadAll(f)
+	require.NoError(t, err)
+
+	return b
+}
+
+const uploadSuccess = `{"upload":{"result":"Success","filename":"Foo.png"}}`
+
+func TestUploadStreaming(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 10000)
+
+	c := newUploadServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.EqualValues(t, -1, r.ContentLength)
+		assert.Equal(t, []string{"chunked"}, r.TransferEncoding)
+
+		assert.Equal(t, data, uploadedFile(t, r))
+		assert.Equal(t, "upload", r.FormValue("action"))
+		assert.Equal(t, "Foo.png", r.FormValue("filename"))
+		assert.Equal(t, `abc+\`, r.FormValue("token"))
+
+		w.Write([]byte(uploadSuccess))
+	})
+
+	var sent, total []int64
+	progress := func(s, t int64) {
+		sent = append(sent, s)
+		total = append(total, t)
+	}
+
+	// The size of the file is unknown.
+	r, err := c.Upload().Filename("Foo.png").File(io.MultiReader(bytes.NewReader(data))).Progress(progress).Do(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, Success, r.Upload.Result)
+	require.NotEmpty(t, sent)
+	assert.EqualValues(t, len(data), sent[len(sent)-1])
+	assert.EqualValues(t, -1, total[0])
+}
+
+func TestUploadContentLength(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 10000)
+
+	for name, f := range map[string]func() io.Reader{
+		"seeker": func() io.Reader {
+			r := bytes.NewReader(append([]byte("skipped"), data...))
+			r.Seek(7, io.SeekStart)
+			return r
+		},
+		"len": func() io.Reader { return bytes.NewBuffer(data) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := newUploadServer(t, func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				assert.EqualValues(t, len(body), r.ContentLength)
+				assert.Empty(t, r.TransferEncoding)
+
+				r.Body = io.NopCloser(bytes.NewReader(body))
+				assert.Equal(t, data, uploadedFile(t, r))
+
+				w.Write([]byte(uploadSuccess))
+			})
+
+			var total int64
+			_, err := c.Upload().Filename("Foo.png").File(f()).Progress(func(_, t int64) { total = t }).Do(context.Background())
+			require.NoError(t, err)
+			assert.EqualValues(t, len(data), total)
+		})
+	}
+}
+
+// failingReader returns err after n bytes.
+type failingReader struct {
+	n   int
+	err error
+}
+
+func (f *failingReader) Read(b []byte) (int, error) {
+	if f.n == 0 {
+		return 0, f.err
+	}
+
+	n := min(len(b), f.n)
+	f.n -= n
+	return n, nil
+}
+
+func TestUploadReadError(t *testing.T) {
+	c := newUploadServer(t, func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Write([]byte(uploadSuccess))
+	})
+
+	errDisk := errors.New("disk failure")
+	_, err := c.Upload().Filename("Foo.png").File(&failingReader{n: 50000, err: errDisk}).Do(context.Background())
+	require.Error(t, err)
+	assert.ErrorIs(t, err, errDisk)
+}
+
+func TestUploadCancel(t *testing.T) {
+	c := newUploadServer(t, func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Write([]byte(uploadSuccess))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The upload is cancelled once some of it has been sent.
+	f := &failingReader{n: 1 << 30, err: io.EOF}
+	_, err := c.Upload().Filename("Foo.png").File(f).Progress(func(sent, _ int64) {
+		if sent > 1<<20 {
+			cancel()
+		}
+	}).Do(ctx)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Greater(t, f.n, 0)
+}
+
+func TestUploadResend(t *testing.T) {
+	data := []byte("file contents")
+
+	calls := 0
+	c := newUploadServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		assert.Equal(t, data, uploadedFile(t, r))
+
+		if calls == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.Write([]byte(`{"error":{"code":"maxlag","info":"Waiting for a database server"}}`))
+			return
+		}
+		w.Write([]byte(uploadSuccess))
+	})
+	c.Maxlag.On = true
+
+	_, err := c.Upload().Filename("Foo.png").File(bytes.NewReader(data)).Do(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+
+	// A file that can't be rewound can't be sent again.
+	calls = 0
+	_, err = c.Upload().Filename("Foo.png").File(io.MultiReader(bytes.NewReader(data))).Do(context.Background())
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "io.Seeker")
+	assert.Equal(t, 1, calls)
+}
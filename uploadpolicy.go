@@ -0,0 +1,289 @@
+package mediawiki
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// WarningPolicy is what UploadWithPolicy does about an upload warning.
+type WarningPolicy string
+
+const (
+	// WarningFail stops the upload with an *UploadWarningError. If the
+	// file was sent, it is left in the stash, and calling
+	// UploadWithPolicy again with another policy publishes it without
+	// sending it again.
+	WarningFail WarningPolicy = "fail"
+
+	// WarningSkip leaves the file unpublished.
+	WarningSkip WarningPolicy = "skip"
+
+	// WarningOverwrite ignores the warning, so that an existing file of
+	// the same name gets a new version.
+	WarningOverwrite WarningPolicy = "overwrite"
+
+	// WarningRename publishes the file under another name: the name
+	// suggested by the wiki for a badfilename warning, or else the name
+	// with a numbered suffix.
+	WarningRename WarningPolicy = "rename"
+)
+
+// DefaultRenames is how many names WarningRename tries if
+// UploadPolicy.Renames is zero.
+const DefaultRenames = 10
+
+// UploadPolicy says what UploadWithPolicy does about each upload warning.
+type UploadPolicy struct {
+	// Warnings maps the codes of upload warnings, such as
+	// UploadWarningExists, UploadWarningDuplicate or "large-file", to
+	// their policy.
+	// Warnings that aren't listed use Default, or WarningFail if Default
+	// isn't set.
+	Warnings map[string]WarningPolicy
+	Default  WarningPolicy
+
+	// RenameSuffix is the format of the suffix WarningRename adds before
+	// the file extension, given the number of the attempt, starting at
+	// 2. If it is empty, " (%d)" is used, as in "Foo (2).jpg".
+	RenameSuffix string
+
+	// Renames is how many names WarningRename tries before failing, or
+	// DefaultRenames if it is zero.
+	Renames int
+}
+
+// policy returns the policy of the warning code.
+func (p UploadPolicy) policy(code string) WarningPolicy {
+	if wp, ok := p.Warnings[code]; ok {
+		return wp
+	}
+
+	return or(p.Default, WarningFail)
+}
+
+// resolve returns the policy applied to the warnings: the strictest of
+// their policies, failing before skipping, skipping before renaming and
+// renaming before overwriting. A warning without codes gets the default
+// policy, since nothing is known about it.
+func (p UploadPolicy) resolve(codes []string) WarningPolicy {
+	if len(codes) == 0 {
+		return or(p.Default, WarningFail)
+	}
+
+	order := []WarningPolicy{WarningFail, WarningSkip, WarningRename, WarningOverwrite}
+
+	resolved := WarningOverwrite
+	for _, c := range codes {
+		wp := p.policy(c)
+		i := slices.Index(order, wp)
+		if i < 0 {
+			i = 0
+		}
+		if i < slices.Index(order, resolved) {
+			resolved = order[i]
+		}
+	}
+
+	return resolved
+}
+
+// renamed returns the name with the numbered suffix of the policy.
+func (p UploadPolicy) renamed(name string, n int) string {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + fmt.Sprintf(or(p.RenameSuffix, " (%d)"), n) + ext
+}
+
+// UploadStatus is what became of a file uploaded by UploadWithPolicy.
+type UploadStatus string
+
+const (
+	UploadStatusUploaded UploadStatus = "uploaded"
+	UploadStatusRenamed  UploadStatus = "renamed"
+	UploadStatusSkipped  UploadStatus = "skipped"
+	UploadStatusFailed   UploadStatus = "failed"
+)
+
+// UploadOutcome is the result of UploadWithPolicy.
+type UploadOutcome struct {
+	Status UploadStatus
+
+	// Filename is the name the file was published under, or the name it
+	// was to be published under if it wasn't.
+	Filename string
+
+	// Sha1 is the SHA-1 of the file, in hexadecimal.
+	Sha1 string
+
+	// Duplicates are the names of the files of the wiki with the same
+	// contents.
+	Duplicates []string
+
+	// Warnings are the codes of the warnings that were resolved by the
+	// policy.
+	Warnings []string
+
+	// Filekey is the key of the stashed file if it wasn't published
+	// because of a warning. It is empty if the file wasn't sent.
+	Filekey string
+
+	// Response is the last response of the upload, if the file was sent.
+	Response UploadResponse
+}
+
+// UploadWarningError is returned by UploadWithPolicy if the policy of a
+// warning is WarningFail.
+type UploadWarningError struct {
+	Filename string
+	Warnings []string
+}
+
+func (e *UploadWarningError) Error() string {
+	return fmt.Sprintf("upload of %s: warnings %s", e.Filename, strings.Join(e.Warnings, ", "))
+}
+
+// UploadWithPolicy uploads the size bytes of r as the file name, and
+// applies the policy to the warnings of the upload.
+//
+// Before the file is sent, its SHA-1 is looked up with list=allimages.
+// If the wiki has files with the same contents, the policy of
+// UploadWarningDuplicate is applied, or of UploadWarningNoChange if the
+// file name is one of them, so that skipped duplicates are never sent.
+//
+// The file is then sent with UploadLarge, and published with the other
+// parameters of the client. If the wiki reports warnings, the policy is
+// applied to them and the stashed file published accordingly, without
+// sending it again. The strictest policy of the warnings applies.
+func (w *UploadClient) UploadWithPolicy(ctx context.Context, name string, r io.ReaderAt, size int64, p UploadPolicy) (UploadOutcome, error) {
+	out := UploadOutcome{Filename: name}
+
+	h := sha1.New()
+	if _, err := io.Copy(h, io.NewSectionReader(r, 0, size)); err != nil {
+		return out, fmt.Errorf("error reading file: %w", err)
+	}
+	out.Sha1 = hex.EncodeToString(h.Sum(nil))
+
+	var codes []string
+	err := w.c.Allimages().Sha1(out.Sha1).DoAll(ctx, func(r AllimagesResponse) error {
+		if r.Query == nil {
+			return nil
+		}
+		for _, f := range r.Query.Allimages {
+			if sameFileName(f.Name, name) {
+				codes = append(codes, UploadWarningNoChange)
+			} else {
+				out.Duplicates = append(out.Duplicates, f.Name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return out, err
+	}
+	if len(out.Duplicates) > 0 {
+		codes = append(codes, UploadWarningDuplicate)
+	}
+
+	if len(codes) > 0 {
+		out.Warnings = codes
+		switch p.resolve(codes) {
+		case WarningFail:
+			out.Status = UploadStatusFailed
+			return out, &UploadWarningError{Filename: name, Warnings: codes}
+		case WarningSkip:
+			out.Status = UploadStatusSkipped
+			return out, nil
+		}
+	}
+
+	res, err := w.UploadLarge(ctx, name, r, size)
+	out.Response = res
+	if err != nil {
+		return out, err
+	}
+
+	key := w.c.uploadStateKey(name, size)
+	renames := 0
+
+	for res.Upload.Result == Warning {
+		codes := res.Upload.Warnings.Codes()
+		out.Filekey = res.Upload.FileKey
+		out.Warnings = appendNew(out.Warnings, codes...)
+		if res.Upload.Warnings != nil {
+			out.Duplicates = appendNew(out.Duplicates, res.Upload.Warnings.Duplicate...)
+		}
+
+		c := w.publish(out.Filename, out.Filekey)
+
+		switch p.resolve(codes) {
+		case WarningFail:
+			out.Status = UploadStatusFailed
+			return out, &UploadWarningError{Filename: out.Filename, Warnings: codes}
+		case WarningSkip:
+			out.Status = UploadStatusSkipped
+			return out, w.c.uploadStates().Delete(key)
+		case WarningOverwrite:
+			c.Ignorewarnings(true)
+		case WarningRename:
+			if renames++; renames > or(p.Renames, DefaultRenames) {
+				out.Status = UploadStatusFailed
+				return out, &UploadWarningError{Filename: out.Filename, Warnings: codes}
+			}
+
+			if b := res.Upload.Warnings.BadFileName; b != "" && out.Filename != b {
+				out.Filename = b
+			} else {
+				out.Filename = p.renamed(name, renames+1)
+			}
+			c.Filename(out.Filename)
+		}
+
+		res, err = c.Do(ctx)
+		if err == nil {
+			res, err = w.wait(ctx, out.Filename, out.Filekey, res)
+		}
+		out.Response = res
+		if err != nil {
+			return out, err
+		}
+	}
+
+	out.Filekey = ""
+	out.Status = UploadStatusUploaded
+	if out.Filename != name {
+		out.Status = UploadStatusRenamed
+	}
+
+	return out, w.c.uploadStates().Delete(key)
+}
+
+// appendNew appends the values that aren't in s yet.
+func appendNew(s []string, v ...string) []string {
+	for _, x := range v {
+		if !slices.Contains(s, x) {
+			s = append(s, x)
+		}
+	}
+
+	return s
+}
+
+// sameFileName reports whether a and b name the same file, ignoring the
+// differences between spaces and underscores, and the case of the first
+// letter.
+func sameFileName(a, b string) bool {
+	norm := func(s string) string {
+		s = strings.ReplaceAll(strings.TrimSpace(s), " ", "_")
+		r, n := utf8.DecodeRuneInString(s)
+		return string(unicode.ToUpper(r)) + s[n:]
+	}
+
+	return norm(a) == norm(b)
+}
@@ -0,0 +1,209 @@
+package mediawiki
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadWithPolicy(t *testing.T) {
+	data := randomFile(t, 5000)
+	other := randomFile(t, 5000)
+
+	cases := []struct {
+		name      string
+		file      string
+		published map[string][]byte
+		policy    UploadPolicy
+
+		status     UploadStatus
+		filename   string
+		warnings   []string
+		duplicates []string
+		sent       bool
+		err        bool
+	}{
+		{
+			name:      "new file",
+			file:      "Foo.jpg",
+			published: map[string][]byte{"Bar.jpg": other},
+			status:    UploadStatusUploaded,
+			filename:  "Foo.jpg",
+			sent:      true,
+		},
+		{
+			name:       "duplicate skipped before sending",
+			file:       "Foo copy.jpg",
+			published:  map[string][]byte{"Foo 1.jpg": data, "Bar.jpg": other},
+			policy:     UploadPolicy{Warnings: map[string]WarningPolicy{UploadWarningDuplicate: WarningSkip}},
+			status:     UploadStatusSkipped,
+			filename:   "Foo copy.jpg",
+			warnings:   []string{UploadWarningDuplicate},
+			duplicates: []string{"Foo_1.jpg"},
+		},
+		{
+			name:      "same file skipped before sending",
+			file:      "foo.jpg",
+			published: map[string][]byte{"Foo.jpg": data},
+			policy:    UploadPolicy{Default: WarningSkip},
+			status:    UploadStatusSkipped,
+			filename:  "foo.jpg",
+			warnings:  []string{UploadWarningNoChange},
+		},
+		{
+			name:       "duplicate uploaded",
+			file:       "Foo copy.jpg",
+			published:  map[string][]byte{"Foo.jpg": data},
+			policy:     UploadPolicy{Warnings: map[string]WarningPolicy{UploadWarningDuplicate: WarningOverwrite}},
+			status:     UploadStatusUploaded,
+			filename:   "Foo copy.jpg",
+			warnings:   []string{UploadWarningDuplicate},
+			duplicates: []string{"Foo.jpg"},
+			sent:       true,
+		},
+		{
+			name:      "exists overwritten",
+			file:      "Foo.jpg",
+			published: map[string][]byte{"Foo.jpg": other},
+			policy:    UploadPolicy{Warnings: map[string]WarningPolicy{UploadWarningExists: WarningOverwrite}},
+			status:    UploadStatusUploaded,
+			filename:  "Foo.jpg",
+			warnings:  []string{UploadWarningExists},
+			sent:      true,
+		},
+		{
+			name:      "exists renamed",
+			file:      "Foo.jpg",
+			published: map[string][]byte{"Foo.jpg": other, "Foo (2).jpg": randomFile(t, 10)},
+			policy:    UploadPolicy{Warnings: map[string]WarningPolicy{UploadWarningExists: WarningRename}},
+			status:    UploadStatusRenamed,
+			filename:  "Foo (3).jpg",
+			warnings:  []string{UploadWarningExists},
+			sent:      true,
+		},
+		{
+			name:     "bad file name renamed",
+			file:     "Foo:Bar.jpg",
+			policy:   UploadPolicy{Default: WarningRename},
+			status:   UploadStatusRenamed,
+			filename: "Foo-Bar.jpg",
+			warnings: []string{UploadWarningBadFileName},
+			sent:     true,
+		},
+		{
+			name:      "too many renames",
+			file:      "Foo.jpg",
+			published: map[string][]byte{"Foo.jpg": other, "Foo (2).jpg": randomFile(t, 10)},
+			policy:    UploadPolicy{Default: WarningRename, Renames: 1},
+			status:    UploadStatusFailed,
+			filename:  "Foo (2).jpg",
+			warnings:  []string{UploadWarningExists},
+			sent:      true,
+			err:       true,
+		},
+		{
+			name:      "strictest policy",
+			file:      "Foo.jpg",
+			published: map[string][]byte{"Foo.jpg": other},
+			policy:    UploadPolicy{Warnings: map[string]WarningPolicy{UploadWarningExists: WarningSkip}, Default: WarningOverwrite},
+			status:    UploadStatusSkipped,
+			filename:  "Foo.jpg",
+			warnings:  []string{UploadWarningExists},
+			sent:      true,
+		},
+		{
+			name:     "unmapped warning fails",
+			file:     "DSC0001.jpg",
+			policy:   UploadPolicy{Warnings: map[string]WarningPolicy{UploadWarningExists: WarningOverwrite}},
+			status:   UploadStatusFailed,
+			filename: "DSC0001.jpg",
+			warnings: []string{"bad-prefix"},
+			sent:     true,
+			err:      true,
+		},
+		{
+			name:     "unmapped warning with a policy",
+			file:     "DSC0001.jpg",
+			policy:   UploadPolicy{Warnings: map[string]WarningPolicy{"bad-prefix": WarningOverwrite}},
+			status:   UploadStatusUploaded,
+			filename: "DSC0001.jpg",
+			warnings: []string{"bad-prefix"},
+			sent:     true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f, s := newStashServer(t)
+			for n, b := range tc.published {
+				f.published[n] = b
+			}
+
+			c, err := New(s.URL, agent)
+			require.NoError(t, err)
+
+			out, err := c.Upload().PollInterval(time.Millisecond).UploadWithPolicy(context.Background(), tc.file, bytes.NewReader(data), int64(len(data)), tc.policy)
+			if tc.err {
+				var werr *UploadWarningError
+				assert.ErrorAs(t, err, &werr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, tc.status, out.Status)
+			assert.Equal(t, tc.filename, out.Filename)
+			assert.Equal(t, tc.warnings, out.Warnings)
+			assert.Equal(t, tc.duplicates, out.Duplicates)
+			assert.Equal(t, fmt.Sprintf("%x", sha1.Sum(data)), out.Sha1)
+			assert.Equal(t, tc.sent, len(f.offsets) > 0)
+
+			if tc.status == UploadStatusUploaded || tc.status == UploadStatusRenamed {
+				assert.Equal(t, data, f.published[tc.filename])
+				assert.Empty(t, out.Filekey)
+			}
+		})
+	}
+}
+
+func TestUploadWithPolicyFail(t *testing.T) {
+	f, s := newStashServer(t)
+	data := randomFile(t, 5000)
+	f.published["Foo.jpg"] = randomFile(t, 5000)
+
+	c, err := New(s.URL, agent)
+	require.NoError(t, err)
+
+	out, err := c.Upload().PollInterval(time.Millisecond).UploadWithPolicy(context.Background(), "Foo.jpg", bytes.NewReader(data), int64(len(data)), UploadPolicy{})
+	var werr *UploadWarningError
+	require.ErrorAs(t, err, &werr)
+	assert.Equal(t, []string{UploadWarningExists}, werr.Warnings)
+	assert.Equal(t, UploadStatusFailed, out.Status)
+	assert.NotEmpty(t, out.Filekey)
+	assert.Equal(t, []int64{0}, f.offsets)
+
+	// The stashed file is published without being sent again.
+	out, err = c.Upload().PollInterval(time.Millisecond).UploadWithPolicy(context.Background(), "Foo.jpg", bytes.NewReader(data), int64(len(data)), UploadPolicy{Default: WarningOverwrite})
+	require.NoError(t, err)
+	assert.Equal(t, UploadStatusUploaded, out.Status)
+	assert.Equal(t, []int64{0}, f.offsets)
+	assert.Equal(t, data, f.published["Foo.jpg"])
+}
+
+func TestUploadWarningsCodes(t *testing.T) {
+	var w UploadUploadWarnings
+	require.NoError(t, json.Unmarshal([]byte(`{"exists":"Foo.jpg","thumb":"Foo.jpg","exists-normalized":"Foo.JPG"}`), &w))
+	assert.Equal(t, "Foo.jpg", w.Exists)
+	assert.Equal(t, []string{UploadWarningExists, "exists-normalized", "thumb"}, w.Codes())
+	assert.JSONEq(t, `"Foo.JPG"`, string(w.Other["exists-normalized"]))
+
+	// A warning nothing is known about isn't ignored.
+	assert.Equal(t, WarningFail, UploadPolicy{}.resolve(nil))
+	assert.Equal(t, WarningSkip, UploadPolicy{Default: WarningSkip}.resolve(nil))
+}
@@ -27,7 +27,7 @@ type UsersQuery struct {
 type UsersResponseUser struct {
 	UserId  int    `json:"userid,omitempty"`
 	Name    string `json:"name,omitempty"`
-	Missing any    `json:"missing,omitempty"`
+	Missing bool   `json:"missing,omitempty"`
 }
 
 type UsersClient struct {
@@ -84,7 +84,22 @@ func (w *UsersClient) Userids(i ...int) *UsersClient {
 	return w
 }
 
+// Validate checks that the users are given either by name or by ID.
+func (w *UsersClient) Validate() error {
+	parameters := Values{}
+	for _, o := range w.o {
+		o(parameters)
+	}
+
+	return checkParams(parameters,
+		exactlyOne("ususers", "ususerids"))
+}
+
 func (w *UsersClient) Do(ctx context.Context) (UsersResponse, error) {
+	if err := w.Validate(); err != nil {
+		return UsersResponse{}, err
+	}
+
 	if err := w.c.checkKeepAlive(ctx); err != nil {
 		return UsersResponse{}, err
 	}
@@ -23,7 +23,7 @@ func TestUsersStandard(t *testing.T) {
 	assert.Len(t, r.Query.Users, 1)
 	assert.Equal(t, "Mtitmus", r.Query.Users[0].Name)
 	assert.NotZero(t, r.Query.Users[0].UserId)
-	assert.Nil(t, r.Query.Users[0].Missing)
+	assert.False(t, r.Query.Users[0].Missing)
 
 	CompareJSON(t, r.RawJSON, r, false)
 }
@@ -43,7 +43,7 @@ func TestUsersMissing(t *testing.T) {
 	assert.Len(t, r.Query.Users, 1)
 	assert.Equal(t, "Nosuchuser", r.Query.Users[0].Name)
 	assert.Zero(t, r.Query.Users[0].UserId)
-	assert.NotNil(t, r.Query.Users[0].Missing)
+	assert.True(t, r.Query.Users[0].Missing)
 
 	CompareJSON(t, r.RawJSON, r, false)
 }
@@ -0,0 +1,98 @@
+package mediawiki
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// paramRule checks a set of request parameters for a single problem.
+type paramRule func(Values) error
+
+// checkParams applies every rule to v and returns all problems found,
+// joined into a single error, or nil if there are none.
+func checkParams(v Values, rules ...paramRule) error {
+	var errs []error
+
+	for _, r := range rules {
+		if err := r(v); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// exactlyOne requires that exactly one of keys is set.
+func exactlyOne(keys ...string) paramRule {
+	return func(v Values) error {
+		switch n := countSet(v, keys); {
+		case n == 0:
+			return fmt.Errorf("one of the parameters %s is required", strings.Join(keys, ", "))
+		case n > 1:
+			return fmt.Errorf("the parameters %s are mutually exclusive", strings.Join(keys, ", "))
+		}
+		return nil
+	}
+}
+
+// atLeastOne requires that at least one of keys is set.
+func atLeastOne(keys ...string) paramRule {
+	return func(v Values) error {
+		if countSet(v, keys) == 0 {
+			return fmt.Errorf("at least one of the parameters %s is required", strings.Join(keys, ", "))
+		}
+		return nil
+	}
+}
+
+// atMostOne requires that no more than one of keys is set.
+func atMostOne(keys ...string) paramRule {
+	return func(v Values) error {
+		if countSet(v, keys) > 1 {
+			return fmt.Errorf("the parameters %s are mutually exclusive", strings.Join(keys, ", "))
+		}
+		return nil
+	}
+}
+
+// oneOf requires that key, if set, has one of the given values.
+func oneOf(key string, values ...string) paramRule {
+	return func(v Values) error {
+		if v[key] != "" && !slices.Contains(values, v[key]) {
+			return fmt.Errorf("the parameter %s must be one of %s, not %q", key, strings.Join(values, ", "), v[key])
+		}
+		return nil
+	}
+}
+
+// required requires that key is set.
+func required(key string) paramRule {
+	return func(v Values) error {
+		if v[key] == "" {
+			return fmt.Errorf("the parameter %s is required", key)
+		}
+		return nil
+	}
+}
+
+// blankable are the parameters that are set even when empty, such as
+// the text that blanks a page.
+var blankable = map[string]bool{
+	"text":        true,
+	"appendtext":  true,
+	"prependtext": true,
+}
+
+// countSet returns the number of keys that are set: present, for
+// blankable parameters, and not empty otherwise.
+func countSet(v Values, keys []string) int {
+	n := 0
+	for _, k := range keys {
+		if _, ok := v[k]; (ok && blankable[k]) || v[k] != "" {
+			n++
+		}
+	}
+	return n
+}
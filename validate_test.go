@@ -0,0 +1,94 @@
+package mediawiki
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/clockworksoul/mediawiki/internal/wikitest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	c, err := New("http://localhost", agent)
+	require.NoError(t, err)
+
+	err = c.Edit().Title("Foo").PageId(1).Text("Bar").Undo("12").Watchlist("forever").Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the parameters title, pageid are mutually exclusive")
+	assert.Contains(t, err.Error(), "the parameters text, undo are mutually exclusive")
+	assert.Contains(t, err.Error(), `the parameter watchlist must be one of nochange, preferences, unwatch, watch, not "forever"`)
+
+	assert.NoError(t, c.Edit().Title("Foo").Text("Bar").Watchlist(WatchlistNoChange).ContentModel(ContentModelWikitext).Validate())
+
+	err = c.Protect().Title("Foo").
+		Protections(Protection{ProtectionEdit, ProtectionSysop}, Protection{ProtectionMove, ProtectionSysop}).
+		Expiry(ExpiryInfinite, "1 week", "1 month").
+		Validate()
+	assert.EqualError(t, err, "the parameter expiry must have 1 or 2 values, not 3")
+
+	assert.NoError(t, c.Protect().Title("Foo").
+		Protections(Protection{ProtectionEdit, ProtectionSysop}, Protection{ProtectionMove, ProtectionAll}).
+		Expiry(ExpiryAt(time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC))).
+		Validate())
+
+	assert.Error(t, c.Upload().Filename("Foo.jpg").Url("https://example.org/foo.jpg").Watchlist(WatchlistUnwatch).Validate())
+	assert.Error(t, c.Allpages().Filterredir("none").Validate())
+	assert.Error(t, c.Revisions().Titles("Foo").Dir(DirAscending).Validate())
+	assert.NoError(t, c.Allusers().Dir(AllusersDescending).Validate())
+
+	// Empty text blanks the page.
+	assert.NoError(t, c.Edit().Title("Foo").Text("").Validate())
+	assert.NoError(t, c.Edit().Title("Foo").AppendText("").Validate())
+	assert.Error(t, c.Edit().Title("Foo").Text("").Undo("12").Validate())
+
+	assert.Error(t, c.CategoryMembers().Validate())
+	assert.Error(t, c.CategoryMembers().Title("Category:Foo").PageId(1).Validate())
+	assert.Error(t, c.CategoryInfo().Titles("Foo").Pageids("1").Validate())
+	assert.Error(t, c.Imageinfo().Validate())
+	assert.Error(t, c.Images().Titles("Foo").Dir("up").Validate())
+	assert.Error(t, c.Linkshere().Validate())
+	assert.Error(t, c.Transcludedin().Validate())
+	assert.Error(t, c.Paraminfo().Modules("edit").Helpformat("text").Validate())
+	assert.Error(t, c.Siteinfo().Filteriw("remote").Validate())
+	assert.Error(t, c.Users().Validate())
+	assert.NoError(t, c.Users().Userids(1).Validate())
+
+	err = c.TemplateEdit("").Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the template name is required")
+	assert.Contains(t, err.Error(), "call Titles or Transclusions")
+	assert.Contains(t, err.Error(), "call Set, Rename, Remove or Func")
+	assert.NoError(t, c.TemplateEdit("Task").Titles("Foo").Remove("status").Validate())
+}
+
+func TestValidateBeforeSending(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request")
+	}))
+	defer s.Close()
+
+	c, err := New(s.URL, agent)
+	require.NoError(t, err)
+
+	_, err = c.Edit().Title("Foo").Do(context.Background())
+	assert.EqualError(t, err, "at least one of the parameters text, appendtext, prependtext, undo is required")
+
+	_, err = c.Allpages().Dir(DirNewer).Do(context.Background())
+	assert.Error(t, err)
+}
+
+func TestEditNotMinor(t *testing.T) {
+	s := wikitest.New(t, nil)
+
+	c, err := New(s.URL, agent)
+	require.NoError(t, err)
+	c.DryRun = true
+
+	_, err = c.Edit().Title("Foo").Text("Bar").NotMinor(true).Do(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "true", c.Plan.Requests()[0].Params["notminor"])
+}
@@ -0,0 +1,130 @@
+package mediawiki
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ResponseWarning is a warning returned by the API, such as the use of
+// a deprecated parameter or an unrecognized value.
+type ResponseWarning struct {
+	// Module is the path of the module that raised the warning, such as
+	// "main" or "query+revisions".
+	Module string `json:"module"`
+
+	// Code is the message key of the warning, such as
+	// "deprecation" or "unrecognizedparams". It is empty for warnings
+	// in the legacy format.
+	Code string `json:"code,omitempty"`
+
+	Text string `json:"text"`
+}
+
+func (w ResponseWarning) String() string {
+	if w.Code == "" {
+		return fmt.Sprintf("%s: %s", w.Module, w.Text)
+	}
+	return fmt.Sprintf("%s: %s: %s", w.Module, w.Code, w.Text)
+}
+
+// ResponseWarnings is the list of warnings of a response. It decodes
+// both the errorformat=plaintext list and the legacy object keyed by
+// module, which holds one warning per line.
+type ResponseWarnings []ResponseWarning
+
+// Has reports whether one of the warnings has the given code.
+func (ws ResponseWarnings) Has(code string) bool {
+	return slices.ContainsFunc(ws, func(w ResponseWarning) bool {
+		return w.Code == code
+	})
+}
+
+func (ws *ResponseWarnings) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+
+	if bytes.HasPrefix(b, []byte("[")) {
+		var list []struct {
+			ResponseWarning
+			Star string `json:"*"`
+		}
+		if err := json.Unmarshal(b, &list); err != nil {
+			return err
+		}
+
+		*ws = make(ResponseWarnings, 0, len(list))
+		for _, w := range list {
+			if w.Text == "" {
+				w.Text = w.Star
+			}
+			*ws = append(*ws, w.ResponseWarning)
+		}
+		return nil
+	}
+
+	var legacy map[string]struct {
+		Warnings string `json:"warnings"`
+		Star     string `json:"*"`
+	}
+	if err := json.Unmarshal(b, &legacy); err != nil {
+		return err
+	}
+
+	modules := make([]string, 0, len(legacy))
+	for m := range legacy {
+		modules = append(modules, m)
+	}
+	slices.Sort(modules)
+
+	*ws = ResponseWarnings{}
+	for _, m := range modules {
+		text := legacy[m].Warnings
+		if text == "" {
+			text = legacy[m].Star
+		}
+		for _, line := range strings.Split(text, "\n") {
+			if line != "" {
+				*ws = append(*ws, ResponseWarning{Module: m, Text: line})
+			}
+		}
+	}
+
+	return nil
+}
+
+// WarningError is returned when a response has warnings listed in the
+// WarningsAsErrors option of the client.
+type WarningError struct {
+	Warnings ResponseWarnings
+}
+
+func (e *WarningError) Error() string {
+	s := make([]string, len(e.Warnings))
+	for i, w := range e.Warnings {
+		s[i] = w.String()
+	}
+	return "warning: " + strings.Join(s, "; ")
+}
+
+// handleWarnings passes the warnings to OnWarning, and returns a
+// *WarningError with the ones listed in WarningsAsErrors.
+func (w *Client) handleWarnings(ctx context.Context, ws ResponseWarnings) error {
+	var fatal ResponseWarnings
+
+	for _, warning := range ws {
+		if w.OnWarning != nil {
+			w.OnWarning(ctx, warning)
+		}
+		if slices.Contains(w.WarningsAsErrors, "*") || slices.Contains(w.WarningsAsErrors, warning.Code) {
+			fatal = append(fatal, warning)
+		}
+	}
+
+	if len(fatal) > 0 {
+		return &WarningError{Warnings: fatal}
+	}
+	return nil
+}
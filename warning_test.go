@@ -0,0 +1,85 @@
+package mediawiki
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseWarningsUnmarshal(t *testing.T) {
+	var r CoreResponse
+	require.NoError(t, json.Unmarshal([]byte(`{"warnings":[
+		{"code":"unrecognizedparams","text":"Unrecognized parameter: foo.","module":"main"},
+		{"code":"deprecation","*":"The parameter \"rvdiffto\" is deprecated.","module":"query+revisions"}
+	]}`), &r))
+	assert.Equal(t, ResponseWarnings{
+		{Module: "main", Code: "unrecognizedparams", Text: "Unrecognized parameter: foo."},
+		{Module: "query+revisions", Code: "deprecation", Text: `The parameter "rvdiffto" is deprecated.`},
+	}, r.Warnings)
+	assert.True(t, r.Warnings.Has("deprecation"))
+	assert.False(t, r.Warnings.Has("badvalue"))
+
+	r = CoreResponse{}
+	require.NoError(t, json.Unmarshal([]byte(`{"warnings":{
+		"revisions":{"warnings":"First.\nSecond."},
+		"main":{"*":"Third."}
+	}}`), &r))
+	assert.Equal(t, ResponseWarnings{
+		{Module: "main", Text: "Third."},
+		{Module: "revisions", Text: "First."},
+		{Module: "revisions", Text: "Second."},
+	}, r.Warnings)
+}
+
+func TestResponseErrorsNormalize(t *testing.T) {
+	r := CoreResponse{}
+	require.NoError(t, json.Unmarshal([]byte(`{"errors":[{"code":"badtoken","text":"Invalid CSRF token.","module":"main"}]}`), &r))
+	r.normalize()
+	require.NotNil(t, r.Error)
+	assert.Equal(t, "badtoken", r.Error.Code)
+	assert.Equal(t, "Invalid CSRF token.", r.Error.Info)
+	assert.Equal(t, "main", r.Error.Module)
+
+	assert.Equal(t, "maxlag", peekErrorCode([]byte(`{"errors":[{"code":"maxlag","text":"Waiting."}]}`)))
+}
+
+func TestClientWarnings(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		assert.Equal(t, "plaintext", r.Form.Get("errorformat"))
+		w.Write([]byte(`{"warnings":[{"code":"deprecation","text":"Deprecated.","module":"query+allpages"}],"query":{"allpages":[]}}`))
+	}))
+	defer s.Close()
+
+	c, err := New(s.URL, agent)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	var got []ResponseWarning
+	c.OnWarning = func(ctx context.Context, w ResponseWarning) {
+		got = append(got, w)
+	}
+
+	r, err := c.Allpages().Do(ctx)
+	require.NoError(t, err)
+	assert.Len(t, r.Warnings, 1)
+	assert.Equal(t, []ResponseWarning{{Module: "query+allpages", Code: "deprecation", Text: "Deprecated."}}, got)
+
+	c.WarningsAsErrors = []string{"badvalue"}
+	_, err = c.Allpages().Do(ctx)
+	require.NoError(t, err)
+
+	c.WarningsAsErrors = []string{"deprecation"}
+	_, err = c.Allpages().Do(ctx)
+
+	var we *WarningError
+	require.True(t, errors.As(err, &we), "%v", err)
+	assert.Equal(t, "deprecation", we.Warnings[0].Code)
+	assert.Len(t, got, 3)
+}
@@ -0,0 +1,127 @@
+package wikitext
+
+// Section is a section of a document, as numbered by MediaWiki: section
+// 0 is the text before the first heading, and each heading starts a new
+// section that runs until the next heading of the same or a higher
+// level. Start and End are byte offsets into the document source; the
+// range includes the heading and any subsections.
+type Section struct {
+	Index   int
+	Level   int
+	Heading *Heading
+	Start   int
+	End     int
+}
+
+// Sections returns the sections of the document. Top-level headings and
+// headings inside tables start sections, as they do in MediaWiki;
+// headings inside templates or tags don't.
+func (d *Document) Sections() []Section {
+	type mark struct {
+		h   *Heading
+		off int
+	}
+
+	var marks []mark
+	var collect func(nodes Nodes, off int) int
+	collect = func(nodes Nodes, off int) int {
+		for _, n := range nodes {
+			switch t := n.(type) {
+			case *Heading:
+				marks = append(marks, mark{t, off})
+			case *Table:
+				collect(t.Body, off+len(t.Open))
+			}
+			off += len(n.String())
+		}
+		return off
+	}
+	total := collect(d.Nodes, 0)
+
+	lead := Section{Index: 0, End: total}
+	if len(marks) > 0 {
+		lead.End = marks[0].off
+	}
+
+	sections := []Section{lead}
+	for i, m := range marks {
+		s := Section{Index: i + 1, Level: m.h.Level, Heading: m.h, Start: m.off, End: total}
+		for _, next := range marks[i+1:] {
+			if next.h.Level <= m.h.Level {
+				s.End = next.off
+				break
+			}
+		}
+		sections = append(sections, s)
+	}
+
+	return sections
+}
+
+// Walk calls fn for every node in n and its descendants, depth first.
+// If fn returns false, the descendants of that node are skipped.
+func Walk(n Nodes, fn func(Node) bool) {
+	for _, e := range n {
+		if !fn(e) {
+			continue
+		}
+		for _, c := range children(e) {
+			Walk(c, fn)
+		}
+	}
+}
+
+// children returns the child node lists of n.
+func children(n Node) []Nodes {
+	switch t := n.(type) {
+	case *Heading:
+		return []Nodes{t.Title, t.Trailing}
+	case *Template:
+		c := []Nodes{t.Title}
+		for _, p := range t.Params {
+			c = append(c, p.Name, p.Value)
+		}
+		return c
+	case *ParserFunction:
+		return t.Args
+	case *Argument:
+		return []Nodes{t.Name, t.Default}
+	case *WikiLink:
+		return []Nodes{t.Target, t.Text}
+	case *Category:
+		return []Nodes{t.Target, t.Text}
+	case *ExternalLink:
+		return []Nodes{t.Text}
+	case *Tag:
+		return []Nodes{t.Body}
+	case *Table:
+		return []Nodes{t.Body}
+	}
+	return nil
+}
+
+// Templates returns every template in the document, including templates
+// nested inside other nodes, in document order.
+func (d *Document) Templates() []*Template {
+	var out []*Template
+	Walk(d.Nodes, func(n Node) bool {
+		if t, ok := n.(*Template); ok {
+			out = append(out, t)
+		}
+		return true
+	})
+	return out
+}
+
+// Categories returns every category link in the document, in document
+// order.
+func (d *Document) Categories() []*Category {
+	var out []*Category
+	Walk(d.Nodes, func(n Node) bool {
+		if c, ok := n.(*Category); ok {
+			out = append(out, c)
+		}
+		return true
+	})
+	return out
+}
@@ -0,0 +1,354 @@
+package wikitext
+
+import (
+	"strings"
+)
+
+// Node is an element of a parsed wikitext document. Every node keeps
+// the exact source it was parsed from, so String returns the original
+// wikitext for any node that hasn't been modified.
+type Node interface {
+	// String returns the wikitext source of the node.
+	String() string
+}
+
+// Nodes is a sequence of nodes.
+type Nodes []Node
+
+// String returns the concatenated wikitext source of all nodes.
+func (n Nodes) String() string {
+	b := &strings.Builder{}
+	for _, e := range n {
+		b.WriteString(e.String())
+	}
+	return b.String()
+}
+
+// Text returns the plain text of the nodes: the source with comments
+// removed.
+func (n Nodes) Text() string {
+	b := &strings.Builder{}
+	for _, e := range n {
+		if _, ok := e.(*Comment); !ok {
+			b.WriteString(e.String())
+		}
+	}
+	return b.String()
+}
+
+// Plain approximates the text MediaWiki would render for the nodes:
+// links are replaced by their label, bold and italic quotes, comments,
+// templates and parser functions are removed, and runs of whitespace
+// are collapsed. Templates can't be expanded without the wiki, so the
+// result is only exact for text that doesn't use them.
+func (n Nodes) Plain() string {
+	b := &strings.Builder{}
+	n.plain(b)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func (n Nodes) plain(b *strings.Builder) {
+	for _, e := range n {
+		switch t := e.(type) {
+		case *Text:
+			b.WriteString(quotes.Replace(t.Value))
+		case *WikiLink:
+			if t.HasText {
+				t.Text.plain(b)
+			} else {
+				b.WriteString(strings.TrimPrefix(strings.TrimSpace(t.Target.Text()), ":"))
+			}
+		case *ExternalLink:
+			if !t.Bracketed {
+				b.WriteString(t.URL)
+			} else {
+				t.Text.plain(b)
+			}
+		case *Tag:
+			t.Body.plain(b)
+		case *Heading:
+			t.Title.plain(b)
+		case *Table:
+			t.Body.plain(b)
+		}
+	}
+}
+
+// quotes removes bold and italic markup.
+var quotes = strings.NewReplacer("'''''", "", "'''", "", "''", "")
+
+// Text is a run of wikitext with no special meaning to the parser.
+type Text struct {
+	Value string
+}
+
+func (t *Text) String() string {
+	return t.Value
+}
+
+// Comment is an HTML comment: <!-- Value -->. A comment that is never
+// closed runs to the end of the document and has Unclosed set.
+type Comment struct {
+	Value    string
+	Unclosed bool
+}
+
+func (c *Comment) String() string {
+	if c.Unclosed {
+		return "<!--" + c.Value
+	}
+	return "<!--" + c.Value + "-->"
+}
+
+// Heading is a section heading such as "== Title ==". Trailing holds any
+// whitespace and comments between the closing equals signs and the end
+// of the line.
+type Heading struct {
+	Level    int
+	Title    Nodes
+	Trailing Nodes
+}
+
+func (h *Heading) String() string {
+	eq := strings.Repeat("=", h.Level)
+	return eq + h.Title.String() + eq + h.Trailing.String()
+}
+
+// Text returns the heading title without surrounding whitespace and
+// comments.
+func (h *Heading) Text() string {
+	return strings.TrimSpace(h.Title.Text())
+}
+
+// Template is a template transclusion: {{Title|param|name=value}}.
+type Template struct {
+	Title  Nodes
+	Params []*Parameter
+}
+
+func (t *Template) String() string {
+	b := &strings.Builder{}
+	b.WriteString("{{")
+	b.WriteString(t.Title.String())
+	for _, p := range t.Params {
+		b.WriteByte('|')
+		b.WriteString(p.String())
+	}
+	b.WriteString("}}")
+	return b.String()
+}
+
+// Name returns the normalized name of the template: comments and
+// surrounding whitespace removed, underscores replaced by spaces and
+// the first letter capitalized. A leading "Template:" prefix is kept.
+func (t *Template) Name() string {
+	return normalizeName(t.Title.Text())
+}
+
+// Parameter is a single template parameter. Named parameters have the
+// form Name=Value; positional parameters have no Name and Named unset.
+// Name and Value keep their surrounding whitespace.
+type Parameter struct {
+	Named bool
+	Name  Nodes
+	Value Nodes
+}
+
+func (p *Parameter) String() string {
+	if p.Named {
+		return p.Name.String() + "=" + p.Value.String()
+	}
+	return p.Value.String()
+}
+
+// ParserFunction is a parser function call such as {{#if:x|y|z}} or
+// {{lc:X}}. Name is the function name as written, including the '#'
+// and any leading whitespace. Args[0] is the text after the colon.
+type ParserFunction struct {
+	Name string
+	Args []Nodes
+}
+
+func (f *ParserFunction) String() string {
+	b := &strings.Builder{}
+	b.WriteString("{{")
+	b.WriteString(f.Name)
+	b.WriteByte(':')
+	for i, a := range f.Args {
+		if i > 0 {
+			b.WriteByte('|')
+		}
+		b.WriteString(a.String())
+	}
+	b.WriteString("}}")
+	return b.String()
+}
+
+// Argument is a template argument reference: {{{name|default}}}.
+type Argument struct {
+	Name       Nodes
+	Default    Nodes
+	HasDefault bool
+}
+
+func (a *Argument) String() string {
+	if a.HasDefault {
+		return "{{{" + a.Name.String() + "|" + a.Default.String() + "}}}"
+	}
+	return "{{{" + a.Name.String() + "}}}"
+}
+
+// WikiLink is an internal link: [[Target|Text]].
+type WikiLink struct {
+	Target  Nodes
+	Text    Nodes
+	HasText bool
+}
+
+func (l *WikiLink) String() string {
+	if l.HasText {
+		return "[[" + l.Target.String() + "|" + l.Text.String() + "]]"
+	}
+	return "[[" + l.Target.String() + "]]"
+}
+
+// Category is a category link such as [[Category:Foo|sort key]]. The
+// sort key, if any, is held in Text.
+type Category struct {
+	WikiLink
+}
+
+// Name returns the category name without its namespace prefix.
+func (c *Category) Name() string {
+	t := c.Target.Text()
+	if i := strings.IndexByte(t, ':'); i >= 0 {
+		t = t[i+1:]
+	}
+	return normalizeName(t)
+}
+
+// ExternalLink is a link to an external URL, either bracketed
+// ([http://example.org Text]) or bare (http://example.org). Space is the
+// whitespace separating the URL from the text in a bracketed link.
+type ExternalLink struct {
+	URL       string
+	Space     string
+	Text      Nodes
+	Bracketed bool
+}
+
+func (l *ExternalLink) String() string {
+	if !l.Bracketed {
+		return l.URL
+	}
+	return "[" + l.URL + l.Space + l.Text.String() + "]"
+}
+
+// Tag is an extension or parser tag such as <ref>, <nowiki> or <pre>.
+// Open and Close are the opening and closing tags exactly as written.
+// The body of tags whose content is not wikitext (nowiki, pre, math and
+// the like) is a single Text node.
+type Tag struct {
+	Name        string
+	Open        string
+	Body        Nodes
+	Close       string
+	SelfClosing bool
+}
+
+func (t *Tag) String() string {
+	if t.SelfClosing {
+		return t.Open
+	}
+	return t.Open + t.Body.String() + t.Close
+}
+
+// Attr returns the value of the named attribute of the opening tag, and
+// whether it is present.
+func (t *Tag) Attr(name string) (string, bool) {
+	v, ok := parseAttrs(t.Open)[strings.ToLower(name)]
+	return v, ok
+}
+
+// Table is a wikitext table. Open is the "{|" line including any
+// attributes, and Close is "|}", or empty if the table is never closed.
+// Body holds everything in between, starting with the newline that ends
+// the Open line.
+type Table struct {
+	Open  string
+	Body  Nodes
+	Close string
+}
+
+func (t *Table) String() string {
+	return t.Open + t.Body.String() + t.Close
+}
+
+// normalizeName normalizes a page or template name the way MediaWiki
+// does for first-letter-case wikis.
+func normalizeName(s string) string {
+	s = strings.ReplaceAll(s, "_", " ")
+	s = strings.Join(strings.Fields(s), " ")
+	if s == "" {
+		return s
+	}
+
+	r := []rune(s)
+	r[0] = []rune(strings.ToUpper(string(r[0])))[0]
+	return string(r)
+}
+
+// parseAttrs parses the attributes of an opening tag.
+func parseAttrs(open string) map[string]string {
+	m := map[string]string{}
+
+	s := strings.TrimPrefix(open, "<")
+	s = strings.TrimSuffix(s, ">")
+	s = strings.TrimSuffix(s, "/")
+
+	// Skip the tag name.
+	i := strings.IndexAny(s, " \t\n")
+	if i < 0 {
+		return m
+	}
+	s = s[i:]
+
+	for {
+		s = strings.TrimLeft(s, " \t\n")
+		if s == "" {
+			return m
+		}
+
+		j := strings.IndexAny(s, "= \t\n")
+		if j < 0 {
+			m[strings.ToLower(s)] = ""
+			return m
+		}
+
+		name := strings.ToLower(s[:j])
+		s = strings.TrimLeft(s[j:], " \t\n")
+		if !strings.HasPrefix(s, "=") {
+			m[name] = ""
+			continue
+		}
+		s = strings.TrimLeft(s[1:], " \t\n")
+
+		var val string
+		if s != "" && (s[0] == '"' || s[0] == '\'') {
+			q := s[0]
+			if k := strings.IndexByte(s[1:], q); k >= 0 {
+				val, s = s[1:k+1], s[k+2:]
+			} else {
+				val, s = s[1:], ""
+			}
+		} else {
+			k := strings.IndexAny(s, " \t\n")
+			if k < 0 {
+				k = len(s)
+			}
+			val, s = s[:k], s[k:]
+		}
+
+		m[name] = val
+	}
+}
@@ -0,0 +1,841 @@
+// Package wikitext parses MediaWiki wikitext into an editable syntax
+// tree and serializes it back.
+//
+// The parser is lossless: every node keeps the exact source it was
+// parsed from, so Parse(s).String() == s for any input, and regions of a
+// document that aren't modified are written back byte for byte.
+//
+// The parser follows the structure of MediaWiki's preprocessor rather
+// than its renderer. Templates, parser functions, template arguments,
+// wikilinks, comments and extension tags are recognized everywhere;
+// headings, tables and external links are recognized where the
+// preprocessor would not split them, so a table inside a template
+// parameter that uses bare pipes is left as text, exactly as MediaWiki
+// would split it.
+package wikitext
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Options configures the parser.
+type Options struct {
+	// CategoryPrefixes lists the local names and aliases of the
+	// category namespace, such as "Category" or "Kategorie". Matching is
+	// case-insensitive. If empty, only "Category" is recognized.
+	CategoryPrefixes []string
+}
+
+// Document is a parsed wikitext document.
+type Document struct {
+	Nodes Nodes
+}
+
+// String returns the wikitext source of the document.
+func (d *Document) String() string {
+	return d.Nodes.String()
+}
+
+// Parse parses s using the default options.
+func Parse(s string) *Document {
+	return ParseWith(s, Options{})
+}
+
+// ParseWith parses s using the given options.
+func ParseWith(s string, o Options) *Document {
+	cats := o.CategoryPrefixes
+	if len(cats) == 0 {
+		cats = []string{"Category"}
+	}
+
+	p := &parser{s: s, memo: map[memoKey]memoResult{}, dead: map[string]map[int]bool{}, raw: map[string]rawClose{}}
+	for _, c := range cats {
+		p.categories = append(p.categories, strings.ToLower(strings.ReplaceAll(c, "_", " ")))
+	}
+
+	return &Document{Nodes: p.scan(nil, "", nil)}
+}
+
+// stopFunc reports whether the parser has reached the end of the
+// construct it is currently parsing.
+type stopFunc func(p *parser) bool
+
+type parser struct {
+	s          string
+	pos        int
+	categories []string
+
+	// memo caches the result of parsing a context-independent construct
+	// at a given position. Without it, unclosed constructs make the
+	// parser backtrack exponentially.
+	memo map[memoKey]memoResult
+
+	// dead holds the positions from which the scans of a construct are
+	// known to fail, and raw the closing tags found by the searches for
+	// raw tags. Without them, every unclosed construct scans the rest of
+	// the input again, and parsing is quadratic.
+	dead map[string]map[int]bool
+	raw  map[string]rawClose
+
+	// inLinkText is set while parsing the text of a bracketed external
+	// link, which can't contain another one.
+	inLinkText bool
+}
+
+type memoKey struct {
+	kind byte
+	pos  int
+}
+
+type memoResult struct {
+	node Node
+	end  int
+}
+
+// A trail records the positions a scan passes through. Scans of the
+// same kind, which is named after their stop condition, are
+// deterministic: if one made its construct fail for a reason that only
+// depends on the rest of the input, such as reaching its end, any later
+// scan of the same kind that reaches one of these positions fails as
+// well.
+type trail struct {
+	kind string
+
+	// runs are the runs of consecutive positions, as [start, end).
+	runs [][2]int
+
+	// dead are the dead positions of the kind. It is nil if the kind
+	// had none when last looked up, at which point kinds other kinds
+	// had some.
+	dead  map[int]bool
+	kinds int
+}
+
+// visit records pos in the trail, and reports whether it is dead.
+func (t *trail) visit(p *parser, pos int) bool {
+	if t.dead == nil && t.kinds != len(p.dead) {
+		t.dead, t.kinds = p.dead[t.kind], len(p.dead)
+	}
+	if t.dead[pos] {
+		return true
+	}
+
+	if n := len(t.runs); n > 0 && t.runs[n-1][1] == pos {
+		t.runs[n-1][1]++
+	} else {
+		t.runs = append(t.runs, [2]int{pos, pos + 1})
+	}
+	return false
+}
+
+// fail marks the positions of the trails as dead.
+func (p *parser) fail(trails ...*trail) {
+	for _, t := range trails {
+		dead := p.dead[t.kind]
+		if dead == nil {
+			dead = map[int]bool{}
+			p.dead[t.kind] = dead
+		}
+		for _, r := range t.runs {
+			for pos := r[0]; pos < r[1]; pos++ {
+				dead[pos] = true
+			}
+		}
+	}
+}
+
+// rawClose is the closing tag found by searching for the end of a raw
+// tag from a position. start and end are -1 if there is none.
+type rawClose struct {
+	from, start, end int
+}
+
+// cached returns the result of parse at the current position, parsing
+// only on the first call for each kind and position. parse must not
+// depend on the outer stop condition.
+func (p *parser) cached(kind byte, parse func() Node) Node {
+	k := memoKey{kind, p.pos}
+	if r, ok := p.memo[k]; ok {
+		if r.node != nil {
+			p.pos = r.end
+		}
+		return r.node
+	}
+
+	inLinkText := p.inLinkText
+	p.inLinkText = false
+	n := parse()
+	p.inLinkText = inLinkText
+
+	p.memo[k] = memoResult{n, p.pos}
+
+	return n
+}
+
+// stopAt returns a stopFunc that stops at any of the given tokens.
+func stopAt(tokens ...string) stopFunc {
+	return func(p *parser) bool {
+		for _, t := range tokens {
+			if strings.HasPrefix(p.s[p.pos:], t) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// either returns a stopFunc that stops when a or b does. Either may be
+// nil.
+func either(a, b stopFunc) stopFunc {
+	if b == nil {
+		return a
+	}
+	return func(p *parser) bool {
+		return a(p) || b(p)
+	}
+}
+
+// scan parses nodes until stop reports true or the input ends. kind
+// names the stop condition, including those of any outer scans it
+// depends on. If t isn't nil, the positions of the scan are recorded in
+// it, and the scan skips to the end of the input if it reaches a dead
+// position of its kind.
+func (p *parser) scan(stop stopFunc, kind string, t *trail) Nodes {
+	var out Nodes
+	text := &strings.Builder{}
+
+	flush := func() {
+		if text.Len() > 0 {
+			out = append(out, &Text{Value: text.String()})
+			text.Reset()
+		}
+	}
+
+	if t != nil {
+		t.kind = kind
+	}
+
+	for p.pos < len(p.s) {
+		if t != nil && t.visit(p, p.pos) {
+			p.pos = len(p.s)
+			break
+		}
+
+		if stop != nil && stop(p) {
+			break
+		}
+
+		if n := p.parseConstruct(stop, kind); n != nil {
+			flush()
+			out = append(out, n)
+			continue
+		}
+
+		text.WriteByte(p.s[p.pos])
+		p.pos++
+	}
+
+	flush()
+
+	return out
+}
+
+// parseConstruct attempts to parse a construct at the current position,
+// within a scan of the given kind. On failure it returns nil and leaves
+// the position unchanged.
+func (p *parser) parseConstruct(outer stopFunc, kind string) Node {
+	s := p.s[p.pos:]
+
+	switch {
+	case strings.HasPrefix(s, "<!--"):
+		return p.parseComment()
+	case strings.HasPrefix(s, "{{{"):
+		if n := p.cached('a', p.parseArgument); n != nil {
+			return n
+		}
+		return p.cached('t', p.parseTemplate)
+	case strings.HasPrefix(s, "{{"):
+		return p.cached('t', p.parseTemplate)
+	case strings.HasPrefix(s, "[["):
+		return p.cached('l', p.parseWikiLink)
+	case s[0] == '[' && !p.inLinkText:
+		return p.parseExternalLink(outer, kind)
+	case s[0] == '<':
+		return p.cached('<', p.parseTag)
+	case s[0] == '=' && p.atLineStart():
+		return p.parseHeading(outer, kind)
+	case strings.HasPrefix(s, "{|") && p.atLineStartIgnoringSpace():
+		return p.parseTable(outer, kind)
+	}
+
+	if p.pos == 0 || !isWordByte(p.s[p.pos-1]) {
+		return p.parseBareURL()
+	}
+
+	return nil
+}
+
+func (p *parser) atLineStart() bool {
+	return p.pos == 0 || p.s[p.pos-1] == '\n'
+}
+
+func (p *parser) atLineStartIgnoringSpace() bool {
+	i := p.pos
+	for i > 0 && (p.s[i-1] == ' ' || p.s[i-1] == '\t') {
+		i--
+	}
+	return i == 0 || p.s[i-1] == '\n'
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.s)
+}
+
+func (p *parser) parseComment() Node {
+	start := p.pos + len("<!--")
+
+	end := strings.Index(p.s[start:], "-->")
+	if end < 0 {
+		p.pos = len(p.s)
+		return &Comment{Value: p.s[start:], Unclosed: true}
+	}
+
+	p.pos = start + end + len("-->")
+	return &Comment{Value: p.s[start : start+end]}
+}
+
+func (p *parser) parseTemplate() Node {
+	start := p.pos
+	p.pos += len("{{")
+
+	stop := stopAt("|", "}}")
+	t := &trail{}
+
+	title := p.scan(stop, "t", t)
+	if p.eof() {
+		p.fail(t)
+		p.pos = start
+		return nil
+	}
+	if strings.TrimSpace(title.Text()) == "" {
+		p.pos = start
+		return nil
+	}
+
+	var args []Nodes
+	for p.s[p.pos] == '|' {
+		p.pos++
+		args = append(args, p.scan(stop, "t", t))
+		if p.eof() {
+			p.fail(t)
+			p.pos = start
+			return nil
+		}
+	}
+
+	p.pos += len("}}")
+
+	if name, first, ok := splitFunction(title); ok {
+		return &ParserFunction{Name: name, Args: append([]Nodes{first}, args...)}
+	}
+
+	tmpl := &Template{Title: title}
+	for _, a := range args {
+		tmpl.Params = append(tmpl.Params, newParameter(a))
+	}
+
+	return tmpl
+}
+
+func (p *parser) parseArgument() Node {
+	start := p.pos
+	p.pos += len("{{{")
+
+	name, def := &trail{}, &trail{}
+
+	a := &Argument{Name: p.scan(stopAt("|", "}}}"), "a", name)}
+	if p.eof() {
+		p.fail(name)
+		p.pos = start
+		return nil
+	}
+
+	if p.s[p.pos] == '|' {
+		p.pos++
+		a.HasDefault = true
+		a.Default = p.scan(stopAt("}}}"), "a|", def)
+		if p.eof() {
+			p.fail(name, def)
+			p.pos = start
+			return nil
+		}
+	}
+
+	p.pos += len("}}}")
+
+	return a
+}
+
+func (p *parser) parseWikiLink() Node {
+	start := p.pos
+	p.pos += len("[[")
+
+	target, text := &trail{}, &trail{}
+
+	l := WikiLink{Target: p.scan(stopAt("|", "]]", "\n"), "l", target)}
+	if p.eof() || p.s[p.pos] == '\n' {
+		p.fail(target)
+		p.pos = start
+		return nil
+	}
+	if strings.TrimSpace(l.Target.Text()) == "" {
+		p.pos = start
+		return nil
+	}
+
+	if p.s[p.pos] == '|' {
+		p.pos++
+		l.HasText = true
+		l.Text = p.scan(stopAt("]]"), "l|", text)
+		if p.eof() {
+			p.fail(target, text)
+			p.pos = start
+			return nil
+		}
+	}
+
+	p.pos += len("]]")
+
+	if p.isCategory(l.Target.Text()) {
+		return &Category{WikiLink: l}
+	}
+
+	return &l
+}
+
+// isCategory reports whether a link to target adds a category rather
+// than linking to one.
+func (p *parser) isCategory(target string) bool {
+	target = strings.TrimLeft(target, " \t")
+	i := strings.IndexByte(target, ':')
+	if i < 0 {
+		return false
+	}
+
+	ns := strings.ToLower(strings.Join(strings.Fields(strings.ReplaceAll(target[:i], "_", " ")), " "))
+	for _, c := range p.categories {
+		if ns == c {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *parser) parseExternalLink(outer stopFunc, kind string) Node {
+	start := p.pos
+	p.pos++
+
+	n := urlLength(p.s[p.pos:], true)
+	if n == 0 {
+		p.pos = start
+		return nil
+	}
+
+	l := &ExternalLink{URL: p.s[p.pos : p.pos+n], Bracketed: true}
+	p.pos += n
+
+	for !p.eof() && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t') {
+		l.Space += string(p.s[p.pos])
+		p.pos++
+	}
+
+	text := &trail{}
+
+	p.inLinkText = true
+	l.Text = p.scan(either(stopAt("]", "\n"), outer), "["+kind, text)
+	p.inLinkText = false
+	if p.eof() || p.s[p.pos] != ']' {
+		p.fail(text)
+		p.pos = start
+		return nil
+	}
+
+	p.pos++
+
+	return l
+}
+
+func (p *parser) parseBareURL() Node {
+	n := urlLength(p.s[p.pos:], false)
+	if n == 0 {
+		return nil
+	}
+
+	u := p.s[p.pos : p.pos+n]
+
+	// Trailing punctuation is not part of a bare URL.
+	u = strings.TrimRight(u, ",;.:!?")
+	if strings.HasSuffix(u, ")") && !strings.Contains(u, "(") {
+		u = strings.TrimRight(u, ")")
+	}
+
+	if hasProtocol(u) == len(u) {
+		return nil
+	}
+
+	p.pos += len(u)
+
+	return &ExternalLink{URL: u}
+}
+
+var tagOpen = regexp.MustCompile(`^<([A-Za-z][A-Za-z0-9]*)(\s[^<>]*?)?(/?)>`)
+
+func (p *parser) parseTag() Node {
+	m := tagOpen.FindStringSubmatch(p.s[p.pos:])
+	if m == nil {
+		return nil
+	}
+
+	name := strings.ToLower(m[1])
+	raw, known := tags[name]
+	if !known {
+		return nil
+	}
+
+	start := p.pos
+	t := &Tag{Name: m[1], Open: m[0]}
+	p.pos += len(m[0])
+
+	if m[3] == "/" {
+		t.SelfClosing = true
+		return t
+	}
+
+	closing := regexp.MustCompile(`(?i)^</` + regexp.QuoteMeta(name) + `\s*>`)
+
+	if raw {
+		c := p.rawClose(name)
+		if c.start < 0 {
+			p.pos = start
+			return nil
+		}
+
+		if c.start > p.pos {
+			t.Body = Nodes{&Text{Value: p.s[p.pos:c.start]}}
+		}
+		t.Close = p.s[c.start:c.end]
+		p.pos = c.end
+
+		return t
+	}
+
+	body := &trail{}
+	t.Body = p.scan(func(p *parser) bool {
+		return p.s[p.pos] == '<' && closing.MatchString(p.s[p.pos:])
+	}, "<"+name, body)
+	if p.eof() {
+		p.fail(body)
+		p.pos = start
+		return nil
+	}
+
+	t.Close = closing.FindString(p.s[p.pos:])
+	p.pos += len(t.Close)
+
+	return t
+}
+
+// rawClose returns the first closing tag of the raw tag name after the
+// current position. The last search for each name is remembered, since
+// the search from any position up to the closing tag it found, or from
+// any position after it found none, has the same result.
+func (p *parser) rawClose(name string) rawClose {
+	if c, ok := p.raw[name]; ok && p.pos >= c.from && (c.start < 0 || p.pos <= c.start) {
+		return c
+	}
+
+	c := rawClose{from: p.pos, start: -1, end: -1}
+	if loc := regexp.MustCompile(`(?i)</` + regexp.QuoteMeta(name) + `\s*>`).FindStringIndex(p.s[p.pos:]); loc != nil {
+		c.start, c.end = p.pos+loc[0], p.pos+loc[1]
+	}
+	p.raw[name] = c
+
+	return c
+}
+
+func (p *parser) parseHeading(outer stopFunc, kind string) Node {
+	start := p.pos
+
+	open := 0
+	for !p.eof() && p.s[p.pos] == '=' {
+		open++
+		p.pos++
+	}
+
+	inner := p.scan(either(stopAt("\n"), outer), "="+kind, nil)
+	if !p.eof() && p.s[p.pos] != '\n' {
+		p.pos = start
+		return nil
+	}
+
+	// Separate trailing whitespace and comments.
+	var trailing Nodes
+	for len(inner) > 0 {
+		last := inner[len(inner)-1]
+
+		if _, ok := last.(*Comment); ok {
+			trailing = append(Nodes{last}, trailing...)
+			inner = inner[:len(inner)-1]
+			continue
+		}
+
+		t, ok := last.(*Text)
+		if !ok {
+			break
+		}
+
+		v := strings.TrimRight(t.Value, " \t")
+		if v == "" {
+			trailing = append(Nodes{last}, trailing...)
+			inner = inner[:len(inner)-1]
+			continue
+		}
+
+		if v != t.Value {
+			trailing = append(Nodes{&Text{Value: t.Value[len(v):]}}, trailing...)
+			inner[len(inner)-1] = &Text{Value: v}
+		}
+
+		break
+	}
+
+	var last *Text
+	if len(inner) > 0 {
+		last, _ = inner[len(inner)-1].(*Text)
+	}
+	if last == nil {
+		p.pos = start
+		return nil
+	}
+
+	close := len(last.Value) - len(strings.TrimRight(last.Value, "="))
+	if close == 0 {
+		p.pos = start
+		return nil
+	}
+
+	level := min(open, close, 6)
+
+	// Any unbalanced equals signs belong to the title.
+	title := append(Nodes{}, inner[:len(inner)-1]...)
+	if v := last.Value[:len(last.Value)-level]; v != "" {
+		title = append(title, &Text{Value: v})
+	}
+	if extra := strings.Repeat("=", open-level); extra != "" {
+		if t, ok := first(title).(*Text); ok {
+			title[0] = &Text{Value: extra + t.Value}
+		} else {
+			title = append(Nodes{&Text{Value: extra}}, title...)
+		}
+	}
+
+	return &Heading{Level: level, Title: title, Trailing: trailing}
+}
+
+func (p *parser) parseTable(outer stopFunc, kind string) Node {
+	start := p.pos
+
+	end := strings.IndexByte(p.s[p.pos:], '\n')
+	if end < 0 {
+		end = len(p.s) - p.pos
+	}
+
+	t := &Table{Open: p.s[p.pos : p.pos+end]}
+	p.pos += end
+
+	closing := func(p *parser) bool {
+		return strings.HasPrefix(p.s[p.pos:], "|}") && p.atLineStartIgnoringSpace()
+	}
+
+	t.Body = p.scan(either(closing, outer), "{|"+kind, nil)
+
+	switch {
+	case p.eof():
+		// MediaWiki closes unterminated tables at the end of the page.
+	case closing(p):
+		t.Close = "|}"
+		p.pos += len(t.Close)
+	default:
+		p.pos = start
+		return nil
+	}
+
+	return t
+}
+
+// newParameter splits the template argument a into a name and value at
+// the first top-level equals sign.
+func newParameter(a Nodes) *Parameter {
+	for i, n := range a {
+		t, ok := n.(*Text)
+		if !ok {
+			continue
+		}
+
+		j := strings.IndexByte(t.Value, '=')
+		if j < 0 {
+			continue
+		}
+
+		name := append(Nodes{}, a[:i]...)
+		if t.Value[:j] != "" {
+			name = append(name, &Text{Value: t.Value[:j]})
+		}
+
+		var value Nodes
+		if t.Value[j+1:] != "" {
+			value = append(value, &Text{Value: t.Value[j+1:]})
+		}
+		value = append(value, a[i+1:]...)
+
+		return &Parameter{Named: true, Name: name, Value: value}
+	}
+
+	return &Parameter{Value: a}
+}
+
+// splitFunction reports whether title is the title of a parser function
+// call and, if so, splits it into the function name and first argument.
+func splitFunction(title Nodes) (string, Nodes, bool) {
+	t, ok := first(title).(*Text)
+	if !ok {
+		return "", nil, false
+	}
+
+	i := strings.IndexByte(t.Value, ':')
+	if i < 0 {
+		return "", nil, false
+	}
+
+	name := t.Value[:i]
+	if !isFunctionName(strings.TrimSpace(name)) {
+		return "", nil, false
+	}
+
+	var arg Nodes
+	if t.Value[i+1:] != "" {
+		arg = append(arg, &Text{Value: t.Value[i+1:]})
+	}
+	arg = append(arg, title[1:]...)
+
+	return name, arg, true
+}
+
+func isFunctionName(s string) bool {
+	if strings.HasPrefix(s, "#") {
+		return len(s) > 1
+	}
+	return functions[strings.ToLower(s)]
+}
+
+func first(n Nodes) Node {
+	if len(n) == 0 {
+		return nil
+	}
+	return n[0]
+}
+
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c >= 0x80
+}
+
+// hasProtocol returns the length of the URL protocol s starts with, or
+// 0 if it doesn't start with one.
+func hasProtocol(s string) int {
+	if len(s) < 2 || !strings.ContainsRune("fghimnorstwx/", rune(s[0]|0x20)) {
+		return 0
+	}
+
+	for _, pr := range protocols {
+		if len(s) >= len(pr) && strings.EqualFold(s[:len(pr)], pr) {
+			return len(pr)
+		}
+	}
+
+	return 0
+}
+
+// urlLength returns the length of the URL s starts with. Protocol
+// relative URLs are only allowed in bracketed links.
+func urlLength(s string, bracketed bool) int {
+	n := hasProtocol(s)
+	if n == 0 || !bracketed && strings.HasPrefix(s, "//") {
+		return 0
+	}
+
+	end := strings.IndexAny(s[n:], " \t\n[]<>\"{}|")
+	if end < 0 {
+		end = len(s) - n
+	}
+	if end == 0 {
+		return 0
+	}
+
+	return n + end
+}
+
+var protocols = []string{
+	"https://", "http://", "ftps://", "ftp://", "sftp://", "ssh://",
+	"git://", "svn://", "irc://", "ircs://", "gopher://", "telnet://",
+	"nntp://", "worldwind://", "mms://", "redis://", "mailto:", "news:",
+	"xmpp:", "sip:", "sips:", "sms:", "tel:", "urn:", "geo:", "magnet:",
+	"matrix:", "//",
+}
+
+// tags maps the name of each recognized tag to whether its content is
+// raw text rather than wikitext.
+var tags = map[string]bool{
+	"ref":             false,
+	"references":      false,
+	"poem":            false,
+	"includeonly":     false,
+	"noinclude":       false,
+	"onlyinclude":     false,
+	"indicator":       false,
+	"nowiki":          true,
+	"pre":             true,
+	"math":            true,
+	"chem":            true,
+	"ce":              true,
+	"syntaxhighlight": true,
+	"source":          true,
+	"templatedata":    true,
+	"templatestyles":  true,
+	"score":           true,
+	"graph":           true,
+	"timeline":        true,
+	"hiero":           true,
+	"mapframe":        true,
+	"maplink":         true,
+	"inputbox":        true,
+	"categorytree":    true,
+	"imagemap":        true,
+	"gallery":         true,
+	"charinsert":      true,
+}
+
+// functions lists the parser functions that don't start with '#'.
+var functions = map[string]bool{
+	"lc": true, "uc": true, "lcfirst": true, "ucfirst": true,
+	"urlencode": true, "anchorencode": true, "fullurl": true, "fullurle": true,
+	"localurl": true, "localurle": true, "canonicalurl": true, "canonicalurle": true,
+	"filepath": true, "ns": true, "nse": true, "formatnum": true,
+	"padleft": true, "padright": true, "plural": true, "grammar": true,
+	"gender": true, "int": true, "msg": true, "msgnw": true, "raw": true,
+	"defaultsort": true, "defaultsortkey": true, "defaultcategorysort": true,
+	"displaytitle": true, "pagesincategory": true, "pagesize": true,
+	"protectionlevel": true, "protectionexpiry": true, "numberingroup": true,
+	"tag": true, "bidi": true, "special": true, "speciale": true,
+}
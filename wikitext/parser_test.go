@@ -0,0 +1,367 @@
+package wikitext
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sample = `{{Infobox person
+| name        = Ada Lovelace <!-- full name -->
+| birth_date  = {{birth date|1815|12|10}}
+| known_for   = [[Analytical Engine|the engine]]
+}}
+'''Ada Lovelace''' was a mathematician.<ref name="bio">{{cite book|title=Ada|url=http://example.org/a?b=c}}</ref>
+
+== Early life ==
+She was born in [[London]].<ref name="bio" /> See [http://example.org the site] or http://example.com/x.
+
+=== Education === <!-- trailing -->
+{| class="wikitable"
+|-
+! Subject !! Tutor
+|-
+| Maths || {{#if:{{{tutor|}}}|{{{tutor}}}|De Morgan}}
+|}
+
+<nowiki>{{not a template}}</nowiki>
+<pre>
+[[not a link]]
+</pre>
+
+==Legacy==
+{{lc:FOO}} {{subst:Foo}} {{{1|default}}}
+
+[[Category:Mathematicians|Lovelace, Ada]]
+[[Category:1815 births]]
+[[:Category:Not a member]]
+<!-- unclosed comment`
+
+func TestParseRoundTrip(t *testing.T) {
+	inputs := []string{
+		"",
+		"plain text",
+		sample,
+		"{{",
+		"}}",
+		"{{foo",
+		"{{foo|bar",
+		"[[foo",
+		"[[foo|bar",
+		"[[\n]]",
+		"[http://x",
+		"[http://x\nfoo]",
+		"<ref>unclosed",
+		"<nowiki>unclosed",
+		"<ref name=a/>",
+		"== unbalanced ===\n",
+		"=== unbalanced ==\n",
+		"==\n",
+		"=\n",
+		"{|\n| unclosed table",
+		"{{foo|\n{|\n| a || b\n|}\n}}",
+		"{{{{{a}}}}}",
+		"{{{a|b|c}}}",
+		"{{ #if: a | b | c }}",
+		"{{foo|a=b=c|=d|e}}",
+		"[[File:X.jpg|thumb|A [[link]] in a caption]]",
+		"{{foo|[http://x a|b]}}",
+		"http://example.org/path_(disambiguation), done.",
+		"<REF>upper</Ref >",
+		"==A==<!--c-->  \nText",
+		"text<!-- a --><!-- b -->",
+	}
+
+	for _, in := range inputs {
+		d := Parse(in)
+		assert.Equal(t, in, d.String(), "round trip of %q", in)
+	}
+}
+
+func TestParseUnclosed(t *testing.T) {
+	// Unclosed constructs must not make the parser backtrack
+	// exponentially.
+	for _, open := range []string{"{{", "{{{", "[[", "{{a|", "{{{a|", "[[a|", "[http://x ", "<ref>", "{{#if:"} {
+		in := strings.Repeat(open, 500)
+		assert.Equal(t, in, Parse(in).String())
+	}
+}
+
+// unclosed are inputs made of constructs that are never closed, each
+// of which used to make the parser scan the rest of the input again.
+var unclosed = []string{"{{{{{", "x {{ y\n", "{{{a|", "[[a|", "[[a\n", "<ref>", "[http://x a ", "{|\n[http://x "}
+
+func TestParseUnclosedLinear(t *testing.T) {
+	for _, open := range unclosed {
+		in := strings.Repeat(open, 20000)
+
+		done := make(chan string, 1)
+		go func() { done <- Parse(in).String() }()
+
+		select {
+		case got := <-done:
+			assert.Equal(t, in, got, "%q", open)
+		case <-time.After(10 * time.Second):
+			t.Fatalf("parsing %q repeated 20000 times took over 10s", open)
+		}
+	}
+}
+
+func BenchmarkParseUnclosed(b *testing.B) {
+	for _, open := range unclosed {
+		in := strings.Repeat(open, 10000)
+		b.Run(strings.TrimSpace(open), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				Parse(in)
+			}
+		})
+	}
+}
+
+func FuzzParseRoundTrip(f *testing.F) {
+	f.Add(sample)
+	f.Add("{{a|b=[[c|{{d}}]]}}")
+	f.Add("== x ==\n{|\n|}\n")
+
+	f.Fuzz(func(t *testing.T, s string) {
+		if got := Parse(s).String(); got != s {
+			t.Fatalf("round trip of %q produced %q", s, got)
+		}
+	})
+}
+
+func TestParseTemplate(t *testing.T) {
+	d := Parse(sample)
+
+	tpls := d.Templates()
+	require.NotEmpty(t, tpls)
+
+	infobox := tpls[0]
+	assert.Equal(t, "Infobox person", infobox.Name())
+	require.Len(t, infobox.Params, 3)
+
+	p := infobox.Params[0]
+	assert.True(t, p.Named)
+	assert.Equal(t, " name        ", p.Name.String())
+	assert.Equal(t, " Ada Lovelace <!-- full name -->\n", p.Value.String())
+	assert.Equal(t, " Ada Lovelace \n", p.Value.Text())
+
+	birth, ok := infobox.Params[1].Value[1].(*Template)
+	require.True(t, ok)
+	assert.Equal(t, "Birth date", birth.Name())
+	require.Len(t, birth.Params, 3)
+	assert.False(t, birth.Params[0].Named)
+	assert.Equal(t, "1815", birth.Params[0].Value.String())
+
+	link, ok := infobox.Params[2].Value[1].(*WikiLink)
+	require.True(t, ok)
+	assert.Equal(t, "Analytical Engine", link.Target.String())
+	assert.Equal(t, "the engine", link.Text.String())
+
+	var names []string
+	for _, tpl := range tpls {
+		names = append(names, tpl.Name())
+	}
+	assert.Equal(t, []string{"Infobox person", "Birth date", "Cite book", "Subst:Foo"}, names)
+}
+
+func TestParseParameterSplit(t *testing.T) {
+	d := Parse("{{foo|a=b=c|=d|e|{{bar|x=y}}}}")
+	tpl := d.Nodes[0].(*Template)
+	require.Len(t, tpl.Params, 4)
+
+	assert.Equal(t, "a", tpl.Params[0].Name.String())
+	assert.Equal(t, "b=c", tpl.Params[0].Value.String())
+	assert.True(t, tpl.Params[1].Named)
+	assert.Equal(t, "", tpl.Params[1].Name.String())
+	assert.False(t, tpl.Params[2].Named)
+	assert.False(t, tpl.Params[3].Named, "= inside a nested template doesn't name the parameter")
+}
+
+func TestParseParserFunction(t *testing.T) {
+	d := Parse("{{ #if: a | b | c }}{{lc:FOO}}")
+	require.Len(t, d.Nodes, 2)
+
+	f, ok := d.Nodes[0].(*ParserFunction)
+	require.True(t, ok)
+	assert.Equal(t, " #if", f.Name)
+	require.Len(t, f.Args, 3)
+	assert.Equal(t, " a ", f.Args[0].String())
+
+	lc, ok := d.Nodes[1].(*ParserFunction)
+	require.True(t, ok)
+	assert.Equal(t, "lc", lc.Name)
+}
+
+func TestParseArgument(t *testing.T) {
+	d := Parse("{{{1|default}}}{{{name}}}")
+	require.Len(t, d.Nodes, 2)
+
+	a := d.Nodes[0].(*Argument)
+	assert.Equal(t, "1", a.Name.String())
+	assert.True(t, a.HasDefault)
+	assert.Equal(t, "default", a.Default.String())
+
+	b := d.Nodes[1].(*Argument)
+	assert.False(t, b.HasDefault)
+}
+
+func TestParseHeadings(t *testing.T) {
+	cc := []struct {
+		In    string
+		Level int
+		Title string
+		Trail string
+	}{
+		{"== A ==\n", 2, " A ", ""},
+		{"==A== \n", 2, "A", " "},
+		{"=== A ==\n", 2, "= A ", ""},
+		{"== A ===\n", 2, " A =", ""},
+		{"======= A =======\n", 6, "= A =", ""},
+		{"== A == <!-- x -->\n", 2, " A ", " <!-- x -->"},
+		{"== [[Link]] ==", 2, " [[Link]] ", ""},
+	}
+
+	for _, c := range cc {
+		d := Parse(c.In)
+		h, ok := d.Nodes[0].(*Heading)
+		require.True(t, ok, c.In)
+		assert.Equal(t, c.Level, h.Level, c.In)
+		assert.Equal(t, c.Title, h.Title.String(), c.In)
+		assert.Equal(t, c.Trail, h.Trailing.String(), c.In)
+	}
+
+	for _, in := range []string{"== A\n", "text == A ==\n", "== A == x\n", "{{foo|\n== A ==}}"} {
+		d := Parse(in)
+		Walk(d.Nodes, func(n Node) bool {
+			_, ok := n.(*Heading)
+			assert.False(t, ok, "%q must not contain a heading", in)
+			return true
+		})
+	}
+}
+
+func TestParseSections(t *testing.T) {
+	src := "Lead\n== A ==\na\n=== A1 ===\na1\n== B ==\nb\n"
+	d := Parse(src)
+
+	ss := d.Sections()
+	require.Len(t, ss, 4)
+
+	assert.Equal(t, "Lead\n", src[ss[0].Start:ss[0].End])
+	assert.Equal(t, "== A ==\na\n=== A1 ===\na1\n", src[ss[1].Start:ss[1].End])
+	assert.Equal(t, "=== A1 ===\na1\n", src[ss[2].Start:ss[2].End])
+	assert.Equal(t, "== B ==\nb\n", src[ss[3].Start:ss[3].End])
+
+	assert.Equal(t, 3, ss[2].Level)
+	assert.Equal(t, "A1", ss[2].Heading.Text())
+
+	// Headings inside tables start sections, but not those inside
+	// templates.
+	src = "a\n{|\n|-\n== H ==\n|}\n{{x|\n== T ==\n}}\n== B ==\nx"
+	ss = Parse(src).Sections()
+	require.Len(t, ss, 3)
+	assert.Equal(t, "a\n{|\n|-\n", src[ss[0].Start:ss[0].End])
+	assert.Equal(t, "H", ss[1].Heading.Text())
+	assert.Equal(t, "== H ==\n|}\n{{x|\n== T ==\n}}\n", src[ss[1].Start:ss[1].End])
+	assert.Equal(t, "B", ss[2].Heading.Text())
+	assert.Equal(t, "== B ==\nx", src[ss[2].Start:ss[2].End])
+}
+
+func TestParseLinks(t *testing.T) {
+	d := Parse(sample)
+
+	cats := d.Categories()
+	require.Len(t, cats, 2)
+	assert.Equal(t, "Mathematicians", cats[0].Name())
+	assert.Equal(t, "Lovelace, Ada", cats[0].Text.String())
+	assert.Equal(t, "1815 births", cats[1].Name())
+
+	var ext []*ExternalLink
+	Walk(d.Nodes, func(n Node) bool {
+		if l, ok := n.(*ExternalLink); ok {
+			ext = append(ext, l)
+		}
+		return true
+	})
+	require.Len(t, ext, 3)
+	assert.Equal(t, "http://example.org/a?b=c", ext[0].URL)
+	assert.False(t, ext[0].Bracketed)
+	assert.Equal(t, "http://example.org", ext[1].URL)
+	assert.Equal(t, "the site", ext[1].Text.String())
+	assert.Equal(t, "http://example.com/x", ext[2].URL)
+
+	de := ParseWith("[[Kategorie:Foo]][[Category:Bar]]", Options{CategoryPrefixes: []string{"Kategorie"}})
+	require.Len(t, de.Categories(), 1)
+	assert.Equal(t, "Foo", de.Categories()[0].Name())
+}
+
+func TestParseTags(t *testing.T) {
+	d := Parse(`<ref name="a b" group=note>{{cite}}</ref><ref name=c /><nowiki>{{x}}</nowiki><div>{{y}}</div>`)
+
+	ref := d.Nodes[0].(*Tag)
+	assert.Equal(t, "ref", ref.Name)
+	v, ok := ref.Attr("name")
+	assert.True(t, ok)
+	assert.Equal(t, "a b", v)
+	v, _ = ref.Attr("group")
+	assert.Equal(t, "note", v)
+	_, ok = ref.Body[0].(*Template)
+	assert.True(t, ok)
+
+	self := d.Nodes[1].(*Tag)
+	assert.True(t, self.SelfClosing)
+
+	nowiki := d.Nodes[2].(*Tag)
+	require.Len(t, nowiki.Body, 1)
+	_, ok = nowiki.Body[0].(*Text)
+	assert.True(t, ok)
+
+	// Unknown tags are left as text.
+	_, ok = d.Nodes[3].(*Text)
+	assert.True(t, ok)
+	_, ok = d.Nodes[4].(*Template)
+	assert.True(t, ok)
+}
+
+func TestParseTables(t *testing.T) {
+	d := Parse("{| class=\"wikitable\"\n| {{a}} || b\n{|\n| nested\n|}\n|}\nafter")
+
+	tbl, ok := d.Nodes[0].(*Table)
+	require.True(t, ok)
+	assert.Equal(t, `{| class="wikitable"`, tbl.Open)
+	assert.Equal(t, "|}", tbl.Close)
+
+	var nested int
+	Walk(tbl.Body, func(n Node) bool {
+		if _, ok := n.(*Table); ok {
+			nested++
+		}
+		return true
+	})
+	assert.Equal(t, 1, nested)
+
+	// Inside a template, bare pipes split parameters, so no table.
+	d = Parse("{{foo|\n{|\n| a\n|}\n}}")
+	tpl := d.Nodes[0].(*Template)
+	assert.Len(t, tpl.Params, 4)
+}
+
+func TestModifyPreservesRest(t *testing.T) {
+	d := Parse(sample)
+
+	tpl := d.Templates()[0]
+	tpl.Params[0].Value = Nodes{&Text{Value: " Augusta Ada King\n"}}
+
+	out := d.String()
+	assert.Contains(t, out, "| name        = Augusta Ada King\n| birth_date")
+	assert.Equal(t, len(sample)-len(" Ada Lovelace <!-- full name -->\n")+len(" Augusta Ada King\n"), len(out))
+}
+
+func TestPlain(t *testing.T) {
+	d := Parse("'''Bold''' [[a|label]] [[:Category:C]] {{tpl}}<!-- x --> [http://x site]  http://y <ref>note</ref>")
+	assert.Equal(t, "Bold label Category:C site http://y note", d.Nodes.Plain())
+}
@@ -0,0 +1,226 @@
+package wikitext
+
+import (
+	"strconv"
+	"strings"
+)
+
+// key returns the name MediaWiki uses to look up the parameter: the
+// trimmed name of a named parameter, or the position of a positional
+// one. Positions are counted from 1 among positional parameters only.
+func (t *Template) key(i int) string {
+	p := t.Params[i]
+	if p.Named {
+		return strings.TrimSpace(p.Name.Text())
+	}
+
+	n := 0
+	for _, q := range t.Params[:i+1] {
+		if !q.Named {
+			n++
+		}
+	}
+	return strconv.Itoa(n)
+}
+
+// index returns the index of the parameter MediaWiki would use for
+// name, which is the last one if the name is repeated, or -1.
+func (t *Template) index(name string) int {
+	name = strings.TrimSpace(name)
+	for i := len(t.Params) - 1; i >= 0; i-- {
+		if t.key(i) == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Param returns the parameter with the given name or position, or nil.
+func (t *Template) Param(name string) *Parameter {
+	if i := t.index(name); i >= 0 {
+		return t.Params[i]
+	}
+	return nil
+}
+
+// Get returns the value of the parameter with the given name or
+// position, and whether it is present. Like MediaWiki, it trims the
+// values of named parameters but not positional ones.
+func (t *Template) Get(name string) (string, bool) {
+	p := t.Param(name)
+	if p == nil {
+		return "", false
+	}
+	if p.Named {
+		return strings.TrimSpace(p.Value.String()), true
+	}
+	return p.Value.String(), true
+}
+
+// Set sets the parameter with the given name or position to value,
+// which is parsed as wikitext; a bare '|' in value starts a new
+// parameter when the template is reparsed.
+//
+// An existing named parameter keeps the whitespace around its value. A
+// new parameter is appended and copies the layout of the last named
+// parameter, including any alignment of the equals signs.
+func (t *Template) Set(name, value string) {
+	name = strings.TrimSpace(name)
+
+	if p := t.Param(name); p != nil {
+		if p.Named {
+			lead, trail := spaceAround(p.Value.String())
+			if lead == "" && strings.TrimSpace(p.Value.String()) == "" {
+				lead = t.valueLead()
+			}
+			p.Value = wrap(lead, value, trail)
+			return
+		}
+
+		// A positional value with an equals sign would be read as a
+		// named parameter.
+		if strings.Contains(value, "=") {
+			p.Named = true
+			p.Name = Nodes{&Text{Value: name}}
+		}
+		p.Value = Parse(value).Nodes
+		return
+	}
+
+	p := &Parameter{Named: true, Name: Nodes{&Text{Value: name}}, Value: Parse(value).Nodes}
+
+	if ref := t.lastNamed(); ref != nil {
+		lead, trail := spaceAround(ref.Name.String())
+		if w := t.nameWidth(); w > 0 && trail != "" && strings.Trim(trail, " ") == "" {
+			trail = strings.Repeat(" ", max(w-len(lead)-len(name), 1))
+		}
+		p.Name = Nodes{&Text{Value: lead + name + trail}}
+
+		_, trail = spaceAround(ref.Value.String())
+		p.Value = wrap(t.valueLead(), value, trail)
+	}
+
+	t.Params = append(t.Params, p)
+}
+
+// Rename renames the named parameter old to name, keeping the
+// whitespace around it and any alignment of the equals signs. It
+// reports whether the parameter was found.
+func (t *Template) Rename(old, name string) bool {
+	p := t.Param(old)
+	if p == nil || !p.Named {
+		return false
+	}
+
+	s := p.Name.String()
+	lead, trail := spaceAround(s)
+	if t.nameWidth() > 0 && trail != "" && strings.Trim(trail, " ") == "" {
+		trail = strings.Repeat(" ", max(len(s)-len(lead)-len(name), 1))
+	}
+	p.Name = Nodes{&Text{Value: lead + strings.TrimSpace(name) + trail}}
+
+	return true
+}
+
+// Remove removes every parameter with the given name. A positional
+// parameter followed by other positional parameters is emptied rather
+// than removed, so that their positions don't change. It reports
+// whether any parameter was found.
+func (t *Template) Remove(name string) bool {
+	name = strings.TrimSpace(name)
+
+	found := false
+	for i := len(t.Params) - 1; i >= 0; i-- {
+		if t.key(i) != name {
+			continue
+		}
+		found = true
+
+		if !t.Params[i].Named && t.hasPositionalAfter(i) {
+			t.Params[i].Value = nil
+			continue
+		}
+		t.Params = append(t.Params[:i], t.Params[i+1:]...)
+	}
+
+	return found
+}
+
+func (t *Template) hasPositionalAfter(i int) bool {
+	for _, p := range t.Params[i+1:] {
+		if !p.Named {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Template) lastNamed() *Parameter {
+	for i := len(t.Params) - 1; i >= 0; i-- {
+		if t.Params[i].Named {
+			return t.Params[i]
+		}
+	}
+	return nil
+}
+
+// valueLead returns the whitespace before the value of the last named
+// parameter that has one.
+func (t *Template) valueLead() string {
+	for i := len(t.Params) - 1; i >= 0; i-- {
+		p := t.Params[i]
+		if s := p.Value.String(); p.Named && strings.TrimSpace(s) != "" {
+			lead, _ := spaceAround(s)
+			return lead
+		}
+	}
+	return ""
+}
+
+// nameWidth returns the common padded width of the named parameters'
+// names if there are at least two and they are aligned, or 0.
+func (t *Template) nameWidth() int {
+	w, n := 0, 0
+	for _, p := range t.Params {
+		if !p.Named {
+			continue
+		}
+		s := p.Name.String()
+		if n > 0 && len(s) != w {
+			return 0
+		}
+		w = len(s)
+		n++
+	}
+	if n < 2 {
+		return 0
+	}
+	return w
+}
+
+// spaceAround returns the leading and trailing whitespace of s. If s is
+// all whitespace, the split is made before the last newline.
+func spaceAround(s string) (string, string) {
+	if strings.TrimSpace(s) == "" {
+		if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+			return s[:i], s[i:]
+		}
+		return s, ""
+	}
+
+	lead := s[:len(s)-len(strings.TrimLeft(s, " \t\n"))]
+	trail := s[len(strings.TrimRight(s, " \t\n")):]
+	return lead, trail
+}
+
+func wrap(lead, value, trail string) Nodes {
+	var n Nodes
+	if lead != "" {
+		n = append(n, &Text{Value: lead})
+	}
+	n = append(n, Parse(value).Nodes...)
+	if trail != "" {
+		n = append(n, &Text{Value: trail})
+	}
+	return n
+}
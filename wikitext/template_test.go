@@ -0,0 +1,114 @@
+package wikitext
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const aligned = `{{Task
+| owner  = Ada
+| status = open <!-- set by bot -->
+| due    =
+}}`
+
+func firstTemplate(t *testing.T, s string) (*Document, *Template) {
+	t.Helper()
+
+	d := Parse(s)
+	tpls := d.Templates()
+	require.NotEmpty(t, tpls)
+
+	return d, tpls[0]
+}
+
+func TestTemplateGet(t *testing.T) {
+	_, tpl := firstTemplate(t, "{{T| a |x= 1 |b|x=2}}")
+
+	v, ok := tpl.Get("x")
+	assert.True(t, ok)
+	assert.Equal(t, "2", v, "the last of repeated parameters wins")
+
+	v, ok = tpl.Get("1")
+	assert.True(t, ok)
+	assert.Equal(t, " a ", v)
+
+	v, _ = tpl.Get("2")
+	assert.Equal(t, "b", v)
+
+	_, ok = tpl.Get("3")
+	assert.False(t, ok)
+}
+
+func TestTemplateSet(t *testing.T) {
+	d, tpl := firstTemplate(t, aligned)
+
+	tpl.Set("status", "done")
+	tpl.Set("due", "2024-01-01")
+	tpl.Set("reviewer", "Bob")
+	tpl.Set("x", "1")
+
+	assert.Equal(t, `{{Task
+| owner  = Ada
+| status = done
+| due    = 2024-01-01
+| reviewer = Bob
+| x = 1
+}}`, d.String())
+}
+
+func TestTemplateSetAligned(t *testing.T) {
+	d, tpl := firstTemplate(t, "{{Task\n| owner  = Ada\n| status = open\n}}")
+
+	tpl.Set("due", "soon")
+
+	assert.Equal(t, "{{Task\n| owner  = Ada\n| status = open\n| due    = soon\n}}", d.String())
+}
+
+func TestTemplateSetInline(t *testing.T) {
+	d, tpl := firstTemplate(t, "{{T|a|b=1}}")
+
+	tpl.Set("1", "z")
+	tpl.Set("c", "2")
+	tpl.Set("2", "k=v")
+
+	assert.Equal(t, "{{T|z|b=1|c=2|2=k=v}}", d.String())
+
+	d, tpl = firstTemplate(t, "{{T}}")
+	tpl.Set("a", "{{U|1}}")
+	assert.Equal(t, "{{T|a={{U|1}}}}", d.String())
+	assert.Len(t, d.Templates(), 2)
+}
+
+func TestTemplateRename(t *testing.T) {
+	d, tpl := firstTemplate(t, aligned)
+
+	assert.True(t, tpl.Rename("status", "state"))
+	assert.False(t, tpl.Rename("missing", "x"))
+
+	assert.Equal(t, `{{Task
+| owner  = Ada
+| state  = open <!-- set by bot -->
+| due    =
+}}`, d.String())
+
+	d, tpl = firstTemplate(t, "{{T|a = 1|bb=2}}")
+	tpl.Rename("a", "long")
+	assert.Equal(t, "{{T|long = 1|bb=2}}", d.String())
+}
+
+func TestTemplateRemove(t *testing.T) {
+	d, tpl := firstTemplate(t, aligned)
+
+	assert.True(t, tpl.Remove("status"))
+	assert.False(t, tpl.Remove("status"))
+	assert.True(t, tpl.Remove("due"))
+
+	assert.Equal(t, "{{Task\n| owner  = Ada\n}}", d.String())
+
+	d, tpl = firstTemplate(t, "{{T|a|b|c}}")
+	tpl.Remove("2")
+	tpl.Remove("3")
+	assert.Equal(t, "{{T|a|}}", d.String())
+}